@@ -11,6 +11,15 @@ import (
 	"time"
 )
 
+// likeReplacer escapes wildcards of LIKE patterns and the escape character itself.
+var likeReplacer = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// ContainsPattern returns a LIKE pattern matching strings that contain s.
+// Wildcards in s are matched literally, so the pattern must be used with ESCAPE '\'.
+func ContainsPattern(s string) string {
+	return "%" + likeReplacer.Replace(s) + "%"
+}
+
 func IsDuplicateKeyErr(err error) bool {
 	return strings.Contains(err.Error(), "SQLSTATE 23505")
 }
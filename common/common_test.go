@@ -25,6 +25,22 @@ func TestIsForeignKeyErr(t *testing.T) {
 	assert.False(t, common.IsForeignKeyErr(errors.New("92374283uasdfj")))
 }
 
+func TestContainsPattern(t *testing.T) {
+	t.Run("should escape wildcards and backslashes", func(t *testing.T) {
+		tests := map[string]string{
+			"john":       "%john%",
+			"john_doe":   `%john\_doe%`,
+			"100%":       `%100\%%`,
+			`back\slash`: `%back\\slash%`,
+			"":           "%%",
+		}
+
+		for s, expected := range tests {
+			assert.Equal(t, expected, common.ContainsPattern(s))
+		}
+	})
+}
+
 func TestMIMEType(t *testing.T) {
 	t.Run("should return MIME Type for provided files", func(t *testing.T) {
 		it := assert.New(t)
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultCurrency is ISO 4217 code of the currency that is used
+// when currency wasn't provided explicitly.
+const DefaultCurrency = "USD"
+
+var ErrCurrencyMismatch = errors.New("currencies don't match")
+
+// Money represents a monetary amount in minor units (e.g. cents) of Currency.
+//
+// To store it in db, embed it into a model with prefix, e.g.
+// `gorm:"embedded;embeddedPrefix:price_"` results in price_amount and price_currency columns.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"not null;default:0"`
+	Currency string `json:"currency" gorm:"size:3;not null;default:USD" binding:"omitempty,len=3,uppercase"`
+}
+
+// NewMoney creates Money with provided amount of minor units in DefaultCurrency.
+func NewMoney(amount int64) Money {
+	return Money{Amount: amount, Currency: DefaultCurrency}
+}
+
+// Add returns the sum of m and other. If m doesn't have a currency,
+// currency of other will be used.
+// Returns ErrCurrencyMismatch if both values have different currencies.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency == "" {
+		m.Currency = other.Currency
+	}
+
+	if other.Currency != "" && m.Currency != other.Currency {
+		return m, ErrCurrencyMismatch
+	}
+
+	m.Amount += other.Amount
+	return m, nil
+}
+
+// Mul returns m multiplied by n.
+func (m Money) Mul(n int) Money {
+	m.Amount *= int64(n)
+	return m
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// WithDefaultCurrency returns a copy of m that has DefaultCurrency
+// if currency wasn't set.
+func (m Money) WithDefaultCurrency() Money {
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	return m
+}
+
+// String formats m as a decimal number followed by currency code, e.g. "12.05 USD".
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d %s", sign, amount/100, amount%100, m.Currency)
+}
+
+// SumMoney returns the sum of all provided values.
+// Returns ErrCurrencyMismatch if values have different currencies.
+func SumMoney(values ...Money) (Money, error) {
+	var sum Money
+
+	for _, v := range values {
+		var err error
+		if sum, err = sum.Add(v); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return sum, nil
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMoney_Add(t *testing.T) {
+	t.Run("should add amounts of the same currency", func(t *testing.T) {
+		it := assert.New(t)
+		sum, err := NewMoney(322).Add(NewMoney(228))
+
+		if it.NoError(err) {
+			it.Equal(NewMoney(550), sum)
+		}
+	})
+
+	t.Run("should use currency of other value if there is none", func(t *testing.T) {
+		it := assert.New(t)
+		sum, err := Money{}.Add(Money{Amount: 100, Currency: "EUR"})
+
+		if it.NoError(err) {
+			it.Equal(Money{Amount: 100, Currency: "EUR"}, sum)
+		}
+	})
+
+	t.Run("should return error if currencies don't match", func(t *testing.T) {
+		_, err := NewMoney(100).Add(Money{Amount: 100, Currency: "EUR"})
+		assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	})
+}
+
+func TestMoney_Mul(t *testing.T) {
+	tests := []struct {
+		money    Money
+		n        int
+		expected int64
+	}{
+		{NewMoney(322), 3, 966},
+		{NewMoney(110), 3, 330},
+		{NewMoney(30), 3, 90},
+		{NewMoney(199), 0, 0},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, NewMoney(tc.expected), tc.money.Mul(tc.n))
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		money    Money
+		expected string
+	}{
+		{NewMoney(1205), "12.05 USD"},
+		{NewMoney(5), "0.05 USD"},
+		{NewMoney(-250), "-2.50 USD"},
+		{Money{Amount: 100000, Currency: "EUR"}, "1000.00 EUR"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, tc.money.String())
+	}
+}
+
+func TestSumMoney(t *testing.T) {
+	t.Run("should sum all values", func(t *testing.T) {
+		it := assert.New(t)
+		sum, err := SumMoney(NewMoney(966), NewMoney(90), NewMoney(2280))
+
+		if it.NoError(err) {
+			it.Equal(NewMoney(3336), sum)
+		}
+	})
+
+	t.Run("should return zero value if nothing was provided", func(t *testing.T) {
+		it := assert.New(t)
+		sum, err := SumMoney()
+
+		if it.NoError(err) {
+			it.Zero(sum)
+		}
+	})
+
+	t.Run("should return error if currencies don't match", func(t *testing.T) {
+		_, err := SumMoney(NewMoney(966), Money{Amount: 90, Currency: "EUR"})
+		assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	})
+}
@@ -0,0 +1,28 @@
+package common
+
+import "gorm.io/gorm"
+
+// Transactor groups multiple repository calls into a single unit of work.
+//
+// DB is opened with SkipDefaultTransaction, so every statement commits on its own.
+// Services that perform several writes should run them inside Transaction
+// using repositories bound to the transaction with their WithTx methods, e.g.
+//
+//	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+//		repo := s.repo.WithTx(tx)
+//		...
+//	})
+type Transactor struct {
+	db *gorm.DB
+}
+
+func ProvideTransactor(db *gorm.DB) *Transactor {
+	return &Transactor{db}
+}
+
+// Transaction calls fn with a handle to a new transaction. The transaction is
+// committed if fn returns nil and rolled back if it returns an error or panics.
+// Calls made with a handle that is already in a transaction use savepoints.
+func (t *Transactor) Transaction(fn func(tx *gorm.DB) error) error {
+	return t.db.Transaction(fn)
+}
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func init() {
@@ -26,6 +30,22 @@ func init() {
 	}
 
 	viper.SetConfigFile(filepath.Join(PathToMain(), envFileName))
+	viper.SetDefault("ORDER_CANCEL_WINDOW", OrderCancelWindow)
+	viper.SetDefault("ORDER_STREAM_HEARTBEAT", OrderStreamHeartbeat)
+	viper.SetDefault("IDEMPOTENCY_KEY_RETENTION", IdempotencyKeyRetention)
+	viper.SetDefault("IDEMPOTENCY_KEY_LEASE", IdempotencyKeyLease)
+	viper.SetDefault("JWT_EXPIRY", JWTExpiry)
+	viper.SetDefault("REFRESH_TOKEN_EXPIRY", RefreshTokenExpiry)
+	viper.SetDefault("SESSION_PRUNE_INTERVAL", SessionPruneInterval)
+	viper.SetDefault("PASSWORD_RESET_TOKEN_EXPIRY", PasswordResetTokenExpiry)
+	viper.SetDefault("EMAIL_VERIFICATION_TOKEN_EXPIRY", EmailVerificationTokenExpiry)
+	viper.SetDefault("REQUIRE_VERIFIED_EMAIL", RequireVerifiedEmail)
+	viper.SetDefault("PASSWORD_HASHER", PasswordHasher)
+	viper.SetDefault("BCRYPT_COST", BcryptCost)
+	viper.SetDefault("RATE_LIMIT", RateLimit.String())
+	viper.SetDefault("AUTH_RATE_LIMIT", AuthRateLimit.String())
+	viper.SetDefault("AUTH_EMAIL_RATE_LIMIT", AuthEmailRateLimit.String())
+	viper.SetDefault("LOGIN_LOCKOUT", LoginLockout.String())
 
 	if err := viper.ReadInConfig(); err != nil {
 		panic(err)
@@ -45,6 +65,134 @@ func init() {
 
 	HostURL = parsedURL
 	ClientURL, _ = url.Parse(viper.GetString("FE_URL"))
+	OrderCancelWindow = viper.GetDuration("ORDER_CANCEL_WINDOW")
+	OrderStreamHeartbeat = viper.GetDuration("ORDER_STREAM_HEARTBEAT")
+	IdempotencyKeyRetention = viper.GetDuration("IDEMPOTENCY_KEY_RETENTION")
+	IdempotencyKeyLease = viper.GetDuration("IDEMPOTENCY_KEY_LEASE")
+
+	JWTKeys, err = ParseJWTKeys(viper.GetString("JWT_KEYS"))
+
+	if err != nil {
+		panic(err)
+	}
+
+	JWTActiveKeyID = viper.GetString("JWT_ACTIVE_KID")
+
+	if _, ok := JWTKeys[JWTActiveKeyID]; !ok {
+		panic(fmt.Sprintf("JWT_ACTIVE_KID %q doesn't match any key from JWT_KEYS", JWTActiveKeyID))
+	}
+
+	JWTExpiry = viper.GetDuration("JWT_EXPIRY")
+	RefreshTokenExpiry = viper.GetDuration("REFRESH_TOKEN_EXPIRY")
+	SessionPruneInterval = viper.GetDuration("SESSION_PRUNE_INTERVAL")
+	PasswordResetTokenExpiry = viper.GetDuration("PASSWORD_RESET_TOKEN_EXPIRY")
+	EmailVerificationTokenExpiry = viper.GetDuration("EMAIL_VERIFICATION_TOKEN_EXPIRY")
+	RequireVerifiedEmail = viper.GetBool("REQUIRE_VERIFIED_EMAIL")
+
+	PasswordHasher = viper.GetString("PASSWORD_HASHER")
+
+	if PasswordHasher != HasherBcrypt && PasswordHasher != HasherArgon2id {
+		panic(fmt.Sprintf("PASSWORD_HASHER must be either %q or %q", HasherBcrypt, HasherArgon2id))
+	}
+
+	BcryptCost = viper.GetInt("BCRYPT_COST")
+
+	if BcryptCost < bcrypt.MinCost || BcryptCost > bcrypt.MaxCost {
+		panic(fmt.Sprintf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+
+	rates := []struct {
+		key  string
+		dest *Rate
+	}{
+		{"RATE_LIMIT", &RateLimit},
+		{"AUTH_RATE_LIMIT", &AuthRateLimit},
+		{"AUTH_EMAIL_RATE_LIMIT", &AuthEmailRateLimit},
+		{"LOGIN_LOCKOUT", &LoginLockout},
+	}
+
+	for _, r := range rates {
+		if *r.dest, err = ParseRate(viper.GetString(r.key)); err != nil {
+			panic(fmt.Sprintf("invalid %s: %s", r.key, err))
+		}
+	}
+}
+
+// ParseJWTKeys parses comma-separated list of "kid:secret" pairs.
+// Secrets can contain colons, but not commas.
+func ParseJWTKeys(raw string) (map[string]string, error) {
+	keys := make(map[string]string)
+
+	for _, pair := range strings.Split(raw, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, ":", 2)
+		kid := strings.TrimSpace(parts[0])
+
+		if len(parts) != 2 || kid == "" || parts[1] == "" {
+			return nil, fmt.Errorf("JWT_KEYS entry %q must be in \"kid:secret\" format", kid)
+		}
+
+		secret := parts[1]
+
+		if _, ok := keys[kid]; ok {
+			return nil, fmt.Errorf("JWT_KEYS has duplicate kid %q", kid)
+		}
+
+		keys[kid] = secret
+	}
+
+	return keys, nil
+}
+
+// Rate limits the amount of events per period of time. Zero Limit means there is no limit.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRate parses rate in "limit/period" format, e.g. "10/1m". Empty string and "0" mean no limit.
+func ParseRate(raw string) (Rate, error) {
+	raw = strings.TrimSpace(raw)
+
+	if raw == "" || raw == "0" {
+		return Rate{}, nil
+	}
+
+	parts := strings.SplitN(raw, "/", 2)
+
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("rate %q must be in \"limit/period\" format", raw)
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+
+	if err != nil || limit < 0 {
+		return Rate{}, fmt.Errorf("invalid limit %q", parts[0])
+	}
+
+	period, err := time.ParseDuration(parts[1])
+
+	if err != nil || period <= 0 {
+		return Rate{}, fmt.Errorf("invalid period %q", parts[1])
+	}
+
+	return Rate{limit, period}, nil
+}
+
+// IsZero reports whether the rate has no limit.
+func (r Rate) IsZero() bool {
+	return r.Limit == 0
+}
+
+func (r Rate) String() string {
+	if r.IsZero() {
+		return "0"
+	}
+
+	return fmt.Sprintf("%d/%s", r.Limit, r.Period)
 }
 
 // ExecutableDir points to the directory of os.Executable
@@ -58,6 +206,81 @@ var ClientURL *url.URL
 
 var MaxUploadFileSize int64 = 512 * 1024 // 512 KiB
 
+// OrderCancelWindow is a period of time after order creation during which
+// a customer is allowed to cancel it.
+var OrderCancelWindow = 5 * time.Minute
+
+// OrderStreamHeartbeat is an interval between heartbeat messages sent to
+// clients of orders' event stream to keep connections open.
+var OrderStreamHeartbeat = 15 * time.Second
+
+// IdempotencyKeyRetention is a period of time during which a repeated request
+// with the same Idempotency-Key header gets the original response.
+var IdempotencyKeyRetention = 24 * time.Hour
+
+// IdempotencyKeyLease is a period of time during which a request holds its
+// Idempotency-Key. If the request hasn't completed by then, it's considered
+// abandoned and the key can be claimed by a repeated request.
+var IdempotencyKeyLease = 30 * time.Second
+
+// JWTKeys maps key ids to secrets that are used to verify JWT signatures.
+// To rotate secrets, add a new key, make it active and remove the old one
+// once tokens signed with it have expired.
+var JWTKeys map[string]string
+
+// JWTActiveKeyID is the id of the key from JWTKeys that signs new tokens.
+var JWTActiveKeyID string
+
+// JWTExpiry is a lifetime of access tokens. Expired access token
+// can be replaced with a new one using session's refresh token.
+var JWTExpiry = 15 * time.Minute
+
+// RefreshTokenExpiry is a lifetime of sessions and their refresh tokens.
+// It's extended every time the refresh token is used.
+var RefreshTokenExpiry = 30 * 24 * time.Hour
+
+// SessionPruneInterval is an interval between removals of expired sessions.
+var SessionPruneInterval = time.Hour
+
+// PasswordResetTokenExpiry is a period of time during which
+// a password reset token sent to a user can be used.
+var PasswordResetTokenExpiry = time.Hour
+
+// EmailVerificationTokenExpiry is a period of time during which
+// an email verification token sent to a user can be used.
+var EmailVerificationTokenExpiry = 48 * time.Hour
+
+// RequireVerifiedEmail prevents users from placing orders until they verify their email.
+var RequireVerifiedEmail = true
+
+const (
+	HasherBcrypt   = "bcrypt"
+	HasherArgon2id = "argon2id"
+)
+
+// PasswordHasher is the algorithm used to hash new passwords, either HasherBcrypt or
+// HasherArgon2id. Passwords hashed with another algorithm are rehashed when users sign in.
+var PasswordHasher = HasherBcrypt
+
+// BcryptCost is the cost of bcrypt password hashes. Passwords hashed with
+// a lower cost are rehashed when users sign in.
+var BcryptCost = 12
+
+// RateLimit limits requests from a single IP to all routes.
+var RateLimit = Rate{600, time.Minute}
+
+// AuthRateLimit limits requests from a single IP to routes that sign in,
+// sign up or send password reset emails.
+var AuthRateLimit = Rate{20, time.Minute}
+
+// AuthEmailRateLimit limits sign in and password reset requests for a single email.
+var AuthEmailRateLimit = Rate{10, time.Minute}
+
+// LoginLockout limits failed sign in attempts for a single email. Once the limit
+// is reached, signing in with the email is locked until one of the failures
+// expires, i.e. for Period / Limit.
+var LoginLockout = Rate{5, 15 * time.Minute}
+
 // StaticDir shows path to "static" directory relative to main.go
 var StaticDir = "static"
 
@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -34,14 +35,14 @@ func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 
 	router.GET("", api.FindAll)
 	router.GET("/:id", api.FindByID)
-	router.POST("", auth(true), api.Create)
-	router.PUT("/:id", auth(true), api.Update)
-	router.PATCH("/:id/upload", auth(true), api.Upload)
-	router.DELETE("/:id", auth(true), api.Delete)
+	router.POST("", auth(user.PermMenuWrite), api.Create)
+	router.PUT("/:id", auth(user.PermMenuWrite), api.Update)
+	router.PATCH("/:id/upload", auth(user.PermMenuWrite), api.Upload)
+	router.DELETE("/:id", auth(user.PermMenuWrite), api.Delete)
 }
 
 // Create godoc
-// @Summary Create new category. Requires admin rights.
+// @Summary Create new category. Requires menu:write permission.
 // @ID category-create
 // @Tags category
 // @Accept json
@@ -49,6 +50,7 @@ func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 // @Produce json
 // @Success 201 {object} DTO
 // @Failure 409,422,500
+// @Security BearerAuth
 // @Router /categories [post]
 func (api *API) Create(c *gin.Context) {
 	dto, err := api.bindJSON(c)
@@ -104,7 +106,7 @@ func (api *API) FindAll(c *gin.Context) {
 }
 
 // Update godoc
-// @Summary Replace category. Requires admin rights.
+// @Summary Replace category. Requires menu:write permission.
 // @ID category-update
 // @Tags category
 // @Accept json
@@ -113,6 +115,7 @@ func (api *API) FindAll(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} DTO
 // @Failure 401,403,404,500
+// @Security BearerAuth
 // @Router /categories/:id [put]
 func (api *API) Update(c *gin.Context) {
 	cat, err := api.findByID(c)
@@ -145,7 +148,7 @@ func (api *API) Update(c *gin.Context) {
 }
 
 // Upload godoc
-// @Summary Upload image for category. Requires admin rights.
+// @Summary Upload image for category. Requires menu:write permission.
 // @ID category-upload
 // @Tags category
 // @Param id path integer true "Category id"
@@ -154,6 +157,7 @@ func (api *API) Update(c *gin.Context) {
 // @Produce text/plain
 // @Success 200 {string} string "Link to uploaded image"
 // @Failure 400,401,404,413,415,500
+// @Security BearerAuth
 // @Router /categories/:id/upload [patch]
 func (api *API) Upload(c *gin.Context) {
 	cat, err := api.findByID(c)
@@ -193,13 +197,14 @@ func (api *API) Upload(c *gin.Context) {
 }
 
 // Delete godoc
-// @Summary Delete category by id. Requires admin rights.
+// @Summary Delete category by id. Requires menu:write permission.
 // @ID category-delete
 // @Tags category
 // @Param id path integer true "Category id"
 // @Produce json
 // @Success 200 {object} DTO
 // @Failure 401,403,404,500
+// @Security BearerAuth
 // @Router /categories/:id [delete]
 func (api *API) Delete(c *gin.Context) {
 	cat, err := api.findByID(c)
@@ -213,40 +218,19 @@ func (api *API) Delete(c *gin.Context) {
 		return
 	}
 
-	dishImages, err := api.service.FindAllDishImages(cat.ID)
-
-	if err != nil {
-		log.Println("[Category] Error finding dish images:", err)
-		c.Status(http.StatusInternalServerError)
-		return
-	}
-
 	cat, err = api.service.Delete(cat)
 
 	if err != nil {
 		log.Println("[Category] Error deleting category:", err)
-
-		if common.IsForeignKeyErr(err) {
-			c.String(http.StatusForbidden, "One of the dishes that corresponds to this category has already been used in the order.")
-			return
-		}
-
-		c.Status(http.StatusInternalServerError)
-		return
-	}
-
-	if err = api.service.DeleteDishImages(dishImages); err != nil {
-		log.Println("[Category] Error deleting dishes images", err)
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
+	// category has already been deleted, so failing to remove its image
+	// isn't an error of the request
 	if cat.Image != nil {
-		err = api.upload.Remove(*cat.Image)
-		if err != nil {
+		if err = api.upload.Remove(*cat.Image); err != nil && !os.IsNotExist(err) {
 			log.Println("[Category] Error deleting image:", err)
-			c.Status(http.StatusInternalServerError)
-			return
 		}
 	}
 
@@ -10,6 +10,11 @@ func ProvideRepository(db *gorm.DB) *Repository {
 	return &Repository{db}
 }
 
+// WithTx returns a copy of the repository that runs all queries in provided transaction.
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{tx}
+}
+
 func (r *Repository) Create(c Category) (Category, error) {
 	err := r.db.Create(&c).Error
 	return c, err
@@ -32,15 +37,20 @@ func (r *Repository) FindAll() []Category {
 	return categories
 }
 
+// FindAllDishImages returns names of images in galleries of dishes of the category
+// along with their primary images. Zero categoryID means all categories.
 func (r *Repository) FindAllDishImages(categoryID uint) ([]string, error) {
 	var res []string
-	tx := r.db.Table("dishes").Select("image").Where("image IS NOT NULL")
+	dishes := r.db.Table("dishes").Select("id")
 
 	if categoryID != 0 {
-		tx.Where("category_id = ?", categoryID)
+		dishes = dishes.Where("category_id = ?", categoryID)
 	}
 
-	rows, err := tx.Rows()
+	rows, err := r.db.Raw(`
+		SELECT name FROM dish_images WHERE dish_id IN (?)
+		UNION SELECT image FROM dishes WHERE image IS NOT NULL AND id IN (?)
+	`, dishes, dishes).Rows()
 
 	if err != nil {
 		return nil, err
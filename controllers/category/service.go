@@ -1,17 +1,21 @@
 package category
 
 import (
+	"food_ordering_backend/common"
 	"food_ordering_backend/config"
+	"gorm.io/gorm"
+	"log"
 	"os"
 	"path/filepath"
 )
 
 type Service struct {
-	repo *Repository
+	repo       *Repository
+	transactor *common.Transactor
 }
 
-func ProvideService(r *Repository) *Service {
-	return &Service{r}
+func ProvideService(r *Repository, t *common.Transactor) *Service {
+	return &Service{r, t}
 }
 
 func (s *Service) Create(c Category) (Category, error) {
@@ -34,18 +38,41 @@ func (s *Service) FindAllDishImages(categoryID uint) ([]string, error) {
 	return s.repo.FindAllDishImages(categoryID)
 }
 
-func (s *Service) DeleteDishImages(dishImages []string) error {
+// DeleteDishImages removes files of dish images with provided names. Removal
+// happens after rows have been deleted, so failures are only logged and files
+// that don't exist anymore are skipped.
+func (s *Service) DeleteDishImages(dishImages []string) {
 	for _, image := range dishImages {
 		err := os.Remove(filepath.Join(config.DishesImgDirAbs, image))
 
-		if err != nil {
-			return err
+		if err != nil && !os.IsNotExist(err) {
+			log.Println("[Category] Error deleting dish image:", err)
 		}
 	}
-
-	return nil
 }
 
+// Delete removes category together with its dishes and their images.
+// Images are removed only after the transaction has been committed,
+// because removed files can't be restored if it's rolled back.
 func (s *Service) Delete(c Category) (Category, error) {
-	return s.repo.Delete(c)
+	var dishImages []string
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		var err error
+		repo := s.repo.WithTx(tx)
+
+		if dishImages, err = repo.FindAllDishImages(c.ID); err != nil {
+			return err
+		}
+
+		c, err = repo.Delete(c)
+		return err
+	})
+
+	if err != nil {
+		return c, err
+	}
+
+	s.DeleteDishImages(dishImages)
+	return c, nil
 }
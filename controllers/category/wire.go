@@ -3,11 +3,12 @@
 package category
 
 import (
+	"food_ordering_backend/common"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
 
 func InitAPI(db *gorm.DB) *API {
-	wire.Build(ProvideAPI, ProvideService, ProvideRepository)
+	wire.Build(ProvideAPI, ProvideService, ProvideRepository, common.ProvideTransactor)
 	return nil
 }
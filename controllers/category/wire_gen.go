@@ -6,6 +6,7 @@
 package category
 
 import (
+	"food_ordering_backend/common"
 	"gorm.io/gorm"
 )
 
@@ -13,7 +14,8 @@ import (
 
 func InitAPI(db *gorm.DB) *API {
 	repository := ProvideRepository(db)
-	service := ProvideService(repository)
+	transactor := common.ProvideTransactor(db)
+	service := ProvideService(repository, transactor)
 	api := ProvideAPI(service)
 	return api
 }
@@ -1,6 +1,8 @@
 package dish
 
 import (
+	"errors"
+	"fmt"
 	"food_ordering_backend/common"
 	"food_ordering_backend/config"
 	"food_ordering_backend/controllers/user"
@@ -12,21 +14,24 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type API struct {
 	service *Service
+	options *OptionService
+	images  *ImageService
 	upload  *services.Upload
 }
 
-func ProvideAPI(s *Service) *API {
+func ProvideAPI(s *Service, options *OptionService, images *ImageService) *API {
 	upload := &services.Upload{
 		AllowedTypes: []string{"image/png", "image/jpeg", "image/webp"},
 		MaxFileSize:  config.MaxUploadFileSize,
 		Root:         config.DishesImgDirAbs,
 		FormDataKey:  "image",
 	}
-	return &API{s, upload}
+	return &API{s, options, images, upload}
 }
 
 func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
@@ -34,14 +39,21 @@ func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 
 	router.GET("", api.FindAll)
 	router.GET("/:id", api.FindByID)
-	router.POST("", auth(true), api.Create)
-	router.PUT("/:id", auth(true), api.Update)
-	router.PATCH("/:id/upload", auth(true), api.Upload)
-	router.DELETE("/:id", auth(true), api.Delete)
+	router.POST("", auth(user.PermMenuWrite), api.Create)
+	router.PUT("/:id", auth(user.PermMenuWrite), api.Update)
+	router.PATCH("/:id/upload", auth(user.PermMenuWrite), api.Upload)
+	router.DELETE("/:id", auth(user.PermMenuWrite), api.Delete)
+	router.POST("/:id/images", auth(user.PermMenuWrite), api.AddImage)
+	router.PUT("/:id/images", auth(user.PermMenuWrite), api.ReorderImages)
+	router.DELETE("/:id/images/:iid", auth(user.PermMenuWrite), api.DeleteImage)
+	router.GET("/:id/options", api.FindOptionGroups)
+	router.POST("/:id/options", auth(user.PermMenuWrite), api.CreateOptionGroup)
+	router.PUT("/:id/options/:gid", auth(user.PermMenuWrite), api.UpdateOptionGroup)
+	router.DELETE("/:id/options/:gid", auth(user.PermMenuWrite), api.DeleteOptionGroup)
 }
 
 // Create godoc
-// @Summary Create new dish. Requires admin rights.
+// @Summary Create new dish. Requires menu:write permission.
 // @ID dish-create
 // @Tags dish
 // @Accept json
@@ -49,6 +61,7 @@ func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 // @Produce json
 // @Success 201 {object} DTO
 // @Failure 409,422
+// @Security BearerAuth
 // @Router /dishes [post]
 func (api *API) Create(c *gin.Context) {
 	dto, err := api.bindJSON(c)
@@ -92,35 +105,73 @@ func (api *API) FindByID(c *gin.Context) {
 
 // FindAll godoc
 // @Summary Get all dishes
+// @Description Returns an array of dishes. If page or limit is provided, dishes are paginated
+// @Description and returned in an object along with pagination info.
+// @Description Search matches dishes whose title or description contains all words of q, the last word can be incomplete.
 // @ID dish-all
 // @Tags dish
-// @Param cid query integer false "filter dishes by category id"
+// @Param q query string false "search query"
+// @Param cid query []integer false "category ids, can be repeated or comma-separated" collectionFormat(multi)
+// @Param min_price query integer false "minimal price in minor units"
+// @Param max_price query integer false "maximal price in minor units"
+// @Param available query boolean false "true for dishes that can be ordered, false for sold out ones"
+// @Param exclude_allergens query []string false "allergens dishes must not contain, can be repeated or comma-separated" collectionFormat(multi)
+// @Param tags query []string false "dietary tags dishes must have, can be repeated or comma-separated" collectionFormat(multi)
+// @Param max_spicy_level query integer false "maximal spicy level from 0 to 3"
+// @Param max_calories query integer false "maximal calories, dishes with unknown calories are excluded"
+// @Param sort query string false "sort field" Enums(id, title, price)
+// @Param order query string false "sort direction" Enums(asc, desc)
+// @Param page query integer false "0-based page number"
+// @Param limit query integer false "amount of entries per page"
 // @Produce json
 // @Success 200 {array} DTO
-// @Failure 403,404
+// @Success 200 {object} DTOsWithPagination
+// @Failure 400,500
 // @Router /dishes [get]
 func (api *API) FindAll(c *gin.Context) {
-	var dishes []Dish
-	cidq := c.Query("cid")
+	q := c.Request.URL.Query()
+	f, err := FilterFromQuery(q)
 
-	if cidq == "" {
-		dishes = api.service.FindAll(0)
-	} else {
-		cid, err := strconv.ParseUint(cidq, 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// clients that don't paginate expect an array of all dishes
+	if q.Get("page") == "" && q.Get("limit") == "" {
+		dishes, err := api.service.FindAll(f, nil)
 
 		if err != nil {
-			c.Status(http.StatusBadRequest)
+			c.Status(http.StatusInternalServerError)
 			return
 		}
 
-		dishes = api.service.FindAll(uint(cid))
+		c.JSON(http.StatusOK, ToDTOs(dishes))
+		return
+	}
+
+	p := common.ExtractPagination(c, 10)
+	dishes, err := api.service.FindAll(f, p)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
 	}
 
-	c.JSON(http.StatusOK, ToDTOs(dishes))
+	c.JSON(http.StatusOK, DTOsWithPagination{
+		Dishes: ToDTOs(dishes),
+		Pagination: common.PaginationDTO{
+			Page:  p.Page(),
+			Limit: p.Limit(),
+			Total: api.service.CountAll(f),
+		},
+	})
 }
 
 // Update godoc
-// @Summary Replace dish. Requires admin rights.
+// @Summary Replace dish. Requires menu:write permission.
+// @Description Omitted availability makes the dish available, omitted stock makes it unlimited.
+// @Description Omitted description, ingredients, allergens, dietary tags, spicy level and calories are cleared.
 // @ID dish-update
 // @Tags dish
 // @Accept json
@@ -129,6 +180,7 @@ func (api *API) FindAll(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} DTO
 // @Failure 401,403,404,409,500
+// @Security BearerAuth
 // @Router /dishes/:id [put]
 func (api *API) Update(c *gin.Context) {
 	dish, err := api.findByID(c)
@@ -147,6 +199,16 @@ func (api *API) Update(c *gin.Context) {
 	dish.Price = dto.Price
 	dish.CategoryID = dto.CategoryID
 	dish.Category.ID = dto.CategoryID
+	dish.Available = dto.Available == nil || *dto.Available
+	dish.Stock = dto.Stock
+
+	details := ToModel(dto)
+	dish.Description = details.Description
+	dish.Ingredients = details.Ingredients
+	dish.Allergens = details.Allergens
+	dish.DietaryTags = details.DietaryTags
+	dish.SpicyLevel = details.SpicyLevel
+	dish.Calories = details.Calories
 
 	dish, err = api.service.Save(dish)
 
@@ -163,7 +225,8 @@ func (api *API) Update(c *gin.Context) {
 }
 
 // Upload godoc
-// @Summary Upload image for dish. Requires admin rights.
+// @Summary Replace the primary image of dish. Requires menu:write permission.
+// @Description Adds the image to the gallery if it's empty, the rest of the gallery is kept.
 // @ID dish-upload
 // @Tags dish
 // @Param id path integer true "Dish id"
@@ -172,6 +235,7 @@ func (api *API) Update(c *gin.Context) {
 // @Produce text/plain
 // @Success 200 {string} string "Link to uploaded image"
 // @Failure 400,401,404,413,415,500
+// @Security BearerAuth
 // @Router /dishes/:id/upload [patch]
 func (api *API) Upload(c *gin.Context) {
 	dish, err := api.findByID(c)
@@ -180,43 +244,150 @@ func (api *API) Upload(c *gin.Context) {
 		return
 	}
 
-	if dish.Image != nil {
-		err = api.upload.Remove(*dish.Image)
+	absPath := api.upload.ParseAndSave(c, strconv.Itoa(int(dish.ID)))
 
-		if err != nil {
-			log.Println("[Dish] Error deleting previous dish image:", err)
-			c.Status(http.StatusInternalServerError)
-			return
-		}
+	if absPath == "" {
+		return
 	}
 
-	absPath := api.upload.ParseAndSave(c, strconv.Itoa(int(dish.ID)))
+	name := filepath.Base(absPath)
+	previous, err := api.images.ReplacePrimary(dish, name)
+
+	// the file isn't removed, since it might have overwritten the primary image
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// a file with the same name has already been overwritten
+	if previous != nil && previous.Name != name {
+		api.removeImage(previous.Name)
+	}
+
+	c.String(http.StatusOK, PathToImg(name))
+}
+
+// AddImage godoc
+// @Summary Add an image to the end of the dish gallery. Requires menu:write permission.
+// @Description The first image of the gallery is the primary image of the dish.
+// @ID dish-images-add
+// @Tags dish
+// @Param id path integer true "Dish id"
+// @Param image formData file true "Dish image"
+// @Accept multipart/form-data
+// @Produce json
+// @Success 201 {object} ImageDTO
+// @Failure 400,401,403,404,413,415,500
+// @Security BearerAuth
+// @Router /dishes/:id/images [post]
+func (api *API) AddImage(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	// unique name, so that browsers don't show a cached image that has been deleted
+	absPath := api.upload.ParseAndSave(c, fmt.Sprintf("%d-%d", dish.ID, time.Now().UnixNano()))
 
 	if absPath == "" {
 		return
 	}
 
 	name := filepath.Base(absPath)
-	dish.Image = &name
-
-	dish, err = api.service.Save(dish)
+	img, err := api.images.Add(dish, name)
 
 	if err != nil {
+		api.removeImage(name)
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
-	c.String(http.StatusOK, PathToImg(name))
+	c.JSON(http.StatusCreated, ToImageDTO(img))
+}
+
+// ReorderImages godoc
+// @Summary Reorder the dish gallery. Requires menu:write permission.
+// @Description ids must list every image of the gallery exactly once, the first one becomes the primary image.
+// @ID dish-images-reorder
+// @Tags dish
+// @Accept json
+// @Param id path integer true "Dish id"
+// @Param dto body ImageOrderDTO true "Image ids in the new order"
+// @Produce json
+// @Success 200 {array} ImageDTO
+// @Failure 400,401,403,404,422,500
+// @Security BearerAuth
+// @Router /dishes/:id/images [put]
+func (api *API) ReorderImages(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	var dto ImageOrderDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	images, err := api.images.Reorder(dish, dto.IDs)
+
+	if err != nil {
+		api.handleImageErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToImageDTOs(images))
+}
+
+// DeleteImage godoc
+// @Summary Delete an image from the dish gallery. Requires menu:write permission.
+// @Description The next image becomes the primary one if the deleted image was primary.
+// @ID dish-images-delete
+// @Tags dish
+// @Param id path integer true "Dish id"
+// @Param iid path integer true "Image id"
+// @Success 204
+// @Failure 400,401,403,404,500
+// @Security BearerAuth
+// @Router /dishes/:id/images/:iid [delete]
+func (api *API) DeleteImage(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	iid, err := strconv.Atoi(c.Param("iid"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	img, err := api.images.Delete(dish, uint(iid))
+
+	if err != nil {
+		api.handleImageErr(c, err)
+		return
+	}
+
+	api.removeImage(img.Name)
+	c.Status(http.StatusNoContent)
 }
 
 // Delete godoc
-// @Summary Delete dish by id. Requires admin rights.
+// @Summary Delete dish by id. Requires menu:write permission.
 // @ID dish-delete
 // @Tags dish
 // @Param id path integer true "Dish id"
 // @Produce json
 // @Success 200 {object} DTO
-// @Failure 401,403,404,500
+// @Failure 401,404,500
+// @Security BearerAuth
 // @Router /dishes/:id [delete]
 func (api *API) Delete(c *gin.Context) {
 	dish, err := api.findByID(c)
@@ -225,21 +396,204 @@ func (api *API) Delete(c *gin.Context) {
 		return
 	}
 
+	// files are removed only once the dish is deleted, since they
+	// can't be restored if the deletion fails
+	images := dish.imageNames()
 	dish, err = api.service.Delete(dish)
 
 	if err != nil {
-		if common.IsForeignKeyErr(err) {
-			c.String(http.StatusForbidden, "Can't delete a dish that has already been used in the order.")
-			return
-		}
-
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
+	for _, name := range images {
+		api.removeImage(name)
+	}
+
 	c.JSON(http.StatusOK, ToDTO(dish))
 }
 
+// FindOptionGroups godoc
+// @Summary Get option groups of the dish along with their options
+// @ID dish-options-get-all
+// @Tags dish
+// @Param id path integer true "Dish id"
+// @Produce json
+// @Success 200 {array} OptionGroupDTO
+// @Failure 400,404,500
+// @Router /dishes/:id/options [get]
+func (api *API) FindOptionGroups(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	groups, err := api.options.FindAll(dish)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToOptionGroupDTOs(groups))
+}
+
+// CreateOptionGroup godoc
+// @Summary Add an option group to the dish. Requires menu:write permission.
+// @Description Customers choose from min_select to max_select options of the group for every ordered dish.
+// @Description Price delta of an option is added to the price of the dish, it defaults to the currency of the dish.
+// @ID dish-options-create
+// @Tags dish
+// @Accept json
+// @Param id path integer true "Dish id"
+// @Param dto body OptionGroupDTO true "Option group"
+// @Produce json
+// @Success 201 {object} OptionGroupDTO
+// @Failure 400,401,403,404,422,500
+// @Security BearerAuth
+// @Router /dishes/:id/options [post]
+func (api *API) CreateOptionGroup(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	var dto OptionGroupDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	g, err := api.options.Create(dish, dto)
+
+	if err != nil {
+		api.handleOptionGroupErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToOptionGroupDTO(g))
+}
+
+// UpdateOptionGroup godoc
+// @Summary Replace an option group of the dish. Requires menu:write permission.
+// @Description Options that aren't present in the group are deleted, options without id are created.
+// @Description Orders keep their copies of chosen options.
+// @ID dish-options-update
+// @Tags dish
+// @Accept json
+// @Param id path integer true "Dish id"
+// @Param gid path integer true "Option group id"
+// @Param dto body OptionGroupDTO true "Option group"
+// @Produce json
+// @Success 200 {object} OptionGroupDTO
+// @Failure 400,401,403,404,422,500
+// @Security BearerAuth
+// @Router /dishes/:id/options/:gid [put]
+func (api *API) UpdateOptionGroup(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	gid, err := strconv.Atoi(c.Param("gid"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var dto OptionGroupDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	g, err := api.options.Update(dish, uint(gid), dto)
+
+	if err != nil {
+		api.handleOptionGroupErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToOptionGroupDTO(g))
+}
+
+// DeleteOptionGroup godoc
+// @Summary Delete an option group of the dish along with its options. Requires menu:write permission.
+// @Description Orders keep their copies of chosen options.
+// @ID dish-options-delete
+// @Tags dish
+// @Param id path integer true "Dish id"
+// @Param gid path integer true "Option group id"
+// @Success 204
+// @Failure 400,401,403,404,500
+// @Security BearerAuth
+// @Router /dishes/:id/options/:gid [delete]
+func (api *API) DeleteOptionGroup(c *gin.Context) {
+	dish, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	gid, err := strconv.Atoi(c.Param("gid"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if err := api.options.Delete(dish, uint(gid)); err != nil {
+		api.handleOptionGroupErr(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (api *API) handleOptionGroupErr(c *gin.Context, err error) {
+	var errGroupID *ErrOptionGroupID
+	var errOptionID *ErrOptionID
+
+	switch {
+	case errors.As(err, &errGroupID):
+		c.String(http.StatusNotFound, errGroupID.Error())
+	case errors.As(err, &errOptionID):
+		c.String(http.StatusBadRequest, errOptionID.Error())
+	case errors.Is(err, common.ErrCurrencyMismatch):
+		c.String(http.StatusBadRequest, "Options must be priced in the currency of the dish")
+	case errors.Is(err, ErrInvalidOptionGroup):
+		c.String(http.StatusUnprocessableEntity, err.Error())
+	default:
+		c.Status(http.StatusInternalServerError)
+	}
+}
+
+func (api *API) handleImageErr(c *gin.Context, err error) {
+	var errImageID *ErrImageID
+
+	switch {
+	case errors.As(err, &errImageID):
+		c.String(http.StatusNotFound, errImageID.Error())
+	case errors.Is(err, ErrInvalidImageOrder):
+		c.String(http.StatusUnprocessableEntity, err.Error())
+	default:
+		c.Status(http.StatusInternalServerError)
+	}
+}
+
+// removeImage removes the file of an image that is no longer used, errors are only logged.
+func (api *API) removeImage(name string) {
+	if err := api.upload.Remove(name); err != nil {
+		log.Println("[Dish] Error deleting image:", err)
+	}
+}
+
 func (api *API) bindJSON(c *gin.Context) (DTO, error) {
 	var dto DTO
 	err := c.BindJSON(&dto)
@@ -249,7 +603,25 @@ func (api *API) bindJSON(c *gin.Context) (DTO, error) {
 		return dto, err
 	}
 
+	if dto.Price.IsNegative() {
+		err = errors.New("price can't be negative")
+		c.String(http.StatusBadRequest, err.Error())
+		return dto, err
+	}
+
 	dto.Title = strings.TrimSpace(dto.Title)
+	dto.Description = strings.TrimSpace(dto.Description)
+
+	ingredients := make([]string, 0, len(dto.Ingredients))
+
+	for _, ingredient := range dto.Ingredients {
+		if ingredient = strings.TrimSpace(ingredient); ingredient != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+
+	dto.Ingredients = ingredients
+	dto.Price = dto.Price.WithDefaultCurrency()
 
 	return dto, nil
 }
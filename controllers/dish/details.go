@@ -0,0 +1,137 @@
+package dish
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Allergens is a set of the 14 allergens that must be declared in the EU.
+// Each allergen is a bit, so that dishes can be filtered by them in a single query.
+type Allergens uint
+
+const (
+	AllergenCelery Allergens = 1 << iota
+	AllergenGluten
+	AllergenCrustaceans
+	AllergenEggs
+	AllergenFish
+	AllergenLupin
+	AllergenMilk
+	AllergenMolluscs
+	AllergenMustard
+	AllergenNuts
+	AllergenPeanuts
+	AllergenSesame
+	AllergenSoy
+	AllergenSulphites
+)
+
+// allergenNames are names of allergens in order of their bits.
+// binding tag of DTO.Allergens must list the same names.
+var allergenNames = []string{
+	"celery", "gluten", "crustaceans", "eggs", "fish", "lupin", "milk",
+	"molluscs", "mustard", "nuts", "peanuts", "sesame", "soy", "sulphites",
+}
+
+// DietaryTags is a set of diets the dish is suitable for.
+type DietaryTags uint
+
+const (
+	TagVegetarian DietaryTags = 1 << iota
+	TagVegan
+	TagGlutenFree
+	TagLactoseFree
+)
+
+// dietaryTagNames are names of tags in order of their bits.
+// binding tag of DTO.DietaryTags must list the same names.
+var dietaryTagNames = []string{"vegetarian", "vegan", "gluten_free", "lactose_free"}
+
+// MaxSpicyLevel is the level of the hottest dishes, 0 means not spicy.
+const MaxSpicyLevel = 3
+
+// Ingredients is a list of ingredients of a dish stored as a json array.
+type Ingredients []string
+
+// ParseAllergens returns a set of allergens with provided names.
+func ParseAllergens(names []string) (Allergens, error) {
+	bits, err := parseBits(names, allergenNames, "allergen")
+	return Allergens(bits), err
+}
+
+// Names returns names of allergens in the set.
+func (a Allergens) Names() []string {
+	return bitNames(uint(a), allergenNames)
+}
+
+// ParseDietaryTags returns a set of tags with provided names.
+func ParseDietaryTags(names []string) (DietaryTags, error) {
+	bits, err := parseBits(names, dietaryTagNames, "dietary tag")
+	return DietaryTags(bits), err
+}
+
+// Names returns names of tags in the set.
+func (t DietaryTags) Names() []string {
+	return bitNames(uint(t), dietaryTagNames)
+}
+
+func (i Ingredients) Value() (driver.Value, error) {
+	if i == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(i)
+	return string(data), err
+}
+
+func (i *Ingredients) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, i)
+	case string:
+		return json.Unmarshal([]byte(v), i)
+	case nil:
+		*i = nil
+		return nil
+	}
+
+	return fmt.Errorf("can't scan %T into Ingredients", value)
+}
+
+// parseBits sets a bit for each of names at the index of the name in known names.
+// Returns an error with the first unknown name, kind describes what names are.
+func parseBits(names []string, known []string, kind string) (uint, error) {
+	var bits uint
+
+	for _, name := range names {
+		found := false
+
+		for i, k := range known {
+			if name == k {
+				bits |= 1 << i
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("unknown %s %q", kind, name)
+		}
+	}
+
+	return bits, nil
+}
+
+// bitNames returns known names of bits that are set.
+func bitNames(bits uint, known []string) []string {
+	names := make([]string, 0)
+
+	for i, name := range known {
+		if bits&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
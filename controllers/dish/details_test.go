@@ -0,0 +1,81 @@
+package dish
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAllergens(t *testing.T) {
+	t.Run("should set bits of provided allergens", func(t *testing.T) {
+		it := assert.New(t)
+		a, err := ParseAllergens([]string{"milk", "celery", "sulphites"})
+
+		if it.NoError(err) {
+			it.Equal(AllergenMilk|AllergenCelery|AllergenSulphites, a)
+		}
+	})
+
+	t.Run("should return error if allergen is unknown", func(t *testing.T) {
+		_, err := ParseAllergens([]string{"milk", "bacon"})
+		assert.EqualError(t, err, `unknown allergen "bacon"`)
+	})
+
+	t.Run("should know all 14 allergens", func(t *testing.T) {
+		a, err := ParseAllergens(allergenNames)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, AllergenSulphites<<1-1, a)
+		}
+	})
+}
+
+func TestAllergens_Names(t *testing.T) {
+	t.Run("should return names in order of bits", func(t *testing.T) {
+		assert.Equal(t, []string{"gluten", "milk", "peanuts"}, (AllergenPeanuts | AllergenGluten | AllergenMilk).Names())
+	})
+
+	t.Run("should return an empty slice if there are no allergens", func(t *testing.T) {
+		assert.Equal(t, []string{}, Allergens(0).Names())
+	})
+}
+
+func TestParseDietaryTags(t *testing.T) {
+	it := assert.New(t)
+	tags, err := ParseDietaryTags([]string{"vegan", "gluten_free"})
+
+	if it.NoError(err) {
+		it.Equal(TagVegan|TagGlutenFree, tags)
+		it.Equal([]string{"vegan", "gluten_free"}, tags.Names())
+	}
+
+	_, err = ParseDietaryTags([]string{"keto"})
+	it.EqualError(err, `unknown dietary tag "keto"`)
+}
+
+func TestIngredients(t *testing.T) {
+	t.Run("should be stored as json array", func(t *testing.T) {
+		it := assert.New(t)
+		value, err := Ingredients{"tomato", "mozzarella"}.Value()
+
+		if it.NoError(err) {
+			it.Equal(`["tomato","mozzarella"]`, value)
+		}
+
+		value, err = Ingredients(nil).Value()
+
+		if it.NoError(err) {
+			it.Equal("[]", value)
+		}
+	})
+
+	t.Run("should be scanned from json array", func(t *testing.T) {
+		it := assert.New(t)
+		var i Ingredients
+
+		if it.NoError(i.Scan([]byte(`["tomato","basil"]`))) {
+			it.Equal(Ingredients{"tomato", "basil"}, i)
+		}
+
+		it.Error(i.Scan(42))
+	})
+}
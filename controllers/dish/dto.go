@@ -1,13 +1,67 @@
 package dish
 
-import "food_ordering_backend/controllers/category"
+import (
+	"food_ordering_backend/common"
+	"food_ordering_backend/controllers/category"
+)
 
 type DTO struct {
+	ID          uint         `json:"id,omitempty"`
+	Title       string       `json:"title"`
+	Price       common.Money `json:"price"`
+	Image       *string      `json:"image,omitempty"`
+	Removable   bool         `json:"removable"`
+	CategoryID  uint         `json:"category_id"`
+	Category    category.DTO `json:"category"`
+	Description string       `json:"description" binding:"max=2000"`
+	Ingredients []string     `json:"ingredients" binding:"dive,required,max=100"`
+	// Allergens are names of the 14 EU allergens the dish contains.
+	Allergens   []string `json:"allergens" binding:"dive,oneof=celery gluten crustaceans eggs fish lupin milk molluscs mustard nuts peanuts sesame soy sulphites"`
+	DietaryTags []string `json:"dietary_tags" binding:"dive,oneof=vegetarian vegan gluten_free lactose_free"`
+	SpicyLevel  int      `json:"spicy_level" binding:"min=0,max=3"`
+	// Calories is energy value of a portion in kcal, null means unknown.
+	Calories *int `json:"calories" binding:"omitempty,min=0"`
+	// Available defaults to true if it's omitted.
+	Available *bool `json:"available"`
+	// Stock is the amount of portions left for today, null means unlimited.
+	Stock *int `json:"stock" binding:"omitempty,min=0"`
+	// SoldOut is true if the dish isn't available or there are no portions left.
+	// It's ignored in requests.
+	SoldOut bool `json:"sold_out"`
+	// Images are the gallery of the dish, the first one is the primary image.
+	// They are ignored in requests.
+	Images []ImageDTO `json:"images"`
+}
+
+type DTOsWithPagination struct {
+	Dishes     []DTO                `json:"dishes"`
+	Pagination common.PaginationDTO `json:"pagination"`
+}
+
+// OptionGroupDTO creates or replaces an option group of a dish.
+type OptionGroupDTO struct {
+	ID        uint        `json:"id,omitempty"`
+	Title     string      `json:"title" binding:"required,max=100"`
+	MinSelect int         `json:"min_select" binding:"min=0"`
+	MaxSelect int         `json:"max_select" binding:"required,min=1"`
+	Options   []OptionDTO `json:"options" binding:"required,gt=0,dive"`
+}
+
+// OptionDTO is an option of a group. When the group is replaced, existing
+// options are kept only if their ids are present in it, options without id
+// are created. PriceDelta defaults to the currency of the dish.
+type OptionDTO struct {
 	ID         uint         `json:"id,omitempty"`
-	Title      string       `json:"title"`
-	Price      float64      `json:"price" binding:"min=0"`
-	Image      *string      `json:"image,omitempty"`
-	Removable  bool         `json:"removable"`
-	CategoryID uint         `json:"category_id"`
-	Category   category.DTO `json:"category"`
+	Title      string       `json:"title" binding:"required,max=100"`
+	PriceDelta common.Money `json:"price_delta"`
+}
+
+type ImageDTO struct {
+	ID  uint   `json:"id"`
+	URL string `json:"url"`
+}
+
+// ImageOrderDTO lists ids of all images of the gallery in the new order.
+type ImageOrderDTO struct {
+	IDs []uint `json:"ids" binding:"required"`
 }
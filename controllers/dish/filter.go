@@ -0,0 +1,247 @@
+package dish
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SearchDocument builds a document for full-text search from columns of dishes table.
+// Expression index idx_dishes_search is built with it, so they must be changed together.
+const SearchDocument = "to_tsvector('simple', title || ' ' || description)"
+
+// sortColumns maps values of "sort" query parameter to columns.
+var sortColumns = map[string]string{
+	"id":    "dishes.id",
+	"title": "dishes.title",
+	"price": "dishes.price_amount",
+}
+
+// Filter narrows down and sorts a list of dishes.
+// Zero values of the fields are ignored.
+type Filter struct {
+	// Search matches dishes whose title or description contain all words from it.
+	// The last word can be incomplete.
+	Search      string
+	CategoryIDs []uint
+	// MinPrice and MaxPrice are amounts of minor units.
+	MinPrice *int64
+	MaxPrice *int64
+	// Available matches dishes that can be ordered if it's true and sold out ones if it's false.
+	Available *bool
+	// ExcludeAllergens matches dishes that contain none of the allergens.
+	ExcludeAllergens Allergens
+	// Tags matches dishes that have all of the tags.
+	Tags          DietaryTags
+	MaxSpicyLevel *int
+	// MaxCalories matches dishes with known calories that don't exceed it.
+	MaxCalories *int
+	// SortBy is one of the keys from sortColumns. Defaults to "id".
+	SortBy   string
+	SortDesc bool
+}
+
+// FilterFromQuery parses query parameters into Filter.
+//
+// Supported parameters: q, cid, exclude_allergens and tags (can be repeated
+// or comma-separated), min_price, max_price, available, max_spicy_level,
+// max_calories, sort and order (asc or desc).
+func FilterFromQuery(q url.Values) (Filter, error) {
+	var f Filter
+	var err error
+
+	f.Search = strings.TrimSpace(q.Get("q"))
+
+	for _, value := range q["cid"] {
+		for _, s := range strings.Split(value, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+
+			if err != nil {
+				return f, fmt.Errorf("invalid cid %q", s)
+			}
+
+			f.CategoryIDs = append(f.CategoryIDs, uint(id))
+		}
+	}
+
+	if f.MinPrice, err = parseAmount(q.Get("min_price")); err != nil {
+		return f, fmt.Errorf("invalid min_price: %w", err)
+	}
+
+	if f.MaxPrice, err = parseAmount(q.Get("max_price")); err != nil {
+		return f, fmt.Errorf("invalid max_price: %w", err)
+	}
+
+	if available := q.Get("available"); available != "" {
+		value, err := strconv.ParseBool(available)
+
+		if err != nil {
+			return f, fmt.Errorf("invalid available %q", available)
+		}
+
+		f.Available = &value
+	}
+
+	if f.ExcludeAllergens, err = ParseAllergens(splitValues(q["exclude_allergens"])); err != nil {
+		return f, fmt.Errorf("invalid exclude_allergens: %w", err)
+	}
+
+	if f.Tags, err = ParseDietaryTags(splitValues(q["tags"])); err != nil {
+		return f, fmt.Errorf("invalid tags: %w", err)
+	}
+
+	if f.MaxSpicyLevel, err = parseInt(q.Get("max_spicy_level")); err != nil {
+		return f, fmt.Errorf("invalid max_spicy_level: %w", err)
+	}
+
+	if f.MaxCalories, err = parseInt(q.Get("max_calories")); err != nil {
+		return f, fmt.Errorf("invalid max_calories: %w", err)
+	}
+
+	if f.SortBy = q.Get("sort"); f.SortBy != "" {
+		if _, ok := sortColumns[f.SortBy]; !ok {
+			return f, fmt.Errorf("can't sort by %q", f.SortBy)
+		}
+	}
+
+	switch strings.ToLower(q.Get("order")) {
+	case "", "asc":
+	case "desc":
+		f.SortDesc = true
+	default:
+		return f, fmt.Errorf("invalid order %q", q.Get("order"))
+	}
+
+	return f, nil
+}
+
+// Where applies filtering conditions to the query.
+func (f Filter) Where(db *gorm.DB) *gorm.DB {
+	if query := searchQuery(f.Search); query != "" {
+		db = db.Where("dishes.id IN (SELECT id FROM dishes WHERE "+SearchDocument+" @@ to_tsquery('simple', ?))", query)
+	}
+
+	if len(f.CategoryIDs) > 0 {
+		db = db.Where("dishes.category_id IN ?", f.CategoryIDs)
+	}
+
+	if f.MinPrice != nil {
+		db = db.Where("dishes.price_amount >= ?", *f.MinPrice)
+	}
+
+	if f.MaxPrice != nil {
+		db = db.Where("dishes.price_amount <= ?", *f.MaxPrice)
+	}
+
+	if f.Available != nil {
+		orderable := "dishes.available AND (dishes.stock IS NULL OR dishes.stock > 0)"
+
+		if *f.Available {
+			db = db.Where(orderable)
+		} else {
+			db = db.Where("NOT (" + orderable + ")")
+		}
+	}
+
+	if f.ExcludeAllergens != 0 {
+		db = db.Where("dishes.allergens & ? = 0", uint(f.ExcludeAllergens))
+	}
+
+	if f.Tags != 0 {
+		db = db.Where("dishes.dietary_tags & ? = ?", uint(f.Tags), uint(f.Tags))
+	}
+
+	if f.MaxSpicyLevel != nil {
+		db = db.Where("dishes.spicy_level <= ?", *f.MaxSpicyLevel)
+	}
+
+	if f.MaxCalories != nil {
+		db = db.Where("dishes.calories <= ?", *f.MaxCalories)
+	}
+
+	return db
+}
+
+// OrderBy applies sorting to the query. Dishes with equal values
+// of the sort column are sorted by id.
+func (f Filter) OrderBy(db *gorm.DB) *gorm.DB {
+	column, ok := sortColumns[f.SortBy]
+
+	if !ok {
+		column = sortColumns["id"]
+	}
+
+	direction := "ASC"
+	if f.SortDesc {
+		direction = "DESC"
+	}
+
+	db = db.Order(column + " " + direction)
+
+	if column != sortColumns["id"] {
+		db = db.Order("dishes.id ASC")
+	}
+
+	return db
+}
+
+// searchQuery turns user input into a tsquery that matches documents
+// containing all of its words as prefixes, e.g. "chick soup" becomes
+// "chick:* & soup:*". Returns an empty string if there are no words.
+func searchQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
+
+func parseAmount(value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &amount, nil
+}
+
+func parseInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// splitValues splits comma-separated values of a repeated query parameter.
+func splitValues(values []string) []string {
+	var split []string
+
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				split = append(split, s)
+			}
+		}
+	}
+
+	return split
+}
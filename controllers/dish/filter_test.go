@@ -0,0 +1,86 @@
+package dish
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestFilterFromQuery(t *testing.T) {
+	t.Run("should parse all parameters", func(t *testing.T) {
+		it := assert.New(t)
+		q, _ := url.ParseQuery("q=%20chicken%20soup%20&cid=1&cid=2,3&min_price=100&max_price=5000&available=false&exclude_allergens=peanuts,milk&exclude_allergens=nuts&tags=vegan&max_spicy_level=1&max_calories=600&sort=price&order=DESC")
+
+		f, err := FilterFromQuery(q)
+
+		if it.NoError(err) {
+			minPrice, maxPrice := int64(100), int64(5000)
+			available := false
+			maxSpicyLevel, maxCalories := 1, 600
+
+			it.Equal(Filter{
+				Search:           "chicken soup",
+				CategoryIDs:      []uint{1, 2, 3},
+				MinPrice:         &minPrice,
+				MaxPrice:         &maxPrice,
+				Available:        &available,
+				ExcludeAllergens: AllergenPeanuts | AllergenMilk | AllergenNuts,
+				Tags:             TagVegan,
+				MaxSpicyLevel:    &maxSpicyLevel,
+				MaxCalories:      &maxCalories,
+				SortBy:           "price",
+				SortDesc:         true,
+			}, f)
+		}
+	})
+
+	t.Run("should return empty filter if there are no parameters", func(t *testing.T) {
+		it := assert.New(t)
+		f, err := FilterFromQuery(url.Values{})
+
+		if it.NoError(err) {
+			it.Zero(f)
+		}
+	})
+
+	t.Run("should return error if parameters are invalid", func(t *testing.T) {
+		queries := []string{
+			"cid=abc",
+			"cid=1,",
+			"min_price=10.5",
+			"max_price=abc",
+			"available=yes",
+			"exclude_allergens=bacon",
+			"tags=vegan,keto",
+			"max_spicy_level=hot",
+			"max_calories=1.5",
+			"sort=category_id",
+			"order=up",
+		}
+
+		for _, query := range queries {
+			q, _ := url.ParseQuery(query)
+			_, err := FilterFromQuery(q)
+			assert.Error(t, err, query)
+		}
+	})
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		search   string
+		expected string
+	}{
+		{"salad", "salad:*"},
+		{" Chicken   soup ", "Chicken:* & soup:*"},
+		{"fish & chips | !beer", "fish:* & chips:* & beer:*"},
+		{"Orange juice 2L", "Orange:* & juice:* & 2L:*"},
+		{"café", "café:*"},
+		{" '&:* ", ""},
+		{"", ""},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, searchQuery(tc.search), tc.search)
+	}
+}
@@ -0,0 +1,153 @@
+package dish
+
+import (
+	"food_ordering_backend/common"
+	"gorm.io/gorm"
+)
+
+// ImageService manages galleries of dishes. It changes only rows in db,
+// saving and removing files is up to the caller.
+type ImageService struct {
+	repo       *Repository
+	transactor *common.Transactor
+}
+
+func ProvideImageService(r *Repository, transactor *common.Transactor) *ImageService {
+	return &ImageService{r, transactor}
+}
+
+// Add appends an image with provided file name to the gallery of the dish.
+// The first image of the gallery becomes the primary one.
+func (s *ImageService) Add(d Dish, name string) (Image, error) {
+	var img Image
+
+	err := s.inGallery(d, func(repo *Repository, images []Image) error {
+		var err error
+
+		if img, err = repo.CreateImage(Image{DishID: d.ID, Name: name, Position: len(images)}); err != nil {
+			return err
+		}
+
+		return savePositions(repo, d.ID, append(images, img))
+	})
+
+	return img, err
+}
+
+// ReplacePrimary replaces the file of the primary image with provided one or
+// adds the image if the gallery is empty. Returns the previous primary image,
+// which is nil if there was none.
+func (s *ImageService) ReplacePrimary(d Dish, name string) (*Image, error) {
+	var previous *Image
+
+	err := s.inGallery(d, func(repo *Repository, images []Image) error {
+		if len(images) == 0 {
+			img, err := repo.CreateImage(Image{DishID: d.ID, Name: name})
+
+			if err != nil {
+				return err
+			}
+
+			return savePositions(repo, d.ID, []Image{img})
+		}
+
+		primary := images[0]
+		previous = &primary
+		images[0].Name = name
+
+		if err := repo.UpdateImage(images[0]); err != nil {
+			return err
+		}
+
+		return savePositions(repo, d.ID, images)
+	})
+
+	return previous, err
+}
+
+// Delete deletes the image with provided id from the gallery of the dish and
+// returns it. The next image becomes the primary one if the image was primary.
+// Returns ErrImageID if the dish doesn't have such image.
+func (s *ImageService) Delete(d Dish, id uint) (Image, error) {
+	var deleted Image
+
+	err := s.inGallery(d, func(repo *Repository, images []Image) error {
+		for i, img := range images {
+			if img.ID != id {
+				continue
+			}
+
+			if err := repo.DeleteImage(img); err != nil {
+				return err
+			}
+
+			deleted = img
+			return savePositions(repo, d.ID, append(images[:i:i], images[i+1:]...))
+		}
+
+		return &ErrImageID{ID: id}
+	})
+
+	return deleted, err
+}
+
+// Reorder sorts the gallery of the dish as ids are, the first image becomes
+// the primary one. Returns ErrInvalidImageOrder unless ids list every image
+// of the gallery exactly once.
+func (s *ImageService) Reorder(d Dish, ids []uint) ([]Image, error) {
+	var ordered []Image
+
+	err := s.inGallery(d, func(repo *Repository, images []Image) error {
+		var err error
+
+		if ordered, err = Reorder(images, ids); err != nil {
+			return err
+		}
+
+		return savePositions(repo, d.ID, ordered)
+	})
+
+	return ordered, err
+}
+
+// inGallery calls fn in a transaction with the gallery of the dish,
+// which stays locked until the transaction ends.
+func (s *ImageService) inGallery(d Dish, fn func(repo *Repository, images []Image) error) error {
+	return s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.LockDish(d.ID); err != nil {
+			return err
+		}
+
+		images, err := repo.FindImages(d.ID)
+
+		if err != nil {
+			return err
+		}
+
+		return fn(repo, images)
+	})
+}
+
+// savePositions numbers images in the order they are provided
+// and makes the first of them the primary image of the dish.
+func savePositions(repo *Repository, dishID uint, images []Image) error {
+	for i := range images {
+		if images[i].Position == i {
+			continue
+		}
+
+		images[i].Position = i
+
+		if err := repo.UpdateImage(images[i]); err != nil {
+			return err
+		}
+	}
+
+	if len(images) == 0 {
+		return repo.SetImage(dishID, nil)
+	}
+
+	return repo.SetImage(dishID, &images[0].Name)
+}
@@ -0,0 +1,60 @@
+package dish
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Image is a picture in the gallery of a dish. Images are shown in order of
+// their positions, the first one is the primary image of the dish.
+type Image struct {
+	ID       uint   `gorm:"primaryKey"`
+	DishID   uint   `gorm:"not null;index"`
+	Dish     Dish   `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Name     string `gorm:"size:255;not null"`
+	Position int    `gorm:"not null;default:0"`
+}
+
+// ErrImageID is returned when the dish doesn't have an image with provided id.
+type ErrImageID struct {
+	ID uint
+}
+
+func (e *ErrImageID) Error() string {
+	return fmt.Sprintf("Image with id %d doesn't exist", e.ID)
+}
+
+var ErrInvalidImageOrder = errors.New("invalid order of images")
+
+func (i Image) TableName() string {
+	return "dish_images"
+}
+
+// Reorder returns images sorted as ids are. Returns ErrInvalidImageOrder
+// unless ids list every image of the gallery exactly once.
+func Reorder(images []Image, ids []uint) ([]Image, error) {
+	if len(ids) != len(images) {
+		return nil, fmt.Errorf("%w: expected %d ids, got %d", ErrInvalidImageOrder, len(images), len(ids))
+	}
+
+	byID := make(map[uint]Image, len(images))
+
+	for _, img := range images {
+		byID[img.ID] = img
+	}
+
+	ordered := make([]Image, 0, len(ids))
+
+	for _, id := range ids {
+		img, ok := byID[id]
+
+		if !ok {
+			return nil, fmt.Errorf("%w: image %d is repeated or doesn't exist", ErrInvalidImageOrder, id)
+		}
+
+		ordered = append(ordered, img)
+		delete(byID, id)
+	}
+
+	return ordered, nil
+}
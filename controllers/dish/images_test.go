@@ -0,0 +1,60 @@
+package dish
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testImages = []Image{
+	{ID: 1, DishID: 6, Name: "6.png", Position: 0},
+	{ID: 2, DishID: 6, Name: "6-1.png", Position: 1},
+	{ID: 3, DishID: 6, Name: "6-2.png", Position: 2},
+}
+
+func TestReorder(t *testing.T) {
+	t.Run("should return images sorted as ids are", func(t *testing.T) {
+		it := assert.New(t)
+
+		ordered, err := Reorder(testImages, []uint{3, 1, 2})
+
+		if it.NoError(err) {
+			it.Equal([]Image{testImages[2], testImages[0], testImages[1]}, ordered)
+		}
+	})
+
+	t.Run("should return ErrInvalidImageOrder unless ids list every image once", func(t *testing.T) {
+		tests := []struct {
+			reason string
+			ids    []uint
+		}{
+			{"ids are empty", nil},
+			{"image is skipped", []uint{1, 2}},
+			{"image is repeated", []uint{1, 2, 2}},
+			{"image doesn't belong to the gallery", []uint{1, 2, 42}},
+		}
+
+		for _, tc := range tests {
+			_, err := Reorder(testImages, tc.ids)
+			assert.ErrorIsf(t, err, ErrInvalidImageOrder, "expected error if %s", tc.reason)
+		}
+	})
+}
+
+func TestDish_imageNames(t *testing.T) {
+	primary := "6.png"
+	legacy := "6.webp"
+
+	tests := []struct {
+		reason   string
+		dish     Dish
+		expected []string
+	}{
+		{"dish doesn't have images", Dish{}, nil},
+		{"primary image is in the gallery", Dish{Image: &primary, Images: testImages}, []string{"6.png", "6-1.png", "6-2.png"}},
+		{"primary image isn't in the gallery", Dish{Image: &legacy, Images: testImages[:1]}, []string{"6.png", "6.webp"}},
+	}
+
+	for _, tc := range tests {
+		assert.Equalf(t, tc.expected, tc.dish.imageNames(), "unexpected names if %s", tc.reason)
+	}
+}
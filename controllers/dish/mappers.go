@@ -3,24 +3,46 @@ package dish
 import (
 	"food_ordering_backend/controllers/category"
 	"path"
+	"strings"
 )
 
 func ToModel(dto DTO) Dish {
 	image := dto.Image
+	var images []Image
 
 	if image != nil {
 		name := path.Base(*image)
 		image = &name
+		images = []Image{{Name: name}}
 	}
 
+	available := true
+
+	if dto.Available != nil {
+		available = *dto.Available
+	}
+
+	// names are validated by binding tags of DTO
+	allergens, _ := ParseAllergens(dto.Allergens)
+	tags, _ := ParseDietaryTags(dto.DietaryTags)
+
 	return Dish{
-		ID:         dto.ID,
-		Title:      dto.Title,
-		CategoryID: dto.CategoryID,
-		Price:      dto.Price,
-		Image:      image,
-		Removable:  dto.Removable,
-		Category:   category.ToModel(dto.Category),
+		ID:          dto.ID,
+		Title:       dto.Title,
+		CategoryID:  dto.CategoryID,
+		Price:       dto.Price,
+		Image:       image,
+		Images:      images,
+		Removable:   dto.Removable,
+		Category:    category.ToModel(dto.Category),
+		Description: dto.Description,
+		Ingredients: Ingredients(dto.Ingredients),
+		Allergens:   allergens,
+		DietaryTags: tags,
+		SpicyLevel:  dto.SpicyLevel,
+		Calories:    dto.Calories,
+		Available:   available,
+		Stock:       dto.Stock,
 	}
 }
 
@@ -32,14 +54,30 @@ func ToDTO(d Dish) DTO {
 		image = &uri
 	}
 
+	ingredients := []string(d.Ingredients)
+
+	if ingredients == nil {
+		ingredients = make([]string, 0)
+	}
+
 	return DTO{
-		ID:         d.ID,
-		Title:      d.Title,
-		Price:      d.Price,
-		CategoryID: d.CategoryID,
-		Image:      image,
-		Removable:  d.Removable,
-		Category:   category.ToDTO(d.Category),
+		ID:          d.ID,
+		Title:       d.Title,
+		Price:       d.Price,
+		CategoryID:  d.CategoryID,
+		Image:       image,
+		Images:      ToImageDTOs(d.Images),
+		Removable:   d.Removable,
+		Category:    category.ToDTO(d.Category),
+		Description: d.Description,
+		Ingredients: ingredients,
+		Allergens:   d.Allergens.Names(),
+		DietaryTags: d.DietaryTags.Names(),
+		SpicyLevel:  d.SpicyLevel,
+		Calories:    d.Calories,
+		Available:   &d.Available,
+		Stock:       d.Stock,
+		SoldOut:     !d.IsOrderable(),
 	}
 }
 
@@ -52,3 +90,67 @@ func ToDTOs(dishes []Dish) []DTO {
 
 	return dtos
 }
+
+func ToImageDTO(img Image) ImageDTO {
+	return ImageDTO{ID: img.ID, URL: PathToImg(img.Name)}
+}
+
+func ToImageDTOs(images []Image) []ImageDTO {
+	dtos := make([]ImageDTO, len(images))
+
+	for i, img := range images {
+		dtos[i] = ToImageDTO(img)
+	}
+
+	return dtos
+}
+
+func OptionGroupFromDTO(dto OptionGroupDTO) OptionGroup {
+	options := make([]Option, len(dto.Options))
+
+	for i, o := range dto.Options {
+		options[i] = Option{
+			ID:         o.ID,
+			Title:      strings.TrimSpace(o.Title),
+			PriceDelta: o.PriceDelta,
+		}
+	}
+
+	return OptionGroup{
+		ID:        dto.ID,
+		Title:     strings.TrimSpace(dto.Title),
+		MinSelect: dto.MinSelect,
+		MaxSelect: dto.MaxSelect,
+		Options:   options,
+	}
+}
+
+func ToOptionGroupDTO(g OptionGroup) OptionGroupDTO {
+	options := make([]OptionDTO, len(g.Options))
+
+	for i, o := range g.Options {
+		options[i] = OptionDTO{
+			ID:         o.ID,
+			Title:      o.Title,
+			PriceDelta: o.PriceDelta,
+		}
+	}
+
+	return OptionGroupDTO{
+		ID:        g.ID,
+		Title:     g.Title,
+		MinSelect: g.MinSelect,
+		MaxSelect: g.MaxSelect,
+		Options:   options,
+	}
+}
+
+func ToOptionGroupDTOs(groups []OptionGroup) []OptionGroupDTO {
+	dtos := make([]OptionGroupDTO, len(groups))
+
+	for i, g := range groups {
+		dtos[i] = ToOptionGroupDTO(g)
+	}
+
+	return dtos
+}
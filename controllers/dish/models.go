@@ -1,34 +1,72 @@
 package dish
 
 import (
-	"food_ordering_backend/config"
+	"fmt"
+	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/category"
-	"gorm.io/gorm"
-	"log"
-	"os"
-	"path/filepath"
 )
 
 type Dishes []Dish
 
 type Dish struct {
-	ID         uint    `gorm:"primaryKey"`
-	Title      string  `gorm:"size:100;unique;not null"`
-	Price      float64 `gorm:"check:price >= 0"`
+	ID         uint         `gorm:"primaryKey"`
+	Title      string       `gorm:"size:100;unique;not null"`
+	Price      common.Money `gorm:"embedded;embeddedPrefix:price_;check:chk_dishes_price_amount,price_amount >= 0"`
 	Image      *string
 	Removable  bool `gorm:"default:true"`
 	CategoryID uint
 	Category   category.Category `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	// Images are the gallery of the dish sorted by position.
+	// Image is the name of the first of them, the primary image.
+	Images []Image
+	// Description is searched along with the title.
+	Description string      `gorm:"size:2000;not null;default:''"`
+	Ingredients Ingredients `gorm:"type:jsonb;not null;default:'[]'"`
+	Allergens   Allergens   `gorm:"type:integer;not null;default:0"`
+	DietaryTags DietaryTags `gorm:"type:integer;not null;default:0"`
+	// SpicyLevel is from 0, which means not spicy, to MaxSpicyLevel.
+	SpicyLevel int `gorm:"type:smallint;not null;default:0;check:chk_dishes_spicy_level,spicy_level BETWEEN 0 AND 3"`
+	// Calories is energy value of a portion in kcal or nil if it's unknown.
+	Calories *int `gorm:"check:chk_dishes_calories,calories >= 0"`
+	// Available is false when the dish is temporarily off the menu.
+	// It has no default, so that GORM doesn't replace false with it on create.
+	Available bool `gorm:"not null"`
+	// Stock is the amount of portions left for today or nil if it's unlimited.
+	// It's decreased when orders are created and restored when they are canceled.
+	Stock *int `gorm:"check:chk_dishes_stock,stock >= 0"`
 }
 
-func (d *Dish) AfterDelete(tx *gorm.DB) (err error) {
-	if d.Image != nil {
-		err = os.Remove(filepath.Join(config.DishesImgDirAbs, *d.Image))
-		if err != nil {
-			log.Println("[Dish] Error deleting image:", err)
-		}
+// ErrInsufficientStock means that the dish with ID is unavailable
+// or doesn't have enough portions left.
+type ErrInsufficientStock struct {
+	ID uint
+}
+
+func (e *ErrInsufficientStock) Error() string {
+	return fmt.Sprintf("Dish with id %d is sold out or doesn't have enough portions left", e.ID)
+}
+
+// IsOrderable reports whether the dish is available and isn't sold out.
+func (d Dish) IsOrderable() bool {
+	return d.Available && (d.Stock == nil || *d.Stock > 0)
+}
+
+// imageNames returns names of images in the gallery along with
+// the primary image, in case the dish has one without a gallery.
+func (d Dish) imageNames() []string {
+	var names []string
+	hasPrimary := d.Image == nil
+
+	for _, img := range d.Images {
+		names = append(names, img.Name)
+		hasPrimary = hasPrimary || img.Name == *d.Image
 	}
-	return
+
+	if !hasPrimary {
+		names = append(names, *d.Image)
+	}
+
+	return names
 }
 
 func (dishes Dishes) Find(lookup func(d Dish, index int) bool) (Dish, bool) {
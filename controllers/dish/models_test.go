@@ -1,8 +1,10 @@
 package dish
 
 import (
+	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/category"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 )
 
@@ -13,14 +15,14 @@ var TestCategories = []category.Category{
 	{ID: 4, Title: "Drinks", Removable: true},
 }
 var TestDishes = []Dish{
-	{ID: 1, Title: "Fresh and Healthy Salad", Price: 2.65, CategoryID: 1, Category: TestCategories[0]},
-	{ID: 2, Title: "Crunchy Cashew Salad", Price: 3.22, CategoryID: 1, Category: TestCategories[0]},
-	{ID: 3, Title: "Hamburger", Price: 1.99, CategoryID: 2, Category: TestCategories[1]},
-	{ID: 4, Title: "Cheeseburger", Price: 2.28, CategoryID: 2, Category: TestCategories[1]},
-	{ID: 5, Title: "Margherita", Price: 4.20, CategoryID: 3, Category: TestCategories[2]},
-	{ID: 6, Title: "4 Cheese", Price: 4.69, CategoryID: 3, Category: TestCategories[2]},
-	{ID: 7, Title: "Pepsi 2L", Price: 1.50, CategoryID: 4, Category: TestCategories[3]},
-	{ID: 8, Title: "Orange Juice 2L", Price: 2, CategoryID: 4, Category: TestCategories[3]},
+	{ID: 1, Title: "Fresh and Healthy Salad", Price: common.NewMoney(265), CategoryID: 1, Category: TestCategories[0]},
+	{ID: 2, Title: "Crunchy Cashew Salad", Price: common.NewMoney(322), CategoryID: 1, Category: TestCategories[0]},
+	{ID: 3, Title: "Hamburger", Price: common.NewMoney(199), CategoryID: 2, Category: TestCategories[1]},
+	{ID: 4, Title: "Cheeseburger", Price: common.NewMoney(228), CategoryID: 2, Category: TestCategories[1]},
+	{ID: 5, Title: "Margherita", Price: common.NewMoney(420), CategoryID: 3, Category: TestCategories[2]},
+	{ID: 6, Title: "4 Cheese", Price: common.NewMoney(469), CategoryID: 3, Category: TestCategories[2]},
+	{ID: 7, Title: "Pepsi 2L", Price: common.NewMoney(150), CategoryID: 4, Category: TestCategories[3]},
+	{ID: 8, Title: "Orange Juice 2L", Price: common.NewMoney(200), CategoryID: 4, Category: TestCategories[3]},
 }
 
 func TestDishes_Find(t *testing.T) {
@@ -77,7 +79,7 @@ func TestDishes_Find(t *testing.T) {
 				return d.CategoryID == 56
 			},
 			func(d Dish, index int) bool {
-				return d.Price == 56
+				return d.Price.Amount == 56
 			},
 		}
 		for _, lookup := range fns {
@@ -87,3 +89,26 @@ func TestDishes_Find(t *testing.T) {
 		}
 	})
 }
+
+func TestDish_IsOrderable(t *testing.T) {
+	intPtr := func(i int) *int {
+		return &i
+	}
+	tests := []struct {
+		name     string
+		dish     Dish
+		expected bool
+	}{
+		{"available with unlimited stock", Dish{Available: true}, true},
+		{"available with portions left", Dish{Available: true, Stock: intPtr(2)}, true},
+		{"available without portions left", Dish{Available: true, Stock: intPtr(0)}, false},
+		{"unavailable", Dish{Stock: intPtr(2)}, false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run("should be "+strconv.FormatBool(tc.expected)+" if dish is "+tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.dish.IsOrderable())
+		})
+	}
+}
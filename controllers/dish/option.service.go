@@ -0,0 +1,136 @@
+package dish
+
+import (
+	"errors"
+	"food_ordering_backend/common"
+	"gorm.io/gorm"
+)
+
+// OptionService manages option groups of dishes.
+type OptionService struct {
+	repo       *Repository
+	transactor *common.Transactor
+}
+
+func ProvideOptionService(r *Repository, transactor *common.Transactor) *OptionService {
+	return &OptionService{r, transactor}
+}
+
+// FindAll returns option groups of the dish along with their options.
+func (s *OptionService) FindAll(d Dish) ([]OptionGroup, error) {
+	return s.repo.FindOptionGroups(d.ID)
+}
+
+// Create adds an option group to the dish. Returns ErrInvalidOptionGroup
+// if the group is invalid and common.ErrCurrencyMismatch if its options
+// are priced in a currency other than the one of the dish.
+func (s *OptionService) Create(d Dish, dto OptionGroupDTO) (OptionGroup, error) {
+	g := OptionGroupFromDTO(dto)
+	g.ID = 0
+	g.DishID = d.ID
+
+	for i := range g.Options {
+		g.Options[i].ID = 0
+	}
+
+	if err := prepareOptionGroup(&g, d); err != nil {
+		return OptionGroup{}, err
+	}
+
+	return s.repo.CreateOptionGroup(g)
+}
+
+// Update replaces option group of the dish with provided id. Options that
+// aren't present in dto are deleted, orders keep their copies of them.
+// Returns ErrOptionGroupID if the dish doesn't have such group and ErrOptionID
+// if any of options in dto doesn't belong to the group.
+func (s *OptionService) Update(d Dish, id uint, dto OptionGroupDTO) (OptionGroup, error) {
+	updated := OptionGroupFromDTO(dto)
+	updated.ID = id
+	updated.DishID = d.ID
+
+	if err := prepareOptionGroup(&updated, d); err != nil {
+		return OptionGroup{}, err
+	}
+
+	var g OptionGroup
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		var err error
+		repo := s.repo.WithTx(tx)
+
+		if g, err = repo.FindOptionGroup(d.ID, id); err != nil {
+			return err
+		}
+
+		existing := make(map[uint]bool, len(g.Options))
+
+		for _, o := range g.Options {
+			existing[o.ID] = true
+		}
+
+		var keep []uint
+
+		for i, o := range updated.Options {
+			if o.ID == 0 {
+				continue
+			}
+
+			if !existing[o.ID] {
+				return &ErrOptionID{ID: o.ID}
+			}
+
+			updated.Options[i].GroupID = g.ID
+			keep = append(keep, o.ID)
+		}
+
+		if err = repo.DeleteOptions(g.ID, keep); err != nil {
+			return err
+		}
+
+		g, err = repo.SaveOptionGroup(updated)
+		return err
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return OptionGroup{}, &ErrOptionGroupID{ID: id}
+	}
+
+	return g, err
+}
+
+// Delete deletes option group of the dish with provided id along with its options.
+// Returns ErrOptionGroupID if the dish doesn't have such group.
+func (s *OptionService) Delete(d Dish, id uint) error {
+	g, err := s.repo.FindOptionGroup(d.ID, id)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &ErrOptionGroupID{ID: id}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteOptionGroup(g)
+}
+
+// prepareOptionGroup validates the group and prices its options
+// in the currency of the dish unless they have a currency.
+func prepareOptionGroup(g *OptionGroup, d Dish) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	for i, o := range g.Options {
+		if o.PriceDelta.Currency == "" {
+			g.Options[i].PriceDelta.Currency = d.Price.Currency
+		}
+
+		if g.Options[i].PriceDelta.Currency != d.Price.Currency {
+			return common.ErrCurrencyMismatch
+		}
+	}
+
+	return nil
+}
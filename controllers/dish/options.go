@@ -0,0 +1,137 @@
+package dish
+
+import (
+	"errors"
+	"fmt"
+	"food_ordering_backend/common"
+)
+
+type OptionGroups []OptionGroup
+
+// OptionGroup is a set of options of a dish, e.g. sizes or toppings. Customers
+// choose from MinSelect to MaxSelect options of the group for every ordered dish.
+// Groups with MinSelect of 0 are optional.
+type OptionGroup struct {
+	ID        uint     `gorm:"primaryKey"`
+	DishID    uint     `gorm:"not null;index"`
+	Dish      Dish     `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Title     string   `gorm:"size:100;not null"`
+	MinSelect int      `gorm:"not null;check:chk_dish_option_groups_min_select,min_select >= 0"`
+	MaxSelect int      `gorm:"not null;check:chk_dish_option_groups_max_select,max_select >= min_select AND max_select > 0"`
+	Options   []Option `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// Option is a modifier of a dish, e.g. "Large" or "Extra cheese".
+// PriceDelta is added to the price of the dish when the option is chosen.
+type Option struct {
+	ID         uint         `gorm:"primaryKey"`
+	GroupID    uint         `gorm:"not null;index"`
+	Title      string       `gorm:"size:100;not null"`
+	PriceDelta common.Money `gorm:"embedded;embeddedPrefix:price_delta_;check:chk_dish_options_price_delta_amount,price_delta_amount >= 0"`
+}
+
+// Choice is an option chosen by a customer along with the title of its group.
+type Choice struct {
+	GroupTitle string
+	Option     Option
+}
+
+// ErrOptionGroupID is returned when the dish doesn't have an option group with provided id.
+type ErrOptionGroupID struct {
+	ID uint
+}
+
+func (e *ErrOptionGroupID) Error() string {
+	return fmt.Sprintf("Option group with id %d doesn't exist", e.ID)
+}
+
+// ErrOptionID is returned when the option group doesn't have an option with provided id.
+type ErrOptionID struct {
+	ID uint
+}
+
+func (e *ErrOptionID) Error() string {
+	return fmt.Sprintf("Option with id %d doesn't exist in the group", e.ID)
+}
+
+var ErrInvalidOptionGroup = errors.New("invalid option group")
+var ErrInvalidChoice = errors.New("invalid choice of options")
+
+func (g OptionGroup) TableName() string {
+	return "dish_option_groups"
+}
+
+func (o Option) TableName() string {
+	return "dish_options"
+}
+
+// Validate checks that the group has a title, at least one option and
+// selection limits that can be satisfied with its options.
+func (g OptionGroup) Validate() error {
+	switch {
+	case g.Title == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidOptionGroup)
+	case len(g.Options) == 0:
+		return fmt.Errorf("%w: at least one option is required", ErrInvalidOptionGroup)
+	case g.MinSelect < 0:
+		return fmt.Errorf("%w: min_select can't be negative", ErrInvalidOptionGroup)
+	case g.MaxSelect < 1:
+		return fmt.Errorf("%w: max_select must be positive", ErrInvalidOptionGroup)
+	case g.MinSelect > g.MaxSelect:
+		return fmt.Errorf("%w: min_select can't be greater than max_select", ErrInvalidOptionGroup)
+	case g.MaxSelect > len(g.Options):
+		return fmt.Errorf("%w: max_select can't be greater than the number of options", ErrInvalidOptionGroup)
+	}
+
+	for _, o := range g.Options {
+		switch {
+		case o.Title == "":
+			return fmt.Errorf("%w: title of option is required", ErrInvalidOptionGroup)
+		case o.PriceDelta.IsNegative():
+			return fmt.Errorf("%w: price delta of %q can't be negative", ErrInvalidOptionGroup, o.Title)
+		}
+	}
+
+	return nil
+}
+
+// Choose returns options with provided ids along with titles of their groups,
+// ordered as the groups and their options are. Returns ErrInvalidChoice
+// if any of ids doesn't belong to the groups, is repeated or if the amount
+// of options chosen from any group is out of its limits.
+func (groups OptionGroups) Choose(optionIDs []uint) ([]Choice, error) {
+	chosen := make(map[uint]bool, len(optionIDs))
+
+	for _, id := range optionIDs {
+		if chosen[id] {
+			return nil, fmt.Errorf("%w: option %d is chosen more than once", ErrInvalidChoice, id)
+		}
+		chosen[id] = true
+	}
+
+	var choices []Choice
+
+	for _, g := range groups {
+		count := 0
+
+		for _, o := range g.Options {
+			if chosen[o.ID] {
+				choices = append(choices, Choice{GroupTitle: g.Title, Option: o})
+				delete(chosen, o.ID)
+				count++
+			}
+		}
+
+		if count < g.MinSelect || count > g.MaxSelect {
+			return nil, fmt.Errorf("%w: %q requires from %d to %d options", ErrInvalidChoice, g.Title, g.MinSelect, g.MaxSelect)
+		}
+	}
+
+	for _, id := range optionIDs {
+		if chosen[id] {
+			return nil, fmt.Errorf("%w: option %d doesn't exist", ErrInvalidChoice, id)
+		}
+	}
+
+	return choices, nil
+}
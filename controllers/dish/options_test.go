@@ -0,0 +1,102 @@
+package dish
+
+import (
+	"food_ordering_backend/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testOptionGroups = OptionGroups{
+	{
+		ID: 1, Title: "Size", MinSelect: 1, MaxSelect: 1,
+		Options: []Option{
+			{ID: 1, GroupID: 1, Title: "Small"},
+			{ID: 2, GroupID: 1, Title: "Large", PriceDelta: common.NewMoney(150)},
+		},
+	},
+	{
+		ID: 2, Title: "Extras", MinSelect: 0, MaxSelect: 2,
+		Options: []Option{
+			{ID: 3, GroupID: 2, Title: "Extra cheese", PriceDelta: common.NewMoney(50)},
+			{ID: 4, GroupID: 2, Title: "No onions"},
+			{ID: 5, GroupID: 2, Title: "Jalapenos", PriceDelta: common.NewMoney(30)},
+		},
+	},
+}
+
+func TestOptionGroup_Validate(t *testing.T) {
+	valid := func() OptionGroup {
+		return OptionGroup{
+			Title:     "Extras",
+			MinSelect: 0,
+			MaxSelect: 2,
+			Options:   []Option{{Title: "Extra cheese", PriceDelta: common.NewMoney(50)}, {Title: "No onions"}},
+		}
+	}
+
+	t.Run("should accept valid group", func(t *testing.T) {
+		assert.NoError(t, valid().Validate())
+	})
+
+	t.Run("should return ErrInvalidOptionGroup if group is invalid", func(t *testing.T) {
+		tests := []struct {
+			reason string
+			modify func(g *OptionGroup)
+		}{
+			{"title is empty", func(g *OptionGroup) { g.Title = "" }},
+			{"there are no options", func(g *OptionGroup) { g.Options = nil }},
+			{"min is negative", func(g *OptionGroup) { g.MinSelect = -1 }},
+			{"max is zero", func(g *OptionGroup) { g.MaxSelect = 0 }},
+			{"min is greater than max", func(g *OptionGroup) { g.MinSelect, g.MaxSelect = 2, 1 }},
+			{"max is greater than the number of options", func(g *OptionGroup) { g.MaxSelect = 3 }},
+			{"option title is empty", func(g *OptionGroup) { g.Options[1].Title = "" }},
+			{"price delta is negative", func(g *OptionGroup) { g.Options[0].PriceDelta = common.NewMoney(-50) }},
+		}
+
+		for _, tc := range tests {
+			g := valid()
+			tc.modify(&g)
+			assert.ErrorIsf(t, g.Validate(), ErrInvalidOptionGroup, "expected error if %s", tc.reason)
+		}
+	})
+}
+
+func TestOptionGroups_Choose(t *testing.T) {
+	t.Run("should return chosen options in order of groups", func(t *testing.T) {
+		it := assert.New(t)
+
+		choices, err := testOptionGroups.Choose([]uint{5, 2, 3})
+
+		if it.NoError(err) {
+			it.Equal([]Choice{
+				{GroupTitle: "Size", Option: testOptionGroups[0].Options[1]},
+				{GroupTitle: "Extras", Option: testOptionGroups[1].Options[0]},
+				{GroupTitle: "Extras", Option: testOptionGroups[1].Options[2]},
+			}, choices)
+		}
+	})
+
+	t.Run("should accept no options if dish doesn't have groups", func(t *testing.T) {
+		choices, err := OptionGroups(nil).Choose(nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, choices)
+	})
+
+	t.Run("should return ErrInvalidChoice if choice is illegal", func(t *testing.T) {
+		tests := []struct {
+			reason string
+			ids    []uint
+		}{
+			{"required group is skipped", []uint{3}},
+			{"too many options are chosen from a group", []uint{1, 2}},
+			{"option is repeated", []uint{1, 3, 3}},
+			{"option doesn't belong to the groups", []uint{1, 42}},
+		}
+
+		for _, tc := range tests {
+			_, err := testOptionGroups.Choose(tc.ids)
+			assert.ErrorIsf(t, err, ErrInvalidChoice, "expected error if %s", tc.reason)
+		}
+	})
+}
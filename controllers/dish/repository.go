@@ -1,7 +1,9 @@
 package dish
 
 import (
+	"food_ordering_backend/common"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository struct {
@@ -12,6 +14,11 @@ func ProvideRepository(db *gorm.DB) *Repository {
 	return &Repository{db}
 }
 
+// WithTx returns a copy of the repository that runs all queries in provided transaction.
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{tx}
+}
+
 func (r *Repository) Create(d Dish) (Dish, error) {
 	err := r.db.Create(&d).Error
 
@@ -24,8 +31,9 @@ func (r *Repository) Create(d Dish) (Dish, error) {
 	return d, err
 }
 
+// Save updates the dish except its images, which are changed by ImageService.
 func (r *Repository) Save(d Dish) (Dish, error) {
-	err := r.preload().Save(&d).Error
+	err := r.preload().Omit("Image", "Images").Save(&d).Error
 
 	if err != nil {
 		return d, err
@@ -41,23 +49,133 @@ func (r *Repository) FindByID(id uint) (Dish, error) {
 	return d, err
 }
 
+// FindByIDs returns dishes with provided ids along with their categories,
+// which are needed to snapshot dishes in orders.
 func (r *Repository) FindByIDs(ids []uint) ([]Dish, error) {
 	var dishes []Dish
-	err := r.db.Find(&dishes, ids).Error
+	err := r.db.Joins("Category").Find(&dishes, ids).Error
 	return dishes, err
 }
 
-func (r *Repository) FindAll(cid uint) []Dish {
+// FindAll returns all dishes matching the filter, sorted as the filter specifies.
+// If Paginator is not nil, it returns paginated result.
+func (r *Repository) FindAll(f Filter, p common.Paginator) ([]Dish, error) {
 	var dishes []Dish
-	tx := r.preload().Order("id ASC")
+	tx := r.preload().Scopes(f.Where, f.OrderBy)
+
+	if p != nil {
+		tx = tx.Scopes(common.WithPagination(p))
+	}
+
+	err := tx.Find(&dishes).Error
+	return dishes, err
+}
+
+func (r *Repository) CountAll(f Filter) int {
+	var count int64
+	r.db.Model(&Dish{}).Scopes(f.Where).Count(&count)
+	return int(count)
+}
+
+// DecreaseStock takes quantity portions of the dish with provided id, unless its
+// stock is unlimited. Returns false if the dish doesn't exist, isn't available
+// or doesn't have enough portions left.
+func (r *Repository) DecreaseStock(id uint, quantity int) (bool, error) {
+	res := r.db.Model(&Dish{}).
+		Where("id = ? AND available AND (stock IS NULL OR stock >= ?)", id, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	return res.RowsAffected > 0, res.Error
+}
+
+// IncreaseStock returns quantity portions of the dish with provided id
+// to its stock, unless the stock is unlimited.
+func (r *Repository) IncreaseStock(id uint, quantity int) error {
+	return r.db.Model(&Dish{}).
+		Where("id = ? AND stock IS NOT NULL", id).
+		Update("stock", gorm.Expr("stock + ?", quantity)).Error
+}
+
+// FindOptionGroups returns option groups of dishes with provided ids
+// along with their options, both sorted by id.
+func (r *Repository) FindOptionGroups(dishIDs ...uint) ([]OptionGroup, error) {
+	var groups []OptionGroup
+	err := r.preloadOptions().Where("dish_id IN ?", dishIDs).Order("id ASC").Find(&groups).Error
+	return groups, err
+}
+
+func (r *Repository) FindOptionGroup(dishID, id uint) (OptionGroup, error) {
+	var g OptionGroup
+	err := r.preloadOptions().Where("dish_id = ?", dishID).First(&g, id).Error
+	return g, err
+}
+
+// CreateOptionGroup creates the group along with its options.
+func (r *Repository) CreateOptionGroup(g OptionGroup) (OptionGroup, error) {
+	if err := r.db.Omit("Dish").Create(&g).Error; err != nil {
+		return g, err
+	}
+
+	return r.FindOptionGroup(g.DishID, g.ID)
+}
+
+// SaveOptionGroup updates the group, updates its options that have ids
+// and creates the rest of them.
+func (r *Repository) SaveOptionGroup(g OptionGroup) (OptionGroup, error) {
+	err := r.db.Session(&gorm.Session{FullSaveAssociations: true}).Omit("Dish").Save(&g).Error
+
+	if err != nil {
+		return g, err
+	}
+
+	return r.FindOptionGroup(g.DishID, g.ID)
+}
+
+// DeleteOptions deletes options of the group except the ones with provided ids.
+func (r *Repository) DeleteOptions(groupID uint, keep []uint) error {
+	tx := r.db.Where("group_id = ?", groupID)
 
-	if cid == 0 {
-		tx.Find(&dishes)
-	} else {
-		tx.Where("category_id = ?", cid).Find(&dishes)
+	if len(keep) > 0 {
+		tx = tx.Where("id NOT IN ?", keep)
 	}
 
-	return dishes
+	return tx.Delete(&Option{}).Error
+}
+
+func (r *Repository) DeleteOptionGroup(g OptionGroup) error {
+	return r.db.Delete(&g).Error
+}
+
+// LockDish locks the dish row until the transaction ends, so that
+// concurrent transactions changing its gallery are run one by one.
+func (r *Repository) LockDish(id uint) error {
+	var ids []uint
+	return r.db.Model(&Dish{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Pluck("id", &ids).Error
+}
+
+// FindImages returns the gallery of the dish sorted by position.
+func (r *Repository) FindImages(dishID uint) ([]Image, error) {
+	var images []Image
+	err := r.db.Where("dish_id = ?", dishID).Order("position ASC").Order("id ASC").Find(&images).Error
+	return images, err
+}
+
+func (r *Repository) CreateImage(img Image) (Image, error) {
+	err := r.db.Omit("Dish").Create(&img).Error
+	return img, err
+}
+
+// UpdateImage updates name and position of the image.
+func (r *Repository) UpdateImage(img Image) error {
+	return r.db.Model(&img).Select("Name", "Position").Updates(img).Error
+}
+
+func (r *Repository) DeleteImage(img Image) error {
+	return r.db.Delete(&img).Error
+}
+
+// SetImage sets the primary image of the dish, nil means the dish doesn't have one.
+func (r *Repository) SetImage(dishID uint, name *string) error {
+	return r.db.Model(&Dish{}).Where("id = ?", dishID).Update("image", name).Error
 }
 
 func (r *Repository) Delete(d Dish) (Dish, error) {
@@ -66,5 +184,16 @@ func (r *Repository) Delete(d Dish) (Dish, error) {
 }
 
 func (r *Repository) preload() *gorm.DB {
-	return r.db.Joins("Category")
+	return r.db.Joins("Category").Preload("Images", PreloadImages)
+}
+
+func (r *Repository) preloadOptions() *gorm.DB {
+	return r.db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dish_options.id ASC")
+	})
+}
+
+// PreloadImages sorts preloaded galleries of dishes by position.
+func PreloadImages(db *gorm.DB) *gorm.DB {
+	return db.Order("dish_images.position ASC").Order("dish_images.id ASC")
 }
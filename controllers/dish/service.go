@@ -1,5 +1,11 @@
 package dish
 
+import (
+	"food_ordering_backend/common"
+	"gorm.io/gorm"
+	"sort"
+)
+
 type Service struct {
 	repo *Repository
 }
@@ -8,6 +14,11 @@ func ProvideService(r *Repository) *Service {
 	return &Service{r}
 }
 
+// WithTx returns a copy of the service that runs all queries in provided transaction.
+func (s *Service) WithTx(tx *gorm.DB) *Service {
+	return &Service{s.repo.WithTx(tx)}
+}
+
 func (s *Service) Create(d Dish) (Dish, error) {
 	return s.repo.Create(d)
 }
@@ -24,10 +35,81 @@ func (s *Service) FindByIDs(ids []uint) ([]Dish, error) {
 	return s.repo.FindByIDs(ids)
 }
 
-func (s *Service) FindAll(cid uint) []Dish {
-	return s.repo.FindAll(cid)
+// FindAll returns all dishes matching the filter.
+// If Paginator is not nil, it returns paginated result.
+func (s *Service) FindAll(f Filter, p common.Paginator) ([]Dish, error) {
+	return s.repo.FindAll(f, p)
+}
+
+// CountAll returns total amount of dishes matching the filter.
+func (s *Service) CountAll(f Filter) int {
+	return s.repo.CountAll(f)
+}
+
+// FindOptionGroups returns option groups of dishes with provided ids, keyed by dish id.
+func (s *Service) FindOptionGroups(ids []uint) (map[uint]OptionGroups, error) {
+	groups, err := s.repo.FindOptionGroups(ids...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byDish := make(map[uint]OptionGroups)
+
+	for _, g := range groups {
+		byDish[g.DishID] = append(byDish[g.DishID], g)
+	}
+
+	return byDish, nil
+}
+
+// TakeStock decreases stock of dishes by provided quantities, keyed by dish id.
+// It should be run in a transaction, so that stock stays unchanged if any of dishes
+// can't be taken. Returns *ErrInsufficientStock with id of the first such dish.
+func (s *Service) TakeStock(quantities map[uint]int) error {
+	for _, id := range sortedIDs(quantities) {
+		ok, err := s.repo.DecreaseStock(id, quantities[id])
+
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return &ErrInsufficientStock{ID: id}
+		}
+	}
+
+	return nil
+}
+
+// RestoreStock increases stock of dishes by provided quantities, keyed by dish id.
+// Dishes that have been deleted or have unlimited stock are skipped.
+func (s *Service) RestoreStock(quantities map[uint]int) error {
+	for _, id := range sortedIDs(quantities) {
+		if err := s.repo.IncreaseStock(id, quantities[id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (s *Service) Delete(d Dish) (Dish, error) {
 	return s.repo.Delete(d)
 }
+
+// sortedIDs returns keys of quantities in ascending order. Dishes are always locked
+// in the same order, so that concurrent transactions don't deadlock.
+func sortedIDs(quantities map[uint]int) []uint {
+	ids := make([]uint, 0, len(quantities))
+
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
@@ -3,6 +3,7 @@
 package dish
 
 import (
+	"food_ordering_backend/common"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
@@ -10,6 +11,6 @@ import (
 var ServiceSet = wire.NewSet(ProvideService, ProvideRepository)
 
 func InitAPI(db *gorm.DB) *API {
-	wire.Build(ProvideAPI, ServiceSet)
+	wire.Build(ProvideAPI, ServiceSet, ProvideOptionService, ProvideImageService, common.ProvideTransactor)
 	return nil
 }
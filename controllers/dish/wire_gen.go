@@ -6,6 +6,7 @@
 package dish
 
 import (
+	"food_ordering_backend/common"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
@@ -15,7 +16,10 @@ import (
 func InitAPI(db *gorm.DB) *API {
 	repository := ProvideRepository(db)
 	service := ProvideService(repository)
-	api := ProvideAPI(service)
+	transactor := common.ProvideTransactor(db)
+	optionService := ProvideOptionService(repository, transactor)
+	imageService := ProvideImageService(repository, transactor)
+	api := ProvideAPI(service, optionService, imageService)
 	return api
 }
 
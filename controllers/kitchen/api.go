@@ -0,0 +1,245 @@
+package kitchen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"food_ordering_backend/config"
+	"food_ordering_backend/controllers/order"
+	"food_ordering_backend/controllers/user"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+)
+
+// queueFilter selects orders that are waiting for the kitchen or are being cooked.
+var queueFilter = order.Filter{
+	Statuses: []order.Status{order.StatusCreated, order.StatusInProgress},
+	SortBy:   "created_at",
+}
+
+type API struct {
+	orders *order.Service
+	users  *user.Service
+}
+
+func ProvideAPI(orders *order.Service, users *user.Service) *API {
+	return &API{orders, users}
+}
+
+func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
+	auth := user.InitAuthMiddleware(db)
+
+	router.GET("/ws", auth(user.PermOrdersReadAll, user.PermOrdersUpdateStatus), api.WS)
+}
+
+// WS godoc
+// @Summary Open kitchen display WebSocket. Requires orders:read_all and orders:update_status permissions.
+// @Description After connection is established, server sends {"type": "queue", "orders": [...]} message
+// @Description with all created and in progress orders, and sends it again every time any order changes.
+// @Description Client can change order status by sending {"type": "set_status", "order_id": 1, "status": 1}.
+// @Description If the command fails, server responds with {"type": "error", "order_id": 1, "error": "..."}.
+// @Description Session and permissions are checked again before every message, server sends an error
+// @Description and closes the connection once the session is revoked or permissions are lost.
+// @ID kitchen-ws
+// @Tags kitchen
+// @Success 101 {object} QueueDTO
+// @Failure 401,403
+// @Security BearerAuth
+// @Router /kitchen/ws [get]
+func (api *API) WS(c *gin.Context) {
+	u := c.MustGet(user.ContextUserKey).(user.User)
+	token := c.MustGet(user.ContextTokenKey).(string)
+
+	server := websocket.Server{
+		Handshake: checkOrigin,
+		Handler: func(conn *websocket.Conn) {
+			api.serve(conn, u, token)
+		},
+	}
+
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serve pushes the queue to the client and executes its commands
+// until either side closes the connection or the session of the user
+// with provided token stops granting access to the kitchen.
+func (api *API) serve(conn *websocket.Conn, u user.User, token string) {
+	defer conn.Close()
+
+	sub := api.orders.Subscribe(u)
+	defer api.orders.Unsubscribe(sub)
+
+	commands := make(chan []byte)
+	closed := make(chan struct{})
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// connection must only be written by one goroutine,
+	// so commands are read here and executed in the loop below
+	go func() {
+		defer close(closed)
+
+		for {
+			var data []byte
+
+			if err := websocket.Message.Receive(conn, &data); err != nil {
+				return
+			}
+
+			select {
+			case commands <- data:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	if err := api.sendQueue(conn); err != nil {
+		return
+	}
+
+	for {
+		var err error
+
+		select {
+		case <-closed:
+			return
+		case _, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if u, err = api.authorize(conn, token); err == nil {
+				err = api.sendQueue(conn)
+			}
+		case data := <-commands:
+			if u, err = api.authorize(conn, token); err == nil {
+				err = api.execute(conn, data, u)
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// authorize loads the user by the session token again, since the session
+// could have been revoked or the user could have lost permissions after
+// the connection was opened. If the user isn't allowed to use the kitchen
+// anymore, the client is notified and an error is returned.
+func (api *API) authorize(conn *websocket.Conn, token string) (user.User, error) {
+	session, err := api.users.FindSessionByToken(token)
+
+	if err != nil {
+		err = errors.New(http.StatusText(http.StatusUnauthorized))
+	} else if !session.User.HasPermission(user.PermOrdersReadAll) || !session.User.HasPermission(user.PermOrdersUpdateStatus) {
+		err = errors.New(http.StatusText(http.StatusForbidden))
+	}
+
+	if err != nil {
+		if sendErr := websocket.JSON.Send(conn, ErrorDTO{Type: MessageError, Error: err.Error()}); sendErr != nil {
+			return user.User{}, sendErr
+		}
+		return user.User{}, err
+	}
+
+	return session.User, nil
+}
+
+func (api *API) sendQueue(conn *websocket.Conn) error {
+	orders, err := api.orders.FindAll(queueFilter, nil)
+
+	if err != nil {
+		return websocket.JSON.Send(conn, ErrorDTO{Type: MessageError, Error: "Couldn't load orders"})
+	}
+
+	return websocket.JSON.Send(conn, ToQueueDTO(orders))
+}
+
+// execute runs a command on behalf of provided user. Command failures are
+// reported to the client, only errors of the connection itself are returned.
+func (api *API) execute(conn *websocket.Conn, data []byte, u user.User) error {
+	var cmd CommandDTO
+
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return websocket.JSON.Send(conn, ErrorDTO{Type: MessageError, Error: "Malformed command"})
+	}
+
+	if cmd.Type != MessageSetStatus {
+		return websocket.JSON.Send(conn, ErrorDTO{
+			Type:    MessageError,
+			OrderID: cmd.OrderID,
+			Error:   fmt.Sprintf("Unknown command %q", cmd.Type),
+		})
+	}
+
+	if err := api.setStatus(cmd, u); err != nil {
+		return websocket.JSON.Send(conn, ErrorDTO{Type: MessageError, OrderID: cmd.OrderID, Error: err.Error()})
+	}
+
+	return nil
+}
+
+// setStatus moves the order to a new status. Successful change results in an
+// order event, which makes the updated queue to be sent to all kitchen clients.
+func (api *API) setStatus(cmd CommandDTO, u user.User) error {
+	if !order.IsValidStatus(int(cmd.Status)) {
+		return fmt.Errorf("Invalid status %d", cmd.Status)
+	}
+
+	o, err := api.orders.FindByID(cmd.OrderID)
+
+	if err != nil {
+		var errOrderID *order.ErrOrderID
+
+		if errors.As(err, &errOrderID) {
+			return errOrderID
+		}
+
+		return errors.New(http.StatusText(http.StatusInternalServerError))
+	}
+
+	if o.Status == cmd.Status {
+		return nil
+	}
+
+	if err := api.orders.UpdateStatus(o, cmd.Status, u); err != nil {
+		var errTransition *order.ErrIllegalTransition
+
+		if errors.As(err, &errTransition) {
+			return errTransition
+		}
+
+		return errors.New(http.StatusText(http.StatusInternalServerError))
+	}
+
+	return nil
+}
+
+// checkOrigin allows connections from the frontend and from clients that
+// don't send Origin header, e.g. dedicated kitchen displays. Since session
+// cookie is sent by browsers regardless of origin, connections initiated by
+// other sites must be rejected.
+func checkOrigin(cfg *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(cfg, req)
+
+	if err != nil {
+		return err
+	}
+
+	if origin == nil {
+		return nil
+	}
+
+	for _, allowed := range []*url.URL{config.ClientURL, config.HostURL} {
+		if allowed != nil && origin.Scheme == allowed.Scheme && origin.Host == allowed.Host {
+			cfg.Origin = origin
+			return nil
+		}
+	}
+
+	return websocket.ErrBadWebSocketOrigin
+}
@@ -0,0 +1,54 @@
+package kitchen
+
+import (
+	"food_ordering_backend/controllers/order"
+	"time"
+)
+
+type MessageType string
+
+const (
+	// MessageQueue is sent by the server with the current queue of orders
+	// on connect and every time any order changes.
+	MessageQueue MessageType = "queue"
+	// MessageError is sent by the server when a command can't be executed.
+	MessageError MessageType = "error"
+	// MessageSetStatus is sent by the client to move an order to another status.
+	MessageSetStatus MessageType = "set_status"
+)
+
+// CommandDTO is a message sent by the client.
+type CommandDTO struct {
+	Type    MessageType  `json:"type"`
+	OrderID uint         `json:"order_id"`
+	Status  order.Status `json:"status"`
+}
+
+type QueueDTO struct {
+	Type   MessageType `json:"type"`
+	Orders []OrderDTO  `json:"orders"`
+}
+
+type ErrorDTO struct {
+	Type MessageType `json:"type"`
+	// OrderID is the id of the order from the failed command, if there was one.
+	OrderID uint   `json:"order_id,omitempty"`
+	Error   string `json:"error"`
+}
+
+type OrderDTO struct {
+	ID        uint         `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Status    order.Status `json:"status"`
+	Items     []ItemDTO    `json:"items"`
+}
+
+type ItemDTO struct {
+	DishID        uint   `json:"dish_id"`
+	DishTitle     string `json:"dish_title"`
+	CategoryTitle string `json:"category_title"`
+	Quantity      int    `json:"quantity"`
+	// Options are titles of chosen options, e.g. "Large" or "No onions".
+	Options []string `json:"options"`
+}
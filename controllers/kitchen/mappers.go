@@ -0,0 +1,41 @@
+package kitchen
+
+import "food_ordering_backend/controllers/order"
+
+func ToQueueDTO(orders []order.Order) QueueDTO {
+	dtos := make([]OrderDTO, len(orders))
+
+	for i, o := range orders {
+		dtos[i] = ToOrderDTO(o)
+	}
+
+	return QueueDTO{Type: MessageQueue, Orders: dtos}
+}
+
+func ToOrderDTO(o order.Order) OrderDTO {
+	items := make([]ItemDTO, len(o.Items))
+
+	for i, item := range o.Items {
+		options := make([]string, len(item.Options))
+
+		for j, option := range item.Options {
+			options[j] = option.Title
+		}
+
+		items[i] = ItemDTO{
+			DishID:        item.DishID,
+			DishTitle:     item.DishTitle,
+			CategoryTitle: item.CategoryTitle,
+			Quantity:      item.Quantity,
+			Options:       options,
+		}
+	}
+
+	return OrderDTO{
+		ID:        o.ID,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+		Status:    o.Status,
+		Items:     items,
+	}
+}
@@ -0,0 +1,16 @@
+// +build wireinject
+
+package kitchen
+
+import (
+	"food_ordering_backend/controllers/order"
+	"food_ordering_backend/controllers/user"
+	"food_ordering_backend/services"
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func InitAPI(db *gorm.DB) *API {
+	wire.Build(ProvideAPI, order.ServiceSet, user.ProvideService, user.ProvideJWTService, services.ProvideMailer, services.ProvideLimiterStore)
+	return nil
+}
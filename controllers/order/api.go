@@ -1,13 +1,27 @@
 package order
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"food_ordering_backend/common"
+	"food_ordering_backend/config"
+	"food_ordering_backend/controllers/dish"
 	"food_ordering_backend/controllers/user"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
 )
 
 type API struct {
@@ -21,34 +35,56 @@ func ProvideAPI(s *Service) *API {
 func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 	auth := user.InitAuthMiddleware(db)
 
-	router.GET("", auth(false), api.FindAll)
-	router.POST("", auth(false), api.Create)
-	router.PATCH("/:id", auth(true), api.Patch)
-	router.PUT("/:id", auth(true), api.Update)
+	router.GET("", auth(), api.FindAll)
+	router.GET("/stream", auth(), api.Stream)
+	router.POST("", auth(), user.RequireVerifiedEmail, api.Create)
+	router.GET("/:id/history", auth(), api.History)
+	router.POST("/:id/cancel", auth(), api.Cancel)
+	router.PATCH("/:id", auth(user.PermOrdersUpdateStatus), api.Patch)
+	router.PUT("/:id", auth(user.PermOrdersWrite), api.Update)
 }
 
 // FindAll godoc
 // @Summary Get all orders. Requires auth.
-// @Description If requester is admin, it returns all orders. Otherwise, it returns orders only for that user.
+// @Description If requester has orders:read_all permission, it returns all orders. Otherwise, it returns orders only for that user.
+// @Description Dates can be either RFC 3339 timestamps or YYYY-MM-DD dates, both bounds are inclusive.
 // @ID order-all
 // @Tags order
 // @Param page query integer false "0-based page number"
 // @Param limit query integer false "amount of entries per page"
+// @Param status query []integer false "order statuses, can be repeated or comma-separated" collectionFormat(multi)
+// @Param created_from query string false "lower bound of creation date"
+// @Param created_to query string false "upper bound of creation date"
+// @Param updated_from query string false "lower bound of last update date"
+// @Param updated_to query string false "upper bound of last update date"
+// @Param email query string false "part of customer's email, requires orders:read_all permission"
+// @Param dish_id query integer false "id of a dish that order contains"
+// @Param min_total query integer false "minimal total in minor units"
+// @Param max_total query integer false "maximal total in minor units"
+// @Param sort query string false "sort field" Enums(id, created_at, updated_at, status, total)
+// @Param order query string false "sort direction" Enums(asc, desc)
 // @Produce json
 // @Success 200 {object} DTOsWithPagination
-// @Failure 401,403,404,500
+// @Failure 400,401,403,404,500
+// @Security BearerAuth
 // @Router /orders [get]
 func (api *API) FindAll(c *gin.Context) {
 	var orders []Order
-	var err error
 	u := c.MustGet(user.ContextUserKey).(user.User)
 	p := common.ExtractPagination(c, 10)
+	f, err := FilterFromQuery(c.Request.URL.Query())
 
-	if u.IsAdmin {
-		u.ID = 0
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
 	}
 
-	orders, err = api.service.FindAll(u.ID, p)
+	if !u.HasPermission(user.PermOrdersReadAll) {
+		f.UserID = u.ID
+		f.UserEmail = ""
+	}
+
+	orders, err = api.service.FindAll(f, p)
 
 	if err != nil {
 		c.Status(http.StatusInternalServerError)
@@ -60,20 +96,80 @@ func (api *API) FindAll(c *gin.Context) {
 		Pagination: common.PaginationDTO{
 			Page:  p.Page(),
 			Limit: p.Limit(),
-			Total: api.service.CountAll(u.ID),
+			Total: api.service.CountAll(f),
 		},
 	})
 }
 
+// Stream godoc
+// @Summary Subscribe to order events. Requires auth.
+// @Description Server-Sent Events stream. Event name is one of "created", "updated" or "status_changed", data is the order in JSON.
+// @Description Users with orders:read_all permission receive events of all orders. Other users receive events only of their own orders.
+// @Description Heartbeat comments are sent periodically to keep connection open.
+// @ID order-stream
+// @Tags order
+// @Produce text/event-stream
+// @Success 200 {object} ResponseDTO
+// @Failure 401,403
+// @Security BearerAuth
+// @Router /orders/stream [get]
+func (api *API) Stream(c *gin.Context) {
+	u := c.MustGet(user.ContextUserKey).(user.User)
+	sub := api.service.Subscribe(u)
+	defer api.service.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(config.OrderStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			// subscription has been dropped by the hub, client is expected to reconnect
+			if !ok {
+				return
+			}
+
+			c.SSEvent(string(e.Type), ToResponseDTO(e.Order))
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		c.Writer.Flush()
+	}
+}
+
 // Create godoc
 // @Summary Create new order. Requires auth.
+// @Description If Idempotency-Key header is provided, repeated requests with the same key and body
+// @Description made within a retention period return the original response instead of creating another order.
+// @Description Such responses have Idempotent-Replayed header set to true. While the original request is being
+// @Description processed, repeated ones get 409. If it doesn't complete within a lease period, the key is taken over
+// @Description by the next repeated request.
+// @Description Responds with 403 if verified email is required and the user hasn't verified it.
+// @Description The order is delivered to the address with address_id or, if it's omitted, to the default address
+// @Description of the user. A copy of the address is saved with the order. Responds with 422 if the user has no addresses.
+// @Description Responds with 409 if any of dishes is sold out or doesn't have enough portions left.
+// @Description Chosen options of items must satisfy option groups of dishes, otherwise responds with 422.
 // @ID order-create
 // @Tags order
 // @Accept json
 // @Param dto body CreateDTO true "Create order DTO"
+// @Param Idempotency-Key header string false "Unique key of the request, up to 255 characters"
 // @Produce json
 // @Success 201 {object} ResponseDTO
-// @Failure 401,403,422,500
+// @Failure 400,401,403,409,422,500
+// @Security BearerAuth
 // @Router /orders [post]
 func (api *API) Create(c *gin.Context) {
 	var dto CreateDTO
@@ -82,29 +178,187 @@ func (api *API) Create(c *gin.Context) {
 		return
 	}
 	u := c.MustGet(user.ContextUserKey).(user.User)
-	o, err := api.service.Create(dto.Items, u)
+	key := c.GetHeader(IdempotencyKeyHeader)
+	var k *IdempotencyKey
+
+	if key != "" {
+		reserved, replay, ok := api.reserveIdempotencyKey(c, key, dto, u)
+
+		if !ok {
+			return
+		}
+
+		if replay {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(reserved.StatusCode, gin.MIMEJSON+"; charset=utf-8", reserved.Response)
+			return
+		}
+
+		k = &reserved
+	}
+
+	o, err := api.service.Create(dto, u, k)
 
 	if err != nil {
+		if k != nil {
+			if err := api.service.ReleaseIdempotencyKey(*k); err != nil {
+				log.Println("[Order] Error releasing idempotency key:", err)
+			}
+		}
+
 		var errDishID *ErrDishID
-		if errors.As(err, &errDishID) {
+		var errAddressID *user.ErrAddressID
+		var errStock *dish.ErrInsufficientStock
+
+		switch {
+		case errors.As(err, &errDishID):
 			c.String(http.StatusBadRequest, errDishID.Error())
-			return
+		case errors.As(err, &errStock):
+			c.String(http.StatusConflict, errStock.Error())
+		case errors.Is(err, dish.ErrInvalidChoice):
+			c.String(http.StatusUnprocessableEntity, err.Error())
+		case errors.As(err, &errAddressID):
+			c.String(http.StatusBadRequest, errAddressID.Error())
+		case errors.Is(err, user.ErrNoDefaultAddress):
+			c.String(http.StatusUnprocessableEntity, "Delivery address is required")
+		case errors.Is(err, common.ErrCurrencyMismatch):
+			c.String(http.StatusBadRequest, "Dishes in the order must be priced in the same currency")
+		case errors.Is(err, ErrIdempotencyKeyLost):
+			c.String(http.StatusConflict, ErrIdempotencyKeyInProgress.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
 		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToResponseDTO(o))
+}
+
+// reserveIdempotencyKey reserves the key for the request with provided dto.
+// If reservation fails, it responds with an appropriate status and returns false.
+func (api *API) reserveIdempotencyKey(c *gin.Context, key string, dto CreateDTO, u user.User) (k IdempotencyKey, replay bool, ok bool) {
+	if len(key) > maxIdempotencyKeyLength {
+		c.String(http.StatusBadRequest, "Idempotency key must not be longer than %d characters", maxIdempotencyKeyLength)
+		return k, false, false
+	}
+
+	// hash of the bound dto doesn't depend on formatting of the body
+	body, err := json.Marshal(dto)
 
+	if err != nil {
 		c.Status(http.StatusInternalServerError)
+		return k, false, false
+	}
+
+	hash := sha256.Sum256(body)
+	k, replay, err = api.service.ReserveIdempotencyKey(u, key, hex.EncodeToString(hash[:]))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			c.String(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, ErrIdempotencyKeyInProgress):
+			c.String(http.StatusConflict, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return k, false, false
+	}
+
+	return k, replay, true
+}
+
+// History godoc
+// @Summary Get status history of the order. Requires auth.
+// @Description Users with orders:read_all permission can access history of any order. Other users can access history only of their own orders.
+// @ID order-history
+// @Tags order
+// @Param id path integer true "Order id"
+// @Produce json
+// @Success 200 {array} StatusChangeDTO
+// @Failure 400,401,403,404,500
+// @Security BearerAuth
+// @Router /orders/:id/history [get]
+func (api *API) History(c *gin.Context) {
+	o, err := api.findByID(c)
+
+	if err != nil {
 		return
 	}
 
-	c.JSON(http.StatusCreated, ToResponseDTO(o))
+	u := c.MustGet(user.ContextUserKey).(user.User)
+
+	if !u.HasPermission(user.PermOrdersReadAll) && o.UserID != u.ID {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	history, err := api.service.FindStatusHistory(o.ID)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToStatusChangeDTOs(history))
+}
+
+// Cancel godoc
+// @Summary Cancel own order. Requires auth.
+// @Description Order can be canceled only by its owner, while it hasn't been taken into work and within a grace window after creation.
+// @Description Items of the order return to stock of dishes.
+// @ID order-cancel
+// @Tags order
+// @Param id path integer true "Order id"
+// @Produce json
+// @Success 200 {object} ResponseDTO
+// @Failure 400,401,403,404,409,500
+// @Security BearerAuth
+// @Router /orders/:id/cancel [post]
+func (api *API) Cancel(c *gin.Context) {
+	o, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	u := c.MustGet(user.ContextUserKey).(user.User)
+
+	if err := api.service.Cancel(o, u); err != nil {
+		var errTransition *ErrIllegalTransition
+
+		switch {
+		case errors.Is(err, ErrNotOwner):
+			c.Status(http.StatusForbidden)
+		case errors.Is(err, ErrCancelNotAllowed), errors.Is(err, ErrCancelWindowExpired):
+			c.String(http.StatusForbidden, err.Error())
+		case errors.As(err, &errTransition):
+			c.String(http.StatusConflict, errTransition.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	o, err = api.service.FindByID(o.ID)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponseDTO(o))
 }
 
 // Patch godoc
-// @Summary Patch order. Requires admin rights.
+// @Summary Patch order. Requires orders:update_status permission.
+// @Description Order status can only be changed according to the status transitions table: Created -> In Progress | Canceled, In Progress -> Done | Canceled.
 // @ID order-patch
 // @Tags order
 // @Param status query integer true "New order status"
 // @Success 204
-// @Failure 401,403,404,500
+// @Failure 401,403,404,409,500
+// @Security BearerAuth
 // @Router /orders/:id [patch]
 func (api *API) Patch(c *gin.Context) {
 	s := c.Query("status")
@@ -126,8 +380,10 @@ func (api *API) Patch(c *gin.Context) {
 		return
 	}
 
-	if err := api.service.UpdateStatus(o.ID, Status(status)); err != nil {
-		c.Status(http.StatusInternalServerError)
+	u := c.MustGet(user.ContextUserKey).(user.User)
+
+	if err := api.service.UpdateStatus(o, Status(status), u); err != nil {
+		api.handleUpdateErr(c, err)
 		return
 	}
 
@@ -135,7 +391,8 @@ func (api *API) Patch(c *gin.Context) {
 }
 
 // Update godoc
-// @Summary Replace order. Requires admin rights.
+// @Summary Replace order. Requires orders:write permission.
+// @Description Total is calculated from items and adjustments. To keep existing adjustment, provide only its id. Adjustments that aren't present in the list will be removed. Portions of previous items return to stock and new items take it, unless the order is canceled.
 // @ID order-update
 // @Tags order
 // @Accept json
@@ -143,7 +400,8 @@ func (api *API) Patch(c *gin.Context) {
 // @Param id path integer true "Order id"
 // @Produce json
 // @Success 200 {object} ResponseDTO
-// @Failure 401,403,404,500
+// @Failure 400,401,403,404,409,422,500
+// @Security BearerAuth
 // @Router /orders/:id [put]
 func (api *API) Update(c *gin.Context) {
 	o, err := api.findByID(c)
@@ -158,22 +416,44 @@ func (api *API) Update(c *gin.Context) {
 		return
 	}
 
-	o, err = api.service.Update(o, dto)
+	u := c.MustGet(user.ContextUserKey).(user.User)
+	o, err = api.service.Update(o, dto, u)
 
 	if err != nil {
-		var errDishID *ErrDishID
-		if errors.As(err, &errDishID) {
-			c.String(http.StatusBadRequest, errDishID.Error())
-			return
-		}
-
-		c.Status(http.StatusInternalServerError)
+		api.handleUpdateErr(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, ToResponseDTO(o))
 }
 
+func (api *API) handleUpdateErr(c *gin.Context, err error) {
+	var errDishID *ErrDishID
+	var errTransition *ErrIllegalTransition
+	var errAdjustmentID *ErrAdjustmentID
+	var errItemID *ErrItemID
+	var errStock *dish.ErrInsufficientStock
+
+	switch {
+	case errors.As(err, &errDishID):
+		c.String(http.StatusBadRequest, errDishID.Error())
+	case errors.As(err, &errItemID):
+		c.String(http.StatusBadRequest, errItemID.Error())
+	case errors.As(err, &errStock):
+		c.String(http.StatusConflict, errStock.Error())
+	case errors.Is(err, common.ErrCurrencyMismatch):
+		c.String(http.StatusBadRequest, "Dishes in the order must be priced in the same currency")
+	case errors.As(err, &errAdjustmentID):
+		c.String(http.StatusBadRequest, errAdjustmentID.Error())
+	case errors.Is(err, ErrInvalidAdjustment), errors.Is(err, ErrNegativeTotal), errors.Is(err, dish.ErrInvalidChoice):
+		c.String(http.StatusUnprocessableEntity, err.Error())
+	case errors.As(err, &errTransition):
+		c.String(http.StatusConflict, errTransition.Error())
+	default:
+		c.Status(http.StatusInternalServerError)
+	}
+}
+
 func (api *API) findByID(c *gin.Context) (Order, error) {
 	id, err := strconv.Atoi(c.Param("id"))
 
@@ -9,13 +9,24 @@ import (
 
 type CreateDTO struct {
 	Items []ItemCreateDTO `json:"items" binding:"required,gt=0,dive"`
+	// AddressID is an id of the address of the user the order is delivered to.
+	// If it's omitted, the default address of the user is used.
+	AddressID uint `json:"address_id"`
 }
 
 type ItemCreateDTO struct {
-	ID       uint `json:"id" binding:"required"`
+	// ItemID is an id of an existing item of the order. It's used only when the order
+	// is replaced, such item keeps its snapshot of the dish and chosen options
+	// and only its quantity is changed. ID and Options are ignored for it.
+	ItemID   uint `json:"item_id,omitempty"`
+	ID       uint `json:"id" binding:"required_without=ItemID"`
 	Quantity int  `json:"quantity" binding:"required,gt=0"`
+	// Options are ids of chosen options of the dish. Each option group
+	// of the dish limits how many of its options can be chosen.
+	Options []uint `json:"options" binding:"dive,gt=0"`
 }
 
+// ResponseDTO is an order. If the user has been deleted, UserID is 0 and User is empty.
 type ResponseDTO struct {
 	ID        uint              `json:"id"`
 	CreatedAt time.Time         `json:"created_at"`
@@ -23,8 +34,24 @@ type ResponseDTO struct {
 	Status    Status            `json:"status"`
 	UserID    uint              `json:"user_id"`
 	User      user.ResponseDTO  `json:"user"`
-	Total     float64           `json:"total"`
+	Total     common.Money      `json:"total"`
 	Items     []ItemResponseDTO `json:"items"`
+	// DeliveryAddress is empty for orders created before addresses were introduced.
+	DeliveryAddress DeliveryAddressDTO `json:"delivery_address"`
+	// Adjustments explain the difference between the total and the cost of items.
+	Adjustments []AdjustmentResponseDTO `json:"adjustments"`
+	// LastTransition is the latest status change or null if status has never been changed.
+	LastTransition *StatusChangeDTO `json:"last_transition"`
+}
+
+type DeliveryAddressDTO struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	PostalCode string `json:"postal_code"`
+	Comment    string `json:"comment"`
 }
 
 type DTOsWithPagination struct {
@@ -32,22 +59,88 @@ type DTOsWithPagination struct {
 	Pagination common.PaginationDTO `json:"pagination"`
 }
 
+// UpdateDTO replaces the order. Total is always calculated from items
+// and adjustments.
+//
+// Items with item_id keep prices and titles they were ordered with, items without
+// it are created with current data of dishes. Existing items that aren't listed
+// are deleted.
+//
+// Adjustments without id are created on behalf of the requester.
+// Existing adjustments are kept only if their ids are present in the list,
+// all other fields of such adjustments are ignored. The rest of them are
+// revoked on behalf of the requester.
 type UpdateDTO struct {
-	Status Status          `json:"status" binding:"required,min=0,max=3"`
-	UserID uint            `json:"user_id" binding:"required"`
-	Total  float64         `json:"total" binding:"required,min=0"`
-	Items  []ItemCreateDTO `json:"items" binding:"required,gt=0,dive"`
+	Status      Status                `json:"status" binding:"required,min=0,max=3"`
+	UserID      uint                  `json:"user_id" binding:"required"`
+	Items       []ItemCreateDTO       `json:"items" binding:"required,gt=0,dive"`
+	Adjustments []AdjustmentCreateDTO `json:"adjustments" binding:"dive"`
+}
+
+type AdjustmentCreateDTO struct {
+	ID     uint           `json:"id"`
+	Kind   AdjustmentKind `json:"kind" binding:"omitempty,oneof=discount surcharge correction"`
+	Value  common.Money   `json:"value"`
+	Reason string         `json:"reason" binding:"max=255"`
+}
+
+// AdjustmentResponseDTO is an adjustment of an order. If the admin that created it
+// has been deleted, CreatedByID is 0 and CreatedBy is empty.
+//
+// Revoked adjustments don't affect the total. RevokedAt is null unless
+// the adjustment has been revoked, RevokedBy is null if it hasn't been revoked
+// or the admin that revoked it has been deleted.
+type AdjustmentResponseDTO struct {
+	ID          uint              `json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Kind        AdjustmentKind    `json:"kind"`
+	Value       common.Money      `json:"value"`
+	Reason      string            `json:"reason"`
+	CreatedByID uint              `json:"created_by_id"`
+	CreatedBy   user.ResponseDTO  `json:"created_by"`
+	RevokedAt   *time.Time        `json:"revoked_at"`
+	RevokedByID *uint             `json:"revoked_by_id"`
+	RevokedBy   *user.ResponseDTO `json:"revoked_by"`
 }
 
 type ItemResponseDTO struct {
-	ID       uint     `json:"id"`
-	OrderID  uint     `json:"order_id"`
-	DishID   uint     `json:"dish_id"`
+	ID      uint `json:"id"`
+	OrderID uint `json:"order_id"`
+	// DishID is 0 if the dish has been deleted.
+	DishID uint `json:"dish_id"`
+	// Dish contains title, price and category title of the dish
+	// at the moment the order was created.
 	Dish     dish.DTO `json:"dish"`
 	Quantity int      `json:"quantity"`
+	// UnitPrice is the price of the dish without options.
+	UnitPrice common.Money            `json:"unit_price"`
+	Options   []ItemOptionResponseDTO `json:"options"`
+	// Cost includes price deltas of chosen options.
+	Cost common.Money `json:"cost"`
+}
+
+// ItemOptionResponseDTO is an option chosen for the order item.
+// OptionID is 0 if the option has been deleted.
+type ItemOptionResponseDTO struct {
+	OptionID   uint         `json:"option_id"`
+	GroupTitle string       `json:"group_title"`
+	Title      string       `json:"title"`
+	PriceDelta common.Money `json:"price_delta"`
 }
 
 type StatusDTO struct {
 	ID    uint   `json:"id" binding:"required,min=1"`
 	Title string `json:"title" binding:"required,min=2,max=30"`
 }
+
+// StatusChangeDTO is a record in order's status history. If the user that changed
+// the status has been deleted, ChangedByID is 0 and ChangedBy is empty.
+type StatusChangeDTO struct {
+	ID          uint             `json:"id"`
+	CreatedAt   time.Time        `json:"created_at"`
+	OrderID     uint             `json:"order_id"`
+	From        Status           `json:"from"`
+	To          Status           `json:"to"`
+	ChangedByID uint             `json:"changed_by_id"`
+	ChangedBy   user.ResponseDTO `json:"changed_by"`
+}
@@ -0,0 +1,225 @@
+package order
+
+import (
+	"fmt"
+	"food_ordering_backend/common"
+	"gorm.io/gorm"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is an alternative to time.RFC3339 for date range filters.
+const dateLayout = "2006-01-02"
+
+// sortColumns maps values of "sort" query parameter to columns.
+var sortColumns = map[string]string{
+	"id":         "orders.id",
+	"created_at": "orders.created_at",
+	"updated_at": "orders.updated_at",
+	"status":     "orders.status",
+	"total":      "orders.total_amount",
+}
+
+// Filter narrows down and sorts a list of orders.
+// Zero values of the fields are ignored.
+type Filter struct {
+	UserID      uint
+	Statuses    []Status
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	// UserEmail matches orders of users whose email contains provided string.
+	UserEmail string
+	// DishID matches orders that contain dish with provided id.
+	DishID uint
+	// MinTotal and MaxTotal are amounts of minor units.
+	MinTotal *int64
+	MaxTotal *int64
+	// SortBy is one of the keys from sortColumns. Defaults to "id".
+	SortBy   string
+	SortDesc bool
+}
+
+// FilterFromQuery parses query parameters into Filter.
+//
+// Supported parameters: status (can be repeated or comma-separated),
+// created_from, created_to, updated_from, updated_to (RFC 3339 timestamps or
+// YYYY-MM-DD dates), email, dish_id, min_total, max_total, sort and order (asc or desc).
+func FilterFromQuery(q url.Values) (Filter, error) {
+	var f Filter
+	var err error
+
+	for _, value := range q["status"] {
+		for _, s := range strings.Split(value, ",") {
+			status, err := strconv.Atoi(strings.TrimSpace(s))
+
+			if err != nil || !IsValidStatus(status) {
+				return f, fmt.Errorf("invalid status %q", s)
+			}
+
+			f.Statuses = append(f.Statuses, Status(status))
+		}
+	}
+
+	dates := []struct {
+		key   string
+		dest  *time.Time
+		isEnd bool
+	}{
+		{"created_from", &f.CreatedFrom, false},
+		{"created_to", &f.CreatedTo, true},
+		{"updated_from", &f.UpdatedFrom, false},
+		{"updated_to", &f.UpdatedTo, true},
+	}
+
+	for _, d := range dates {
+		if *d.dest, err = parseDate(q.Get(d.key), d.isEnd); err != nil {
+			return f, fmt.Errorf("invalid %s: %w", d.key, err)
+		}
+	}
+
+	f.UserEmail = strings.TrimSpace(q.Get("email"))
+
+	if dishID := q.Get("dish_id"); dishID != "" {
+		id, err := strconv.ParseUint(dishID, 10, 64)
+
+		if err != nil {
+			return f, fmt.Errorf("invalid dish_id %q", dishID)
+		}
+
+		f.DishID = uint(id)
+	}
+
+	if f.MinTotal, err = parseAmount(q.Get("min_total")); err != nil {
+		return f, fmt.Errorf("invalid min_total: %w", err)
+	}
+
+	if f.MaxTotal, err = parseAmount(q.Get("max_total")); err != nil {
+		return f, fmt.Errorf("invalid max_total: %w", err)
+	}
+
+	if f.SortBy = q.Get("sort"); f.SortBy != "" {
+		if _, ok := sortColumns[f.SortBy]; !ok {
+			return f, fmt.Errorf("can't sort by %q", f.SortBy)
+		}
+	}
+
+	switch strings.ToLower(q.Get("order")) {
+	case "", "asc":
+	case "desc":
+		f.SortDesc = true
+	default:
+		return f, fmt.Errorf("invalid order %q", q.Get("order"))
+	}
+
+	return f, nil
+}
+
+// Where applies filtering conditions to the query.
+func (f Filter) Where(db *gorm.DB) *gorm.DB {
+	if f.UserID != 0 {
+		db = db.Where("orders.user_id = ?", f.UserID)
+	}
+
+	if len(f.Statuses) > 0 {
+		db = db.Where("orders.status IN ?", f.Statuses)
+	}
+
+	if !f.CreatedFrom.IsZero() {
+		db = db.Where("orders.created_at >= ?", f.CreatedFrom)
+	}
+
+	if !f.CreatedTo.IsZero() {
+		db = db.Where("orders.created_at <= ?", f.CreatedTo)
+	}
+
+	if !f.UpdatedFrom.IsZero() {
+		db = db.Where("orders.updated_at >= ?", f.UpdatedFrom)
+	}
+
+	if !f.UpdatedTo.IsZero() {
+		db = db.Where("orders.updated_at <= ?", f.UpdatedTo)
+	}
+
+	if f.UserEmail != "" {
+		db = db.Where(`orders.user_id IN (SELECT id FROM users WHERE email ILIKE ? ESCAPE '\')`, common.ContainsPattern(f.UserEmail))
+	}
+
+	if f.DishID != 0 {
+		db = db.Where("EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id AND order_items.dish_id = ?)", f.DishID)
+	}
+
+	if f.MinTotal != nil {
+		db = db.Where("orders.total_amount >= ?", *f.MinTotal)
+	}
+
+	if f.MaxTotal != nil {
+		db = db.Where("orders.total_amount <= ?", *f.MaxTotal)
+	}
+
+	return db
+}
+
+// OrderBy applies sorting to the query. Orders with equal values
+// of the sort column are sorted by id.
+func (f Filter) OrderBy(db *gorm.DB) *gorm.DB {
+	column, ok := sortColumns[f.SortBy]
+
+	if !ok {
+		column = sortColumns["id"]
+	}
+
+	direction := "ASC"
+	if f.SortDesc {
+		direction = "DESC"
+	}
+
+	db = db.Order(column + " " + direction)
+
+	if column != sortColumns["id"] {
+		db = db.Order("orders.id ASC")
+	}
+
+	return db
+}
+
+// parseDate parses RFC 3339 timestamp or a date. If isEnd is true,
+// date is treated as the end of the day.
+func parseDate(value string, isEnd bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(dateLayout, value)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if isEnd {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return t, nil
+}
+
+func parseAmount(value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	amount, err := strconv.ParseInt(value, 10, 64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &amount, nil
+}
@@ -0,0 +1,64 @@
+package order
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFilterFromQuery(t *testing.T) {
+	t.Run("should parse all parameters", func(t *testing.T) {
+		it := assert.New(t)
+		q, _ := url.ParseQuery("status=0&status=1,2&created_from=2021-05-01&created_to=2021-05-31" +
+			"&updated_from=2021-05-02T10:00:00Z&email=%20john%20&dish_id=7&min_total=100&max_total=5000" +
+			"&sort=total&order=DESC")
+
+		f, err := FilterFromQuery(q)
+
+		if it.NoError(err) {
+			minTotal, maxTotal := int64(100), int64(5000)
+
+			it.Equal(Filter{
+				Statuses:    []Status{StatusCreated, StatusInProgress, StatusDone},
+				CreatedFrom: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+				CreatedTo:   time.Date(2021, 5, 31, 23, 59, 59, 999999999, time.UTC),
+				UpdatedFrom: time.Date(2021, 5, 2, 10, 0, 0, 0, time.UTC),
+				UserEmail:   "john",
+				DishID:      7,
+				MinTotal:    &minTotal,
+				MaxTotal:    &maxTotal,
+				SortBy:      "total",
+				SortDesc:    true,
+			}, f)
+		}
+	})
+
+	t.Run("should return empty filter if there are no parameters", func(t *testing.T) {
+		it := assert.New(t)
+		f, err := FilterFromQuery(url.Values{})
+
+		if it.NoError(err) {
+			it.Zero(f)
+		}
+	})
+
+	t.Run("should return error if parameters are invalid", func(t *testing.T) {
+		queries := []string{
+			"status=4",
+			"status=0,",
+			"created_from=01.05.2021",
+			"updated_to=2021-02-30",
+			"dish_id=abc",
+			"max_total=10.5",
+			"sort=user_id",
+			"order=up",
+		}
+
+		for _, query := range queries {
+			q, _ := url.ParseQuery(query)
+			_, err := FilterFromQuery(q)
+			assert.Error(t, err, query)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package order
+
+import (
+	"food_ordering_backend/controllers/user"
+	"sync"
+)
+
+// subscriptionBufferSize is the amount of events that can wait
+// for delivery to a single subscriber.
+const subscriptionBufferSize = 16
+
+type EventType string
+
+const (
+	EventCreated       EventType = "created"
+	EventUpdated       EventType = "updated"
+	EventStatusChanged EventType = "status_changed"
+)
+
+// Event notifies subscribers about changes of an order.
+type Event struct {
+	Type  EventType
+	Order Order
+}
+
+// Subscription receives events of orders visible to its user:
+// users with user.PermOrdersReadAll receive events of all orders, customers only of their own.
+type Subscription struct {
+	userID  uint
+	readAll bool
+	events  chan Event
+}
+
+// Events returns a channel of events. It's closed when subscription
+// is removed from the hub.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) accepts(e Event) bool {
+	return s.readAll || s.userID == e.Order.UserID
+}
+
+// Hub delivers order events to subscribers.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// DefaultHub is shared by all services so that events published by any of them
+// reach every subscriber.
+var DefaultHub = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscription]struct{})}
+}
+
+func ProvideHub() *Hub {
+	return DefaultHub
+}
+
+// Subscribe creates a subscription for provided user.
+// It must be removed with Unsubscribe once it's not needed anymore.
+func (h *Hub) Subscribe(u user.User) *Subscription {
+	s := &Subscription{
+		userID:  u.ID,
+		readAll: u.HasPermission(user.PermOrdersReadAll),
+		events:  make(chan Event, subscriptionBufferSize),
+	}
+
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+// Unsubscribe removes subscription from the hub and closes its channel.
+// It's safe to call it multiple times.
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// Publish sends event to every subscriber that accepts it. Publish never blocks:
+// subscribers that don't keep up with events are removed, so that their clients
+// can reconnect and fetch the current state.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		if !s.accepts(e) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			h.remove(s)
+		}
+	}
+}
+
+// Len returns the amount of active subscriptions.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,62 @@
+package order
+
+import (
+	"food_ordering_backend/controllers/user"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHub_Publish(t *testing.T) {
+	customer := user.User{ID: 1}
+	otherCustomer := user.User{ID: 2}
+	admin := user.User{ID: 3, Roles: []user.UserRole{{Role: user.RoleAdmin}}}
+
+	t.Run("should deliver events only to subscribers that can see the order", func(t *testing.T) {
+		it := assert.New(t)
+		hub := NewHub()
+		customerSub := hub.Subscribe(customer)
+		otherSub := hub.Subscribe(otherCustomer)
+		adminSub := hub.Subscribe(admin)
+
+		e := Event{Type: EventCreated, Order: Order{ID: 10, UserID: customer.ID}}
+		hub.Publish(e)
+
+		it.Equal(e, <-customerSub.Events())
+		it.Equal(e, <-adminSub.Events())
+		it.Empty(otherSub.Events())
+	})
+
+	t.Run("should drop subscribers that don't keep up with events", func(t *testing.T) {
+		it := assert.New(t)
+		hub := NewHub()
+		slow := hub.Subscribe(admin)
+		fast := hub.Subscribe(admin)
+
+		for i := 0; i <= subscriptionBufferSize; i++ {
+			hub.Publish(Event{Type: EventUpdated, Order: Order{ID: uint(i)}})
+			<-fast.Events()
+		}
+
+		it.Equal(1, hub.Len())
+
+		received := 0
+		for range slow.Events() {
+			received++
+		}
+		it.Equal(subscriptionBufferSize, received)
+	})
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	it := assert.New(t)
+	hub := NewHub()
+	sub := hub.Subscribe(user.User{ID: 1})
+
+	hub.Unsubscribe(sub)
+	hub.Unsubscribe(sub)
+	hub.Publish(Event{Type: EventCreated, Order: Order{UserID: 1}})
+
+	_, ok := <-sub.Events()
+	it.False(ok)
+	it.Zero(hub.Len())
+}
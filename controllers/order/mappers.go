@@ -6,16 +6,33 @@ import (
 )
 
 func ToResponseDTO(o Order) ResponseDTO {
-	return ResponseDTO{
-		ID:        o.ID,
-		CreatedAt: o.CreatedAt,
-		UpdatedAt: o.UpdatedAt,
-		Status:    o.Status,
-		UserID:    o.UserID,
-		User:      user.ToResponseDTO(o.User),
-		Total:     o.Total,
-		Items:     ToItemsResponseDTO(o.Items),
+	dto := ResponseDTO{
+		ID:          o.ID,
+		CreatedAt:   o.CreatedAt,
+		UpdatedAt:   o.UpdatedAt,
+		Status:      o.Status,
+		UserID:      o.UserID,
+		User:        user.ToResponseDTO(o.User),
+		Total:       o.Total,
+		Items:       ToItemsResponseDTO(o.Items),
+		Adjustments: ToAdjustmentResponseDTOs(o.Adjustments),
+		DeliveryAddress: DeliveryAddressDTO{
+			Recipient:  o.DeliveryAddress.Recipient,
+			Phone:      o.DeliveryAddress.Phone,
+			Line1:      o.DeliveryAddress.Line1,
+			Line2:      o.DeliveryAddress.Line2,
+			City:       o.DeliveryAddress.City,
+			PostalCode: o.DeliveryAddress.PostalCode,
+			Comment:    o.DeliveryAddress.Comment,
+		},
 	}
+
+	if o.LastStatusChange != nil {
+		change := ToStatusChangeDTO(*o.LastStatusChange)
+		dto.LastTransition = &change
+	}
+
+	return dto
 }
 
 func ToResponseDTOs(orders []Order) []ResponseDTO {
@@ -29,13 +46,36 @@ func ToResponseDTOs(orders []Order) []ResponseDTO {
 }
 
 func ToItemResponseDTO(i Item) ItemResponseDTO {
+	d := dish.ToDTO(i.Dish)
+	d.Title = i.DishTitle
+	d.Price = i.UnitPrice
+	d.Category.Title = i.CategoryTitle
+
 	return ItemResponseDTO{
-		ID:       i.ID,
-		OrderID:  i.OrderID,
-		DishID:   i.DishID,
-		Dish:     dish.ToDTO(i.Dish),
-		Quantity: i.Quantity,
+		ID:        i.ID,
+		OrderID:   i.OrderID,
+		DishID:    i.DishID,
+		Dish:      d,
+		Quantity:  i.Quantity,
+		UnitPrice: i.UnitPrice,
+		Options:   ToItemOptionResponseDTOs(i.Options),
+		Cost:      i.Cost(),
+	}
+}
+
+func ToItemOptionResponseDTOs(options []ItemOption) []ItemOptionResponseDTO {
+	dtos := make([]ItemOptionResponseDTO, len(options))
+
+	for i, o := range options {
+		dtos[i] = ItemOptionResponseDTO{
+			OptionID:   o.OptionID,
+			GroupTitle: o.GroupTitle,
+			Title:      o.Title,
+			PriceDelta: o.PriceDelta,
+		}
 	}
+
+	return dtos
 }
 
 func ToItemsResponseDTO(items []Item) []ItemResponseDTO {
@@ -47,3 +87,56 @@ func ToItemsResponseDTO(items []Item) []ItemResponseDTO {
 
 	return dtos
 }
+
+func ToStatusChangeDTO(s StatusChange) StatusChangeDTO {
+	return StatusChangeDTO{
+		ID:          s.ID,
+		CreatedAt:   s.CreatedAt,
+		OrderID:     s.OrderID,
+		From:        s.FromStatus,
+		To:          s.ToStatus,
+		ChangedByID: s.ChangedByID,
+		ChangedBy:   user.ToResponseDTO(s.ChangedBy),
+	}
+}
+
+func ToStatusChangeDTOs(history []StatusChange) []StatusChangeDTO {
+	dtos := make([]StatusChangeDTO, len(history))
+
+	for i, change := range history {
+		dtos[i] = ToStatusChangeDTO(change)
+	}
+
+	return dtos
+}
+
+func ToAdjustmentResponseDTO(a Adjustment) AdjustmentResponseDTO {
+	dto := AdjustmentResponseDTO{
+		ID:          a.ID,
+		CreatedAt:   a.CreatedAt,
+		Kind:        a.Kind,
+		Value:       a.Value,
+		Reason:      a.Reason,
+		CreatedByID: a.CreatedByID,
+		CreatedBy:   user.ToResponseDTO(a.CreatedBy),
+		RevokedAt:   a.RevokedAt,
+		RevokedByID: a.RevokedByID,
+	}
+
+	if a.RevokedBy.ID != 0 {
+		revokedBy := user.ToResponseDTO(a.RevokedBy)
+		dto.RevokedBy = &revokedBy
+	}
+
+	return dto
+}
+
+func ToAdjustmentResponseDTOs(adjustments []Adjustment) []AdjustmentResponseDTO {
+	dtos := make([]AdjustmentResponseDTO, len(adjustments))
+
+	for i, a := range adjustments {
+		dtos[i] = ToAdjustmentResponseDTO(a)
+	}
+
+	return dtos
+}
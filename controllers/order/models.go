@@ -1,9 +1,11 @@
 package order
 
 import (
+	"errors"
+	"fmt"
+	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/dish"
 	"food_ordering_backend/controllers/user"
-	"math"
 	"time"
 )
 
@@ -18,6 +20,21 @@ const (
 
 var Statuses = []Status{StatusCreated, StatusInProgress, StatusDone, StatusCanceled}
 
+// transitions describes the order status state machine. Each key maps
+// to a list of statuses an order is allowed to move to. StatusDone and
+// StatusCanceled are final.
+var transitions = map[Status][]Status{
+	StatusCreated:    {StatusInProgress, StatusCanceled},
+	StatusInProgress: {StatusDone, StatusCanceled},
+}
+
+var statusTitles = map[Status]string{
+	StatusCreated:    "Created",
+	StatusInProgress: "In Progress",
+	StatusDone:       "Done",
+	StatusCanceled:   "Canceled",
+}
+
 type Items []Item
 
 type Order struct {
@@ -26,28 +43,237 @@ type Order struct {
 	UpdatedAt time.Time
 	Status    Status `gorm:"type:smallint;check:status IN (0,1,2,3)"`
 	UserID    uint
-	User      user.User `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
-	Total     float64   `gorm:"check:total >= 0"`
-	Items     []Item    `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	User      user.User    `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+	Total     common.Money `gorm:"embedded;embeddedPrefix:total_;check:chk_orders_total_amount,total_amount >= 0"`
+	Items     []Item       `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	// DeliveryAddress is empty for orders created before addresses were introduced.
+	DeliveryAddress DeliveryAddress `gorm:"embedded;embeddedPrefix:delivery_"`
+	// Adjustments are manual changes of the total made by admins.
+	Adjustments []Adjustment `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	// LastStatusChange is the latest entry from order's status history.
+	// It's read-only and must be omitted on create and save.
+	LastStatusChange *StatusChange `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// DeliveryAddress is a copy of user's address made when the order is created,
+// so that changing or deleting the address doesn't affect the order.
+type DeliveryAddress struct {
+	Recipient  string `gorm:"size:100;not null;default:''"`
+	Phone      string `gorm:"size:20;not null;default:''"`
+	Line1      string `gorm:"size:255;not null;default:''"`
+	Line2      string `gorm:"size:255;not null;default:''"`
+	City       string `gorm:"size:100;not null;default:''"`
+	PostalCode string `gorm:"size:20;not null;default:''"`
+	Comment    string `gorm:"size:255;not null;default:''"`
 }
 
+// StatusChange is a record in order's status history.
+type StatusChange struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	OrderID     uint   `gorm:"index;not null"`
+	FromStatus  Status `gorm:"type:smallint;check:from_status IN (0,1,2,3)"`
+	ToStatus    Status `gorm:"type:smallint;check:to_status IN (0,1,2,3)"`
+	ChangedByID uint
+	ChangedBy   user.User `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+}
+
+// Item is a dish in the order. Since the dish can be changed or deleted
+// after order creation, its price, title and category title are saved along
+// with the item, as well as chosen options.
 type Item struct {
-	ID       uint `gorm:"primaryKey"`
-	OrderID  uint
-	DishID   uint
-	Dish     dish.Dish `gorm:"constraint:OnUpdate:CASCADE"`
-	Quantity int       `gorm:"type:int;check:quantity > 0"`
+	ID            uint `gorm:"primaryKey"`
+	OrderID       uint
+	DishID        uint
+	Dish          dish.Dish    `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+	Quantity      int          `gorm:"type:int;check:quantity > 0"`
+	UnitPrice     common.Money `gorm:"embedded;embeddedPrefix:unit_price_;check:chk_order_items_unit_price_amount,unit_price_amount >= 0"`
+	DishTitle     string       `gorm:"size:100;not null;default:''"`
+	CategoryTitle string       `gorm:"size:255;not null;default:''"`
+	Options       []ItemOption `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// ItemOption is a copy of an option of the dish chosen for the order item.
+type ItemOption struct {
+	ID         uint `gorm:"primaryKey"`
+	ItemID     uint `gorm:"index;not null"`
+	OptionID   uint
+	Option     dish.Option  `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+	GroupTitle string       `gorm:"size:100;not null;default:''"`
+	Title      string       `gorm:"size:100;not null;default:''"`
+	PriceDelta common.Money `gorm:"embedded;embeddedPrefix:price_delta_"`
+}
+
+type AdjustmentKind string
+
+const (
+	AdjustmentDiscount   AdjustmentKind = "discount"
+	AdjustmentSurcharge  AdjustmentKind = "surcharge"
+	AdjustmentCorrection AdjustmentKind = "correction"
+)
+
+var ErrInvalidAdjustment = errors.New("invalid adjustment")
+
+// Adjustment is a change of order total on top of the cost of its items.
+// Discount decreases the total by Value, surcharge increases it and
+// correction adds Value that can be either positive or negative.
+//
+// Adjustments are never deleted, revoked ones are kept for the record
+// but don't affect the total anymore.
+type Adjustment struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	OrderID     uint           `gorm:"index;not null"`
+	Kind        AdjustmentKind `gorm:"size:20;not null;check:kind IN ('discount','surcharge','correction')"`
+	Value       common.Money   `gorm:"embedded;embeddedPrefix:value_"`
+	Reason      string         `gorm:"size:255;not null"`
+	CreatedByID uint
+	CreatedBy   user.User `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+	RevokedAt   *time.Time
+	RevokedByID *uint
+	RevokedBy   user.User `gorm:"constraint:OnDelete:SET NULL,OnUpdate:CASCADE"`
+}
+
+// IdempotencyKey remembers a request to create an order made with Idempotency-Key
+// header, so that repeated requests with the same key get the original response
+// instead of creating another order. Keys are unique per user.
+type IdempotencyKey struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_order_idempotency_keys_user_key"`
+	User      user.User `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	Key       string    `gorm:"size:255;not null;uniqueIndex:idx_order_idempotency_keys_user_key"`
+	// RequestHash is a fingerprint of the request body.
+	RequestHash string `gorm:"size:64;not null"`
+	// StatusCode is 0 while the request is being processed.
+	StatusCode int    `gorm:"not null;default:0"`
+	Response   []byte `gorm:"type:bytea"`
+	// Attempt is incremented every time the key is claimed again after its
+	// lease has expired, so that an abandoned request can't complete it.
+	Attempt int `gorm:"not null;default:0"`
+}
+
+func (k IdempotencyKey) IsCompleted() bool {
+	return k.StatusCode != 0
+}
+
+func (k IdempotencyKey) TableName() string {
+	return "order_idempotency_keys"
 }
 
 func (i Item) TableName() string {
 	return "order_items"
 }
 
-// Cost calculates the total cost of order item.
-func (i *Item) Cost() float64 {
-	res := i.Dish.Price * float64(i.Quantity)
-	// Dealing with precision problems
-	return math.Ceil(res*100) / 100
+func (o ItemOption) TableName() string {
+	return "order_item_options"
+}
+
+func (a Adjustment) TableName() string {
+	return "order_adjustments"
+}
+
+// Validate checks that adjustment has a reason and its value
+// corresponds to its kind.
+func (a Adjustment) Validate() error {
+	switch {
+	case a.Reason == "":
+		return fmt.Errorf("%w: reason is required", ErrInvalidAdjustment)
+	case a.Kind == AdjustmentCorrection && a.Value.Amount == 0:
+		return fmt.Errorf("%w: correction can't be zero", ErrInvalidAdjustment)
+	case a.Kind == AdjustmentCorrection:
+		return nil
+	case a.Kind != AdjustmentDiscount && a.Kind != AdjustmentSurcharge:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidAdjustment, a.Kind)
+	case a.Value.Amount <= 0:
+		return fmt.Errorf("%w: %s must be positive", ErrInvalidAdjustment, a.Kind)
+	}
+
+	return nil
+}
+
+func (a Adjustment) IsRevoked() bool {
+	return a.RevokedAt != nil
+}
+
+// Effect returns the amount that adjustment adds to the order total.
+func (a Adjustment) Effect() common.Money {
+	if a.Kind == AdjustmentDiscount {
+		return a.Value.Mul(-1)
+	}
+	return a.Value
+}
+
+func (s StatusChange) TableName() string {
+	return "order_status_history"
+}
+
+func (s Status) String() string {
+	if title, ok := statusTitles[s]; ok {
+		return title
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// NewItem creates order item for provided dish with a snapshot of dish's current data
+// and chosen options.
+func NewItem(d dish.Dish, quantity int, choices ...dish.Choice) Item {
+	options := make([]ItemOption, len(choices))
+
+	for i, c := range choices {
+		options[i] = ItemOption{
+			OptionID:   c.Option.ID,
+			GroupTitle: c.GroupTitle,
+			Title:      c.Option.Title,
+			PriceDelta: c.Option.PriceDelta,
+		}
+	}
+
+	return Item{
+		DishID:        d.ID,
+		Dish:          d,
+		Quantity:      quantity,
+		UnitPrice:     d.Price,
+		DishTitle:     d.Title,
+		CategoryTitle: d.Category.Title,
+		Options:       options,
+	}
+}
+
+// NewDeliveryAddress creates a snapshot of the address of provided user.
+// Recipient and phone fall back to name and phone from user's profile.
+func NewDeliveryAddress(a user.Address, u user.User) DeliveryAddress {
+	d := DeliveryAddress{
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		PostalCode: a.PostalCode,
+		Comment:    a.Comment,
+	}
+
+	if d.Recipient == "" {
+		d.Recipient = u.Name
+	}
+
+	if d.Phone == "" {
+		d.Phone = u.Phone
+	}
+
+	return d
+}
+
+// Cost calculates the total cost of order item including price deltas of its options.
+// Options are priced in the currency of the dish, it's checked when they are chosen.
+func (i *Item) Cost() common.Money {
+	price := i.UnitPrice
+
+	for _, o := range i.Options {
+		price.Amount += o.PriceDelta.Amount
+	}
+
+	return price.Mul(i.Quantity)
 }
 
 // IDs is a convenience method to extract all ids from Items.
@@ -61,15 +287,72 @@ func (items Items) IDs() []uint {
 	return ids
 }
 
-// CalcTotal calculates the total of cost of all items in a provided slice.
-func CalcTotal(items []Item) float64 {
-	var total float64
+// Quantities sums up quantities of items by dish id. Items
+// of deleted dishes are skipped.
+func (items Items) Quantities() map[uint]int {
+	quantities := make(map[uint]int)
 
 	for _, item := range items {
-		total += item.Cost()
+		if item.DishID != 0 {
+			quantities[item.DishID] += item.Quantity
+		}
+	}
+
+	return quantities
+}
+
+// StockDelta compares quantities held by an order before and after a change,
+// both keyed by dish id. Returns portions that should be additionally taken from
+// stock and portions that should be returned to it. Dishes whose quantities
+// haven't changed are in neither of them.
+func StockDelta(old, new map[uint]int) (take, restore map[uint]int) {
+	take = make(map[uint]int)
+	restore = make(map[uint]int)
+
+	for id, quantity := range new {
+		if delta := quantity - old[id]; delta > 0 {
+			take[id] = delta
+		} else if delta < 0 {
+			restore[id] = -delta
+		}
+	}
+
+	for id, quantity := range old {
+		if _, ok := new[id]; !ok {
+			restore[id] = quantity
+		}
+	}
+
+	return take, restore
+}
+
+// CalcTotal calculates the total of cost of all items in a provided slice.
+// Returns common.ErrCurrencyMismatch if items are priced in different currencies.
+func CalcTotal(items []Item) (common.Money, error) {
+	costs := make([]common.Money, len(items))
+
+	for i, item := range items {
+		costs[i] = item.Cost()
 	}
 
-	return total
+	return common.SumMoney(costs...)
+}
+
+// ApplyAdjustments adds effects of provided adjustments to the total.
+// Revoked adjustments are skipped.
+func ApplyAdjustments(total common.Money, adjustments []Adjustment) (common.Money, error) {
+	for _, a := range adjustments {
+		if a.IsRevoked() {
+			continue
+		}
+
+		var err error
+		if total, err = total.Add(a.Effect()); err != nil {
+			return common.Money{}, err
+		}
+	}
+
+	return total, nil
 }
 
 // IsValidStatus checks whether provided status is a valid Status.
@@ -84,3 +367,14 @@ func IsValidStatus(status int) bool {
 
 	return false
 }
+
+// CanTransition checks whether an order is allowed to move from one status to another.
+func CanTransition(from, to Status) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,22 +1,26 @@
 package order
 
 import (
+	"food_ordering_backend/common"
+	"food_ordering_backend/controllers/category"
 	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/user"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestItem_Cost(t *testing.T) {
 	t.Run("should correctly calculate the cost of order item", func(t *testing.T) {
 		tests := []struct {
 			item     Item
-			expected float64
+			expected common.Money
 		}{
-			{item: Item{Dish: dish.Dish{Price: 3.22}, Quantity: 3}, expected: 9.66},
-			{item: Item{Dish: dish.Dish{Price: 2.28}, Quantity: 10}, expected: 22.8},
-			{item: Item{Dish: dish.Dish{Price: 4.20}, Quantity: 4}, expected: 16.8},
-			{item: Item{Dish: dish.Dish{Price: 0.3}, Quantity: 3}, expected: 0.9},
-			{item: Item{Dish: dish.Dish{Price: 1.1}, Quantity: 3}, expected: 3.3},
+			{item: Item{UnitPrice: common.NewMoney(322), Quantity: 3}, expected: common.NewMoney(966)},
+			{item: Item{UnitPrice: common.NewMoney(228), Quantity: 10}, expected: common.NewMoney(2280)},
+			{item: Item{UnitPrice: common.NewMoney(420), Quantity: 4}, expected: common.NewMoney(1680)},
+			{item: Item{UnitPrice: common.NewMoney(30), Quantity: 3}, expected: common.NewMoney(90)},
+			{item: Item{UnitPrice: common.NewMoney(110), Quantity: 3}, expected: common.NewMoney(330)},
 		}
 
 		for _, tc := range tests {
@@ -24,45 +28,145 @@ func TestItem_Cost(t *testing.T) {
 		}
 
 	})
+
+	t.Run("should add price deltas of options to the unit price", func(t *testing.T) {
+		item := Item{
+			UnitPrice: common.NewMoney(420),
+			Quantity:  2,
+			Options:   []ItemOption{{PriceDelta: common.NewMoney(150)}, {PriceDelta: common.NewMoney(0)}, {PriceDelta: common.NewMoney(50)}},
+		}
+
+		assert.Equal(t, common.NewMoney(1240), item.Cost())
+	})
+}
+
+func TestNewItem(t *testing.T) {
+	t.Run("should create an item with a snapshot of dish data", func(t *testing.T) {
+		it := assert.New(t)
+		d := dish.Dish{
+			ID:       5,
+			Title:    "Margherita",
+			Price:    common.NewMoney(420),
+			Category: category.Category{ID: 3, Title: "Pizza"},
+		}
+
+		item := NewItem(d, 3)
+
+		it.Equal(d.ID, item.DishID)
+		it.Equal(3, item.Quantity)
+		it.Equal(d.Price, item.UnitPrice)
+		it.Equal(d.Title, item.DishTitle)
+		it.Equal(d.Category.Title, item.CategoryTitle)
+	})
+
+	t.Run("should keep a snapshot of chosen options", func(t *testing.T) {
+		it := assert.New(t)
+		large := dish.Option{ID: 2, GroupID: 1, Title: "Large", PriceDelta: common.NewMoney(150)}
+
+		item := NewItem(dish.Dish{ID: 5, Price: common.NewMoney(420)}, 2, dish.Choice{GroupTitle: "Size", Option: large})
+
+		if it.Len(item.Options, 1) {
+			it.Equal(ItemOption{OptionID: 2, GroupTitle: "Size", Title: "Large", PriceDelta: common.NewMoney(150)}, item.Options[0])
+		}
+		it.Equal(common.NewMoney(1140), item.Cost())
+	})
+
+	t.Run("should calculate cost from the snapshot", func(t *testing.T) {
+		item := NewItem(dish.Dish{Price: common.NewMoney(420)}, 3)
+		item.Dish.Price = common.NewMoney(1000)
+
+		assert.Equal(t, common.NewMoney(1260), item.Cost())
+	})
+}
+
+func TestNewDeliveryAddress(t *testing.T) {
+	a := user.Address{
+		Recipient:  "Jane Doe",
+		Phone:      "+14155552671",
+		Line1:      "1 Main St",
+		Line2:      "Apt 2",
+		City:       "Springfield",
+		PostalCode: "12345",
+		Comment:    "Door code 42",
+	}
+	u := user.User{Name: "John Doe", Phone: "+14155550000"}
+
+	t.Run("should copy the address", func(t *testing.T) {
+		assert.Equal(t, DeliveryAddress{
+			Recipient:  "Jane Doe",
+			Phone:      "+14155552671",
+			Line1:      "1 Main St",
+			Line2:      "Apt 2",
+			City:       "Springfield",
+			PostalCode: "12345",
+			Comment:    "Door code 42",
+		}, NewDeliveryAddress(a, u))
+	})
+
+	t.Run("should fall back to name and phone of the user", func(t *testing.T) {
+		it := assert.New(t)
+		a := a
+		a.Recipient = ""
+		a.Phone = ""
+
+		d := NewDeliveryAddress(a, u)
+
+		it.Equal(u.Name, d.Recipient)
+		it.Equal(u.Phone, d.Phone)
+	})
 }
 
 func TestCalcTotal(t *testing.T) {
 	t.Run("should correctly calculate total cost", func(t *testing.T) {
 		tests := []struct {
 			items    []Item
-			expected float64
+			expected common.Money
 		}{
 			{
 				items: []Item{
-					{Dish: dish.Dish{Price: 3.22}, Quantity: 3},
-					{Dish: dish.Dish{Price: 0.3}, Quantity: 3},
+					{UnitPrice: common.NewMoney(322), Quantity: 3},
+					{UnitPrice: common.NewMoney(30), Quantity: 3},
 				},
-				expected: 10.56,
+				expected: common.NewMoney(1056),
 			},
 			{
 				items: []Item{
-					{Dish: dish.Dish{Price: 2.28}, Quantity: 10},
-					{Dish: dish.Dish{Price: 4.20}, Quantity: 4},
-					{Dish: dish.Dish{Price: 1.1}, Quantity: 3},
+					{UnitPrice: common.NewMoney(228), Quantity: 10},
+					{UnitPrice: common.NewMoney(420), Quantity: 4},
+					{UnitPrice: common.NewMoney(110), Quantity: 3},
 				},
-				expected: 42.9,
+				expected: common.NewMoney(4290),
 			},
 			{
 				items: []Item{
-					{Dish: dish.Dish{Price: 3.22}, Quantity: 3},
-					{Dish: dish.Dish{Price: 2.28}, Quantity: 10},
-					{Dish: dish.Dish{Price: 4.20}, Quantity: 4},
-					{Dish: dish.Dish{Price: 0.3}, Quantity: 3},
-					{Dish: dish.Dish{Price: 1.1}, Quantity: 3},
+					{UnitPrice: common.NewMoney(322), Quantity: 3},
+					{UnitPrice: common.NewMoney(228), Quantity: 10},
+					{UnitPrice: common.NewMoney(420), Quantity: 4},
+					{UnitPrice: common.NewMoney(30), Quantity: 3},
+					{UnitPrice: common.NewMoney(110), Quantity: 3},
 				},
-				expected: 53.46,
+				expected: common.NewMoney(5346),
 			},
 		}
 
 		for _, tc := range tests {
-			assert.Equal(t, tc.expected, CalcTotal(tc.items))
+			total, err := CalcTotal(tc.items)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, total)
+			}
 		}
 	})
+
+	t.Run("should return error if items are priced in different currencies", func(t *testing.T) {
+		items := []Item{
+			{UnitPrice: common.NewMoney(322), Quantity: 3},
+			{UnitPrice: common.Money{Amount: 30, Currency: "EUR"}, Quantity: 3},
+		}
+
+		_, err := CalcTotal(items)
+		assert.ErrorIs(t, err, common.ErrCurrencyMismatch)
+	})
 }
 
 func TestItems_IDs(t *testing.T) {
@@ -73,6 +177,40 @@ func TestItems_IDs(t *testing.T) {
 	})
 }
 
+func TestItems_Quantities(t *testing.T) {
+	items := Items{{DishID: 1, Quantity: 2}, {DishID: 3, Quantity: 1}, {DishID: 1, Quantity: 4}, {Quantity: 5}}
+
+	t.Run("should sum quantities by dish id and skip deleted dishes", func(t *testing.T) {
+		assert.Equal(t, map[uint]int{1: 6, 3: 1}, items.Quantities())
+	})
+}
+
+func TestStockDelta(t *testing.T) {
+	t.Run("should take and restore only differences in quantities", func(t *testing.T) {
+		take, restore := StockDelta(map[uint]int{1: 2, 2: 3, 3: 1}, map[uint]int{1: 2, 2: 5, 4: 1})
+
+		assert.Equal(t, map[uint]int{2: 2, 4: 1}, take)
+		assert.Equal(t, map[uint]int{3: 1}, restore)
+	})
+
+	t.Run("should restore decreased quantities", func(t *testing.T) {
+		take, restore := StockDelta(map[uint]int{1: 4}, map[uint]int{1: 1})
+
+		assert.Empty(t, take)
+		assert.Equal(t, map[uint]int{1: 3}, restore)
+	})
+
+	t.Run("should work with nil quantities", func(t *testing.T) {
+		take, restore := StockDelta(nil, map[uint]int{1: 4})
+		assert.Equal(t, map[uint]int{1: 4}, take)
+		assert.Empty(t, restore)
+
+		take, restore = StockDelta(map[uint]int{1: 4}, nil)
+		assert.Empty(t, take)
+		assert.Equal(t, map[uint]int{1: 4}, restore)
+	})
+}
+
 func TestIsValidStatus(t *testing.T) {
 	t.Run("should return true if status is valid", func(t *testing.T) {
 		for _, status := range Statuses {
@@ -88,3 +226,103 @@ func TestIsValidStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestCanTransition(t *testing.T) {
+	t.Run("should allow moves from the transitions table", func(t *testing.T) {
+		tests := []struct{ from, to Status }{
+			{StatusCreated, StatusInProgress},
+			{StatusCreated, StatusCanceled},
+			{StatusInProgress, StatusDone},
+			{StatusInProgress, StatusCanceled},
+		}
+
+		for _, tc := range tests {
+			assert.Truef(t, CanTransition(tc.from, tc.to), "expected %q -> %q to be allowed", tc.from, tc.to)
+		}
+	})
+
+	t.Run("should reject illegal moves", func(t *testing.T) {
+		tests := []struct{ from, to Status }{
+			{StatusCreated, StatusCreated},
+			{StatusCreated, StatusDone},
+			{StatusInProgress, StatusCreated},
+			{StatusDone, StatusCreated},
+			{StatusDone, StatusInProgress},
+			{StatusDone, StatusCanceled},
+			{StatusCanceled, StatusCreated},
+			{StatusCanceled, StatusInProgress},
+			{StatusCanceled, StatusDone},
+		}
+
+		for _, tc := range tests {
+			assert.Falsef(t, CanTransition(tc.from, tc.to), "expected %q -> %q to be rejected", tc.from, tc.to)
+		}
+	})
+}
+
+func TestAdjustment_Validate(t *testing.T) {
+	t.Run("should accept valid adjustments", func(t *testing.T) {
+		tests := []Adjustment{
+			{Kind: AdjustmentDiscount, Value: common.NewMoney(100), Reason: "Loyal customer"},
+			{Kind: AdjustmentSurcharge, Value: common.NewMoney(250), Reason: "Night delivery"},
+			{Kind: AdjustmentCorrection, Value: common.NewMoney(-30), Reason: "Rounding"},
+			{Kind: AdjustmentCorrection, Value: common.NewMoney(30), Reason: "Rounding"},
+		}
+
+		for _, a := range tests {
+			assert.NoError(t, a.Validate())
+		}
+	})
+
+	t.Run("should reject invalid adjustments", func(t *testing.T) {
+		tests := []Adjustment{
+			{Kind: AdjustmentDiscount, Value: common.NewMoney(100)},
+			{Kind: AdjustmentDiscount, Value: common.NewMoney(-100), Reason: "Negative discount"},
+			{Kind: AdjustmentSurcharge, Value: common.NewMoney(0), Reason: "Zero surcharge"},
+			{Kind: AdjustmentCorrection, Value: common.NewMoney(0), Reason: "Zero correction"},
+			{Kind: "gift", Value: common.NewMoney(100), Reason: "Unknown kind"},
+		}
+
+		for _, a := range tests {
+			assert.ErrorIs(t, a.Validate(), ErrInvalidAdjustment)
+		}
+	})
+}
+
+func TestApplyAdjustments(t *testing.T) {
+	t.Run("should apply effects of all adjustments to the total", func(t *testing.T) {
+		it := assert.New(t)
+		adjustments := []Adjustment{
+			{Kind: AdjustmentDiscount, Value: common.NewMoney(100)},
+			{Kind: AdjustmentSurcharge, Value: common.NewMoney(250)},
+			{Kind: AdjustmentCorrection, Value: common.NewMoney(-30)},
+		}
+
+		total, err := ApplyAdjustments(common.NewMoney(1000), adjustments)
+
+		if it.NoError(err) {
+			it.Equal(common.NewMoney(1120), total)
+		}
+	})
+
+	t.Run("should skip revoked adjustments", func(t *testing.T) {
+		it := assert.New(t)
+		revokedAt := time.Now()
+		adjustments := []Adjustment{
+			{Kind: AdjustmentDiscount, Value: common.NewMoney(100), RevokedAt: &revokedAt},
+			{Kind: AdjustmentSurcharge, Value: common.NewMoney(250)},
+		}
+
+		total, err := ApplyAdjustments(common.NewMoney(1000), adjustments)
+
+		if it.NoError(err) {
+			it.Equal(common.NewMoney(1250), total)
+		}
+	})
+
+	t.Run("should return error if currencies don't match", func(t *testing.T) {
+		adjustments := []Adjustment{{Kind: AdjustmentSurcharge, Value: common.Money{Amount: 100, Currency: "EUR"}}}
+		_, err := ApplyAdjustments(common.NewMoney(1000), adjustments)
+		assert.ErrorIs(t, err, common.ErrCurrencyMismatch)
+	})
+}
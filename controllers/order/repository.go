@@ -2,7 +2,11 @@ package order
 
 import (
 	"food_ordering_backend/common"
+	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/user"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type Repository struct {
@@ -13,8 +17,13 @@ func ProvideRepository(db *gorm.DB) *Repository {
 	return &Repository{db}
 }
 
+// WithTx returns a copy of the repository that runs all queries in provided transaction.
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{tx}
+}
+
 func (r *Repository) Create(o Order) (Order, error) {
-	err := r.db.Omit("Items.Dish").Create(&o).Error
+	err := r.db.Omit("Items.Dish", "LastStatusChange").Create(&o).Error
 
 	if err != nil {
 		return o, err
@@ -24,8 +33,9 @@ func (r *Repository) Create(o Order) (Order, error) {
 	return o, err
 }
 
+// Save updates the order and its adjustments. Items are changed separately.
 func (r *Repository) Save(o Order) (Order, error) {
-	if err := r.db.Omit("User").Save(&o).Error; err != nil {
+	if err := r.db.Omit("User", "Items", "LastStatusChange", "Adjustments.CreatedBy", "Adjustments.RevokedBy").Save(&o).Error; err != nil {
 		return Order{}, err
 	}
 
@@ -37,22 +47,17 @@ func (r *Repository) Save(o Order) (Order, error) {
 	return updated, nil
 }
 
-// FindAll returns all orders for user with specified user ID.
-// If user ID equals 0, return orders for all users instead.
+// FindAll returns all orders matching the filter, sorted as the filter specifies.
 // If Paginator is not nil, it returns paginated result.
-func (r *Repository) FindAll(uid uint, p common.Paginator) ([]Order, error) {
+func (r *Repository) FindAll(f Filter, p common.Paginator) ([]Order, error) {
 	var orders []Order
-	tx := r.preload()
-
-	if uid != 0 {
-		tx.Where("user_id = ?", uid)
-	}
+	tx := r.preload().Scopes(f.Where, f.OrderBy)
 
 	if p != nil {
-		tx.Scopes(common.WithPagination(p))
+		tx = tx.Scopes(common.WithPagination(p))
 	}
 
-	err := tx.Order("id ASC").Find(&orders).Error
+	err := tx.Find(&orders).Error
 	return orders, err
 }
 
@@ -62,29 +67,157 @@ func (r *Repository) FindByID(id uint) (Order, error) {
 	return order, err
 }
 
-func (r *Repository) UpdateStatus(id uint, status Status) error {
-	o := Order{
-		ID: id,
+// UpdateStatus changes status of the order and records the change in
+// order's status history. Returns gorm.ErrRecordNotFound if the order
+// doesn't exist or its status isn't change.FromStatus anymore.
+func (r *Repository) UpdateStatus(change StatusChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		o := Order{ID: change.OrderID}
+		res := tx.Model(&o).Where("status = ?", change.FromStatus).Update("status", change.ToStatus)
+
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Omit("ChangedBy").Create(&change).Error
+	})
+}
+
+// LockOrder locks the order row until the transaction ends, so that concurrent
+// transactions changing the same order are run one by one. Returns gorm.ErrRecordNotFound
+// if the order doesn't exist or its status isn't provided status anymore.
+func (r *Repository) LockOrder(id uint, status Status) error {
+	var ids []uint
+	err := r.db.Model(&Order{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND status = ?", id, status).Pluck("id", &ids).Error
+
+	if err == nil && len(ids) == 0 {
+		return gorm.ErrRecordNotFound
 	}
-	return r.db.Model(&o).Update("status", status).Error
+
+	return err
+}
+
+func (r *Repository) CreateStatusChange(change StatusChange) error {
+	return r.db.Omit("ChangedBy").Create(&change).Error
+}
+
+// FindStatusHistory returns status changes for order with provided id
+// in chronological order.
+func (r *Repository) FindStatusHistory(orderID uint) ([]StatusChange, error) {
+	var history []StatusChange
+	err := r.db.
+		Joins("ChangedBy").
+		Preload("ChangedBy.Roles").
+		Where("order_id = ?", orderID).
+		Order("order_status_history.id ASC").
+		Find(&history).Error
+	return history, err
+}
+
+func (r *Repository) FindItems(orderID uint) ([]Item, error) {
+	var items []Item
+	err := r.db.Where("order_id = ?", orderID).Find(&items).Error
+	return items, err
+}
+
+// CreateItems adds provided items to the order with provided id.
+func (r *Repository) CreateItems(orderID uint, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	for i := range items {
+		items[i].OrderID = orderID
+	}
+
+	return r.db.Omit("Dish").Create(&items).Error
+}
+
+func (r *Repository) UpdateItemQuantity(id uint, quantity int) error {
+	return r.db.Model(&Item{ID: id}).Update("quantity", quantity).Error
 }
 
 func (r *Repository) DeleteItemsByID(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
 	return r.db.Delete(Item{}, ids).Error
 }
 
-// CountAll returns total amount of orders for user with specified ID.
-// If ID equals 0, returns total amount of orders instead.
-func (r *Repository) CountAll(uid uint) int {
+// RevokeAdjustments marks adjustments with provided ids as revoked by provided user.
+// Adjustments that have already been revoked are left as they are.
+func (r *Repository) RevokeAdjustments(ids []uint, by user.User) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return r.db.Model(&Adjustment{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_by_id": by.ID}).Error
+}
+
+// CountAll returns total amount of orders matching the filter.
+func (r *Repository) CountAll(f Filter) int {
 	var count int64
-	tx := r.db.Model(&Order{})
+	r.db.Model(&Order{}).Scopes(f.Where).Count(&count)
+	return int(count)
+}
 
-	if uid != 0 {
-		tx.Where("user_id = ?", uid)
+func (r *Repository) CreateIdempotencyKey(k IdempotencyKey) (IdempotencyKey, error) {
+	err := r.db.Omit("User").Create(&k).Error
+	return k, err
+}
+
+func (r *Repository) FindIdempotencyKey(uid uint, key string) (IdempotencyKey, error) {
+	var k IdempotencyKey
+	err := r.db.Where("user_id = ? AND key = ?", uid, key).First(&k).Error
+	return k, err
+}
+
+// ClaimIdempotencyKey takes over the key if it's still in progress, hasn't been
+// claimed by anyone else since it was read and was claimed before provided time.
+// Returns the claimed key and false if the key can't be claimed.
+func (r *Repository) ClaimIdempotencyKey(k IdempotencyKey, claimedBefore time.Time) (IdempotencyKey, bool, error) {
+	now := time.Now()
+	res := r.db.Model(&IdempotencyKey{}).
+		Where("id = ? AND status_code = 0 AND attempt = ? AND created_at < ?", k.ID, k.Attempt, claimedBefore).
+		Updates(map[string]interface{}{"created_at": now, "attempt": k.Attempt + 1})
+
+	if res.Error != nil || res.RowsAffected == 0 {
+		return k, false, res.Error
 	}
 
-	tx.Count(&count)
-	return int(count)
+	k.CreatedAt = now
+	k.Attempt++
+	return k, true, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request made with the key.
+// Returns ErrIdempotencyKeyLost if the key has been claimed by another request.
+func (r *Repository) CompleteIdempotencyKey(k IdempotencyKey, statusCode int, response []byte) error {
+	res := r.db.Model(&IdempotencyKey{}).
+		Where("id = ? AND status_code = 0 AND attempt = ?", k.ID, k.Attempt).
+		Updates(IdempotencyKey{StatusCode: statusCode, Response: response})
+
+	if res.Error == nil && res.RowsAffected == 0 {
+		return ErrIdempotencyKeyLost
+	}
+
+	return res.Error
+}
+
+// DeleteIdempotencyKey deletes the key unless it has been claimed by another request.
+func (r *Repository) DeleteIdempotencyKey(k IdempotencyKey) error {
+	return r.db.Where("attempt = ?", k.Attempt).Delete(&IdempotencyKey{}, k.ID).Error
+}
+
+// DeleteExpiredIdempotencyKeys deletes keys of the user that were created before provided time.
+func (r *Repository) DeleteExpiredIdempotencyKeys(uid uint, before time.Time) error {
+	return r.db.Where("user_id = ? AND created_at < ?", uid, before).Delete(&IdempotencyKey{}).Error
 }
 
 func (r *Repository) preload() *gorm.DB {
@@ -92,5 +225,22 @@ func (r *Repository) preload() *gorm.DB {
 		Preload("Items").
 		Preload("Items.Dish").
 		Preload("Items.Dish.Category").
+		Preload("Items.Dish.Images", dish.PreloadImages).
+		Preload("Items.Options", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_item_options.id ASC")
+		}).
+		Preload("Adjustments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("order_adjustments.id ASC")
+		}).
+		Preload("Adjustments.CreatedBy.Roles").
+		Preload("Adjustments.RevokedBy.Roles").
+		Preload("LastStatusChange", func(db *gorm.DB) *gorm.DB {
+			// preload query is limited to loaded orders, DISTINCT ON picks the latest change of each
+			return db.
+				Select("DISTINCT ON (order_status_history.order_id) order_status_history.*").
+				Order("order_status_history.order_id, order_status_history.id DESC")
+		}).
+		Preload("LastStatusChange.ChangedBy.Roles").
+		Preload("User.Roles").
 		Joins("User")
 }
@@ -1,17 +1,25 @@
 package order
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"food_ordering_backend/common"
+	"food_ordering_backend/config"
 	"food_ordering_backend/controllers/dish"
 	"food_ordering_backend/controllers/user"
 	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"time"
 )
 
 type Service struct {
-	repo   *Repository
-	dishes *dish.Service
+	repo       *Repository
+	dishes     *dish.Service
+	addresses  *user.AddressService
+	hub        *Hub
+	transactor *common.Transactor
 }
 
 type ErrDishID struct {
@@ -22,6 +30,28 @@ type ErrOrderID struct {
 	ID uint
 }
 
+type ErrAdjustmentID struct {
+	ID uint
+}
+
+type ErrItemID struct {
+	ID uint
+}
+
+// ErrIllegalTransition indicates that order can't move from one status to another.
+type ErrIllegalTransition struct {
+	From Status
+	To   Status
+}
+
+var ErrNotOwner = errors.New("order belongs to another user")
+var ErrNegativeTotal = errors.New("order total can't be negative")
+var ErrCancelNotAllowed = errors.New("only orders that haven't been taken into work can be canceled")
+var ErrCancelWindowExpired = errors.New("order can no longer be canceled")
+var ErrIdempotencyKeyReused = errors.New("idempotency key has already been used for a different request")
+var ErrIdempotencyKeyInProgress = errors.New("request with the same idempotency key is still being processed")
+var ErrIdempotencyKeyLost = errors.New("idempotency key has been claimed by another request")
+
 func (e *ErrDishID) Error() string {
 	return fmt.Sprintf("Dish with id %d doesn't exist", e.ID)
 }
@@ -30,15 +60,26 @@ func (e *ErrOrderID) Error() string {
 	return fmt.Sprintf("Order with id %d doesn't exist", e.ID)
 }
 
-func ProvideService(repo *Repository, dishes *dish.Service) *Service {
-	return &Service{repo, dishes}
+func (e *ErrAdjustmentID) Error() string {
+	return fmt.Sprintf("Adjustment with id %d doesn't exist in the order", e.ID)
+}
+
+func (e *ErrItemID) Error() string {
+	return fmt.Sprintf("Item with id %d doesn't exist in the order", e.ID)
+}
+
+func (e *ErrIllegalTransition) Error() string {
+	return fmt.Sprintf("Can't change order status from %q to %q", e.From, e.To)
 }
 
-// FindAll returns all orders for user with specified user ID.
-// If user ID equals 0, return orders for all users instead.
+func ProvideService(repo *Repository, dishes *dish.Service, addresses *user.AddressService, hub *Hub, transactor *common.Transactor) *Service {
+	return &Service{repo, dishes, addresses, hub, transactor}
+}
+
+// FindAll returns all orders matching the filter.
 // If Paginator is not nil, it returns paginated result.
-func (s *Service) FindAll(uid uint, p common.Paginator) ([]Order, error) {
-	return s.repo.FindAll(uid, p)
+func (s *Service) FindAll(f Filter, p common.Paginator) ([]Order, error) {
+	return s.repo.FindAll(f, p)
 }
 
 func (s *Service) FindByID(id uint) (Order, error) {
@@ -55,46 +96,370 @@ func (s *Service) FindByID(id uint) (Order, error) {
 	return o, nil
 }
 
-func (s *Service) Create(itemsDTO []ItemCreateDTO, u user.User) (Order, error) {
-	items, err := s.ItemsFromDTOs(itemsDTO)
+// Create creates an order of provided user delivered to the address from dto
+// or, if it's omitted, to the default address of the user. Returns user.ErrAddressID
+// if the user doesn't have such address and user.ErrNoDefaultAddress if the user
+// has no addresses. Ordered portions are taken from stock of dishes, if any of
+// dishes is sold out, *dish.ErrInsufficientStock is returned.
+//
+// If k isn't nil, the reserved idempotency key is completed with the response
+// in the same transaction, so that the order can't be created without it.
+// Returns ErrIdempotencyKeyLost if the key has been claimed by another request.
+func (s *Service) Create(dto CreateDTO, u user.User, k *IdempotencyKey) (Order, error) {
+	items, err := s.ItemsFromDTOs(dto.Items)
+
+	if err != nil {
+		return Order{}, err
+	}
+
+	address, err := s.deliveryAddress(dto.AddressID, u)
+
+	if err != nil {
+		return Order{}, err
+	}
+
+	total, err := CalcTotal(items)
 
 	if err != nil {
 		return Order{}, err
 	}
 
 	o := Order{
-		UserID: u.ID,
-		Status: StatusCreated,
-		Items:  items,
-		Total:  CalcTotal(items),
+		UserID:          u.ID,
+		Status:          StatusCreated,
+		Items:           items,
+		Total:           total,
+		DeliveryAddress: NewDeliveryAddress(address, u),
 	}
 
-	return s.repo.Create(o)
+	err = s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := s.dishes.WithTx(tx).TakeStock(Items(items).Quantities()); err != nil {
+			return err
+		}
+
+		if o, err = repo.Create(o); err != nil || k == nil {
+			return err
+		}
+
+		response, err := json.Marshal(ToResponseDTO(o))
+
+		if err != nil {
+			return err
+		}
+
+		return repo.CompleteIdempotencyKey(*k, http.StatusCreated, response)
+	})
+
+	if err != nil {
+		return Order{}, err
+	}
+
+	s.hub.Publish(Event{Type: EventCreated, Order: o})
+	return o, nil
 }
 
-func (s *Service) Update(o Order, dto UpdateDTO) (Order, error) {
-	items, err := s.ItemsFromDTOs(dto.Items)
+// Update replaces order with data from provided dto on behalf of provided user.
+// If status has been changed, it will be validated against status transitions
+// table and recorded in order's status history. Previous items return to stock
+// of dishes and new ones are taken from it, unless the order is canceled.
+// Returns ErrIllegalTransition if the status has been changed by someone else
+// since the order was read.
+func (s *Service) Update(o Order, dto UpdateDTO, by user.User) (Order, error) {
+	prevStatus := o.Status
+
+	if prevStatus != dto.Status && !CanTransition(prevStatus, dto.Status) {
+		return Order{}, &ErrIllegalTransition{From: prevStatus, To: dto.Status}
+	}
+
+	var newItemsDTO []ItemCreateDTO
+
+	for _, item := range dto.Items {
+		if item.ItemID == 0 {
+			newItemsDTO = append(newItemsDTO, item)
+		}
+	}
+
+	newItems, err := s.ItemsFromDTOs(newItemsDTO)
 
 	if err != nil {
 		return Order{}, err
 	}
 
-	if err := s.repo.DeleteItemsByID(Items(o.Items).IDs()); err != nil {
+	err = s.transactor.Transaction(func(tx *gorm.DB) error {
+		var err error
+		repo := s.repo.WithTx(tx)
+
+		// the order stays locked until the transaction ends, so that it isn't
+		// changed concurrently, and it's read again in case it has been changed
+		// since it was read
+		if err = repo.LockOrder(o.ID, prevStatus); err != nil {
+			return err
+		}
+
+		if o, err = repo.FindByID(o.ID); err != nil {
+			return err
+		}
+
+		adjustments, revoked, err := AdjustmentsFromDTOs(o.Adjustments, dto.Adjustments, by)
+
+		if err != nil {
+			return err
+		}
+
+		kept, removedItems, err := KeepItems(o.Items, dto.Items)
+
+		if err != nil {
+			return err
+		}
+
+		items := append(kept, newItems...)
+		total, err := CalcTotal(items)
+
+		if err != nil {
+			return err
+		}
+
+		if o.Total, err = ApplyAdjustments(total, adjustments); err != nil {
+			return err
+		}
+
+		if o.Total.IsNegative() {
+			return ErrNegativeTotal
+		}
+
+		oldQuantities := Items(o.Items).Quantities()
+		o.Status = dto.Status
+		o.UserID = dto.UserID
+		o.Items = nil
+		o.Adjustments = adjustments
+
+		if err = repo.DeleteItemsByID(removedItems); err != nil {
+			return err
+		}
+
+		for _, item := range kept {
+			if err = repo.UpdateItemQuantity(item.ID, item.Quantity); err != nil {
+				return err
+			}
+		}
+
+		if err = repo.CreateItems(o.ID, newItems); err != nil {
+			return err
+		}
+
+		if err = repo.RevokeAdjustments(revoked, by); err != nil {
+			return err
+		}
+
+		if o, err = repo.Save(o); err != nil {
+			return err
+		}
+
+		// items of canceled orders don't hold stock, the rest change it only
+		// by the difference, so that unchanged dishes aren't checked again
+		newQuantities := Items(items).Quantities()
+
+		if prevStatus == StatusCanceled {
+			oldQuantities = nil
+		}
+
+		if dto.Status == StatusCanceled {
+			newQuantities = nil
+		}
+
+		take, restore := StockDelta(oldQuantities, newQuantities)
+		dishes := s.dishes.WithTx(tx)
+
+		if err = dishes.RestoreStock(restore); err != nil {
+			return err
+		}
+
+		if err = dishes.TakeStock(take); err != nil {
+			return err
+		}
+
+		if prevStatus == dto.Status {
+			return nil
+		}
+
+		err = repo.CreateStatusChange(StatusChange{
+			OrderID:     o.ID,
+			FromStatus:  prevStatus,
+			ToStatus:    dto.Status,
+			ChangedByID: by.ID,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		o, err = repo.FindByID(o.ID)
+		return err
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Order{}, &ErrIllegalTransition{From: prevStatus, To: dto.Status}
+	}
+
+	if err != nil {
 		return Order{}, err
 	}
 
-	o.Status = dto.Status
-	o.UserID = dto.UserID
-	o.Total = dto.Total
-	o.Items = items
+	if prevStatus == dto.Status {
+		s.hub.Publish(Event{Type: EventUpdated, Order: o})
+	} else {
+		s.hub.Publish(Event{Type: EventStatusChanged, Order: o})
+	}
+
+	return o, nil
+}
+
+// UpdateStatus moves provided order to a new status on behalf of provided user.
+// Items of canceled orders return to stock of dishes. Returns ErrIllegalTransition
+// if the move isn't allowed or the status has been changed by someone else since
+// the order was read.
+func (s *Service) UpdateStatus(o Order, status Status, by user.User) error {
+	if !CanTransition(o.Status, status) {
+		return &ErrIllegalTransition{From: o.Status, To: status}
+	}
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		err := repo.UpdateStatus(StatusChange{
+			OrderID:     o.ID,
+			FromStatus:  o.Status,
+			ToStatus:    status,
+			ChangedByID: by.ID,
+		})
+
+		if err != nil || status != StatusCanceled {
+			return err
+		}
+
+		// items are read again, since they could have been changed after
+		// the order was loaded and the order is locked only from now on
+		items, err := repo.FindItems(o.ID)
+
+		if err != nil {
+			return err
+		}
+
+		return s.dishes.WithTx(tx).RestoreStock(Items(items).Quantities())
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &ErrIllegalTransition{From: o.Status, To: status}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// status has already been changed, so failing to notify subscribers
+	// isn't an error of the update itself
+	if updated, err := s.repo.FindByID(o.ID); err == nil {
+		s.hub.Publish(Event{Type: EventStatusChanged, Order: updated})
+	}
+
+	return nil
+}
+
+// Cancel cancels provided order on behalf of its owner. Order can only be
+// canceled while it has StatusCreated and within config.OrderCancelWindow
+// since its creation.
+func (s *Service) Cancel(o Order, owner user.User) error {
+	if o.UserID != owner.ID {
+		return ErrNotOwner
+	}
+
+	if o.Status != StatusCreated {
+		return ErrCancelNotAllowed
+	}
+
+	if time.Since(o.CreatedAt) > config.OrderCancelWindow {
+		return ErrCancelWindowExpired
+	}
+
+	return s.UpdateStatus(o, StatusCanceled, owner)
+}
+
+// ReserveIdempotencyKey marks key as used by provided user for the request with
+// provided hash. If the user has already used the key within config.IdempotencyKeyRetention,
+// it returns the stored key with replay set to true, so that its response can be
+// sent again. Returns ErrIdempotencyKeyReused if the key was used for a different
+// request and ErrIdempotencyKeyInProgress if the original request hasn't completed yet.
+//
+// A key that has been in progress for longer than config.IdempotencyKeyLease
+// is considered abandoned and is claimed again for the new request.
+//
+// Reserved key must be either completed by Create or released with ReleaseIdempotencyKey.
+func (s *Service) ReserveIdempotencyKey(u user.User, key, requestHash string) (k IdempotencyKey, replay bool, err error) {
+	if err := s.repo.DeleteExpiredIdempotencyKeys(u.ID, time.Now().Add(-config.IdempotencyKeyRetention)); err != nil {
+		return k, false, err
+	}
+
+	k, err = s.repo.CreateIdempotencyKey(IdempotencyKey{UserID: u.ID, Key: key, RequestHash: requestHash})
+
+	if err == nil {
+		return k, false, nil
+	}
+
+	if !common.IsDuplicateKeyErr(err) {
+		return k, false, err
+	}
+
+	if k, err = s.repo.FindIdempotencyKey(u.ID, key); err != nil {
+		return k, false, err
+	}
+
+	if k.RequestHash != requestHash {
+		return k, false, ErrIdempotencyKeyReused
+	}
+
+	if !k.IsCompleted() {
+		claimed, ok, err := s.repo.ClaimIdempotencyKey(k, time.Now().Add(-config.IdempotencyKeyLease))
+
+		if err != nil {
+			return k, false, err
+		}
+
+		if !ok {
+			return k, false, ErrIdempotencyKeyInProgress
+		}
+
+		return claimed, false, nil
+	}
+
+	return k, true, nil
+}
+
+// ReleaseIdempotencyKey makes the key available again, e.g. when the request
+// made with it has failed. Keys claimed by another request are left as they are.
+func (s *Service) ReleaseIdempotencyKey(k IdempotencyKey) error {
+	return s.repo.DeleteIdempotencyKey(k)
+}
+
+// Subscribe creates a subscription to events of orders visible to provided user.
+// It must be removed with Unsubscribe once it's not needed anymore.
+func (s *Service) Subscribe(u user.User) *Subscription {
+	return s.hub.Subscribe(u)
+}
 
-	return s.repo.Save(o)
+func (s *Service) Unsubscribe(sub *Subscription) {
+	s.hub.Unsubscribe(sub)
 }
 
-func (s *Service) UpdateStatus(id uint, status Status) error {
-	return s.repo.UpdateStatus(id, status)
+// FindStatusHistory returns status changes of the order with provided id
+// in chronological order.
+func (s *Service) FindStatusHistory(orderID uint) ([]StatusChange, error) {
+	return s.repo.FindStatusHistory(orderID)
 }
 
+// ItemsFromDTOs creates items with snapshots of dishes and options chosen for them.
+// Returns *ErrDishID if any of dishes doesn't exist and dish.ErrInvalidChoice
+// if chosen options don't satisfy option groups of the dish.
 func (s *Service) ItemsFromDTOs(itemsDTO []ItemCreateDTO) ([]Item, error) {
 	ids := make([]uint, len(itemsDTO))
 
@@ -108,6 +473,12 @@ func (s *Service) ItemsFromDTOs(itemsDTO []ItemCreateDTO) ([]Item, error) {
 		return nil, err
 	}
 
+	groups, err := s.dishes.FindOptionGroups(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
 	items := make([]Item, len(itemsDTO))
 	for i, dto := range itemsDTO {
 		d, ok := dish.Dishes(dishes).Find(func(d dish.Dish, index int) bool {
@@ -118,19 +489,140 @@ func (s *Service) ItemsFromDTOs(itemsDTO []ItemCreateDTO) ([]Item, error) {
 			return nil, &ErrDishID{ID: dto.ID}
 		}
 
-		item := Item{
-			DishID:   dto.ID,
-			Quantity: dto.Quantity,
-			Dish:     d,
+		choices, err := groups[d.ID].Choose(dto.Options)
+
+		if err != nil {
+			return nil, fmt.Errorf("dish %d: %w", d.ID, err)
 		}
-		items[i] = item
+
+		for _, c := range choices {
+			if c.Option.PriceDelta.Currency != d.Price.Currency {
+				return nil, common.ErrCurrencyMismatch
+			}
+		}
+
+		items[i] = NewItem(d, dto.Quantity, choices...)
 	}
 
 	return items, nil
 }
 
-// CountAll returns total amount of orders for user with specified ID.
-// If ID equals 0, returns total amount of orders instead.
-func (s *Service) CountAll(uid uint) int {
-	return s.repo.CountAll(uid)
+// deliveryAddress returns address of the user with provided id or the default
+// address of the user if id is 0.
+func (s *Service) deliveryAddress(id uint, u user.User) (user.Address, error) {
+	if id == 0 {
+		return s.addresses.FindDefault(u)
+	}
+	return s.addresses.FindByID(u, id)
+}
+
+// CountAll returns total amount of orders matching the filter.
+func (s *Service) CountAll(f Filter) int {
+	return s.repo.CountAll(f)
+}
+
+// KeepItems returns existing items referenced by item id in dtos with quantities
+// from dtos and ids of the rest of existing items, which should be removed.
+// Dtos without item id are skipped. Returns *ErrItemID if any of dtos references
+// an item that doesn't exist or has already been referenced.
+func KeepItems(existing []Item, dtos []ItemCreateDTO) ([]Item, []uint, error) {
+	var kept []Item
+	keep := make(map[uint]bool)
+
+	for _, dto := range dtos {
+		if dto.ItemID == 0 {
+			continue
+		}
+
+		item, ok := findItem(existing, dto.ItemID)
+
+		if !ok || keep[item.ID] {
+			return nil, nil, &ErrItemID{ID: dto.ItemID}
+		}
+
+		keep[item.ID] = true
+		item.Quantity = dto.Quantity
+		kept = append(kept, item)
+	}
+
+	var removed []uint
+	for _, item := range existing {
+		if !keep[item.ID] {
+			removed = append(removed, item.ID)
+		}
+	}
+
+	return kept, removed, nil
+}
+
+// AdjustmentsFromDTOs builds a new list of order adjustments. Existing adjustments
+// that aren't referenced by id in dtos are revoked on behalf of provided user,
+// already revoked ones are kept as they are and can't be referenced anymore.
+// New adjustments are created on behalf of provided user.
+//
+// Returns resulting list and ids of adjustments that should be revoked.
+func AdjustmentsFromDTOs(existing []Adjustment, dtos []AdjustmentCreateDTO, by user.User) ([]Adjustment, []uint, error) {
+	var created []Adjustment
+	kept := make(map[uint]bool)
+
+	for _, dto := range dtos {
+		if dto.ID != 0 {
+			a, ok := findAdjustment(existing, dto.ID)
+
+			if !ok {
+				return nil, nil, &ErrAdjustmentID{ID: dto.ID}
+			}
+
+			kept[a.ID] = true
+			continue
+		}
+
+		a := Adjustment{
+			Kind:        dto.Kind,
+			Value:       dto.Value.WithDefaultCurrency(),
+			Reason:      strings.TrimSpace(dto.Reason),
+			CreatedByID: by.ID,
+		}
+
+		if err := a.Validate(); err != nil {
+			return nil, nil, err
+		}
+
+		created = append(created, a)
+	}
+
+	adjustments := make([]Adjustment, 0, len(existing)+len(created))
+	var revoked []uint
+	now := time.Now()
+
+	for _, a := range existing {
+		if !a.IsRevoked() && !kept[a.ID] {
+			a.RevokedAt = &now
+			a.RevokedByID = &by.ID
+			revoked = append(revoked, a.ID)
+		}
+
+		adjustments = append(adjustments, a)
+	}
+
+	return append(adjustments, created...), revoked, nil
+}
+
+func findItem(items []Item, id uint) (Item, bool) {
+	for _, item := range items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// findAdjustment looks for an adjustment with provided id that hasn't been revoked.
+func findAdjustment(adjustments []Adjustment, id uint) (Adjustment, bool) {
+	for _, a := range adjustments {
+		if a.ID == id && !a.IsRevoked() {
+			return a, true
+		}
+	}
+	return Adjustment{}, false
 }
@@ -0,0 +1,99 @@
+package order
+
+import (
+	"food_ordering_backend/common"
+	"food_ordering_backend/controllers/user"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestKeepItems(t *testing.T) {
+	existing := []Item{
+		{ID: 1, DishID: 3, Quantity: 2, UnitPrice: common.NewMoney(100), DishTitle: "Soup"},
+		{ID: 2, DishID: 4, Quantity: 1, UnitPrice: common.NewMoney(250), DishTitle: "Steak"},
+		{ID: 3, Quantity: 5, UnitPrice: common.NewMoney(50), DishTitle: "Deleted dish"},
+	}
+
+	t.Run("should keep snapshots of referenced items and change only quantities", func(t *testing.T) {
+		dtos := []ItemCreateDTO{{ItemID: 3, Quantity: 1}, {ID: 7, Quantity: 2}, {ItemID: 1, ID: 9, Quantity: 4}}
+
+		kept, removed, err := KeepItems(existing, dtos)
+
+		if assert.NoError(t, err) {
+			expected := []Item{existing[2], existing[0]}
+			expected[0].Quantity = 1
+			expected[1].Quantity = 4
+			assert.Equal(t, expected, kept)
+			assert.Equal(t, []uint{2}, removed)
+		}
+	})
+
+	t.Run("should remove every item if none is referenced", func(t *testing.T) {
+		kept, removed, err := KeepItems(existing, []ItemCreateDTO{{ID: 7, Quantity: 2}})
+
+		if assert.NoError(t, err) {
+			assert.Empty(t, kept)
+			assert.Equal(t, []uint{1, 2, 3}, removed)
+		}
+	})
+
+	t.Run("should return error if item doesn't exist or is repeated", func(t *testing.T) {
+		tests := [][]ItemCreateDTO{
+			{{ItemID: 4, Quantity: 1}},
+			{{ItemID: 1, Quantity: 1}, {ItemID: 1, Quantity: 2}},
+		}
+
+		for _, dtos := range tests {
+			_, _, err := KeepItems(existing, dtos)
+			var errItemID *ErrItemID
+			assert.ErrorAs(t, err, &errItemID)
+		}
+	})
+}
+
+func TestAdjustmentsFromDTOs(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Hour)
+	revokedByID := uint(3)
+	existing := []Adjustment{
+		{ID: 1, Kind: AdjustmentDiscount, Value: common.NewMoney(100), Reason: "Loyal customer"},
+		{ID: 2, Kind: AdjustmentSurcharge, Value: common.NewMoney(250), Reason: "Night delivery"},
+		{ID: 3, Kind: AdjustmentCorrection, Value: common.NewMoney(-30), Reason: "Rounding", RevokedAt: &revokedAt, RevokedByID: &revokedByID},
+	}
+	by := user.User{ID: 7}
+
+	t.Run("should revoke unlisted adjustments and create new ones", func(t *testing.T) {
+		it := assert.New(t)
+		dtos := []AdjustmentCreateDTO{
+			{ID: 2},
+			{Kind: AdjustmentSurcharge, Value: common.Money{Amount: 50}, Reason: " Big order "},
+		}
+
+		adjustments, revoked, err := AdjustmentsFromDTOs(existing, dtos, by)
+
+		if it.NoError(err) && it.Len(adjustments, 4) {
+			it.Equal([]uint{1}, revoked)
+
+			if it.NotNil(adjustments[0].RevokedAt) && it.NotNil(adjustments[0].RevokedByID) {
+				it.Equal(by.ID, *adjustments[0].RevokedByID)
+			}
+
+			it.Equal(existing[1], adjustments[1])
+			it.Equal(existing[2], adjustments[2], "expected revoked adjustment to be kept as it is")
+
+			it.Zero(adjustments[3].ID)
+			it.Equal(common.NewMoney(50), adjustments[3].Value)
+			it.Equal("Big order", adjustments[3].Reason)
+			it.Equal(by.ID, adjustments[3].CreatedByID)
+			it.False(adjustments[3].IsRevoked())
+		}
+	})
+
+	t.Run("should return error if adjustment doesn't exist or has been revoked", func(t *testing.T) {
+		for _, id := range []uint{3, 4} {
+			_, _, err := AdjustmentsFromDTOs(existing, []AdjustmentCreateDTO{{ID: id}}, by)
+			var errAdjustmentID *ErrAdjustmentID
+			assert.ErrorAs(t, err, &errAdjustmentID)
+		}
+	})
+}
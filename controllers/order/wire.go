@@ -3,12 +3,16 @@
 package order
 
 import (
+	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/user"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
 
+var ServiceSet = wire.NewSet(ProvideService, ProvideRepository, ProvideHub, common.ProvideTransactor, dish.ServiceSet, user.AddressServiceSet)
+
 func InitAPI(db *gorm.DB) *API {
-	wire.Build(ProvideAPI, ProvideService, ProvideRepository, dish.ServiceSet)
+	wire.Build(ProvideAPI, ServiceSet)
 	return nil
 }
@@ -6,7 +6,10 @@
 package order
 
 import (
+	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/user"
+	"github.com/google/wire"
 	"gorm.io/gorm"
 )
 
@@ -16,7 +19,15 @@ func InitAPI(db *gorm.DB) *API {
 	repository := ProvideRepository(db)
 	dishRepository := dish.ProvideRepository(db)
 	service := dish.ProvideService(dishRepository)
-	orderService := ProvideService(repository, service)
+	userRepository := user.ProvideRepository(db)
+	transactor := common.ProvideTransactor(db)
+	addressService := user.ProvideAddressService(userRepository, transactor)
+	hub := ProvideHub()
+	orderService := ProvideService(repository, service, addressService, hub, transactor)
 	api := ProvideAPI(orderService)
 	return api
 }
+
+// wire.go:
+
+var ServiceSet = wire.NewSet(ProvideService, ProvideRepository, ProvideHub, common.ProvideTransactor, dish.ServiceSet, user.AddressServiceSet)
@@ -0,0 +1,166 @@
+package user
+
+import (
+	"errors"
+	"food_ordering_backend/common"
+	"gorm.io/gorm"
+)
+
+// AddressService manages delivery addresses of users. Once a user has addresses,
+// exactly one of them is default.
+type AddressService struct {
+	repo       *Repository
+	transactor *common.Transactor
+}
+
+func ProvideAddressService(r *Repository, transactor *common.Transactor) *AddressService {
+	return &AddressService{r, transactor}
+}
+
+// FindAll returns addresses of the user, the default one first.
+func (s *AddressService) FindAll(u User) ([]Address, error) {
+	return s.repo.FindAddresses(u.ID)
+}
+
+// FindByID returns address of the user with provided id.
+// Returns ErrAddressID if the user doesn't have such address.
+func (s *AddressService) FindByID(u User, id uint) (Address, error) {
+	a, err := s.repo.FindAddress(u.ID, id)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Address{}, &ErrAddressID{ID: id}
+	}
+
+	return a, err
+}
+
+// FindDefault returns default address of the user.
+// Returns ErrNoDefaultAddress if the user doesn't have addresses.
+func (s *AddressService) FindDefault(u User) (Address, error) {
+	a, err := s.repo.FindDefaultAddress(u.ID)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Address{}, ErrNoDefaultAddress
+	}
+
+	return a, err
+}
+
+// Create adds a new address to the user. The first address of the user becomes
+// default. If the new address is default, the previous default one stops being so.
+func (s *AddressService) Create(u User, dto AddressDTO) (Address, error) {
+	a := AddressFromDTO(dto)
+	a.UserID = u.ID
+
+	if err := a.Validate(); err != nil {
+		return Address{}, err
+	}
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.LockUser(u.ID); err != nil {
+			return err
+		}
+
+		count, err := repo.CountAddresses(u.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			a.IsDefault = true
+		} else if a.IsDefault {
+			if err := repo.UnsetDefaultAddress(u.ID, 0); err != nil {
+				return err
+			}
+		}
+
+		a, err = repo.CreateAddress(a)
+		return err
+	})
+
+	return a, err
+}
+
+// Update replaces address of the user with provided id. The default address
+// stays default even if dto says otherwise, another address should be made
+// default instead. Returns ErrAddressID if the user doesn't have such address.
+func (s *AddressService) Update(u User, id uint, dto AddressDTO) (Address, error) {
+	updated := AddressFromDTO(dto)
+
+	if err := updated.Validate(); err != nil {
+		return Address{}, err
+	}
+
+	var a Address
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		var err error
+		repo := s.repo.WithTx(tx)
+
+		if err = repo.LockUser(u.ID); err != nil {
+			return err
+		}
+
+		if a, err = repo.FindAddress(u.ID, id); err != nil {
+			return err
+		}
+
+		updated.ID = a.ID
+		updated.UserID = a.UserID
+		updated.CreatedAt = a.CreatedAt
+		updated.IsDefault = a.IsDefault || dto.IsDefault
+
+		if updated.IsDefault && !a.IsDefault {
+			if err = repo.UnsetDefaultAddress(u.ID, a.ID); err != nil {
+				return err
+			}
+		}
+
+		a, err = repo.SaveAddress(updated)
+		return err
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Address{}, &ErrAddressID{ID: id}
+	}
+
+	return a, err
+}
+
+// Delete deletes address of the user with provided id. If it was default,
+// the oldest of remaining addresses becomes default. Orders keep their copy
+// of the address. Returns ErrAddressID if the user doesn't have such address.
+func (s *AddressService) Delete(u User, id uint) error {
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.LockUser(u.ID); err != nil {
+			return err
+		}
+
+		a, err := repo.FindAddress(u.ID, id)
+
+		if err != nil {
+			return err
+		}
+
+		if err := repo.DeleteAddress(a); err != nil {
+			return err
+		}
+
+		if !a.IsDefault {
+			return nil
+		}
+
+		return repo.PromoteOldestAddress(u.ID)
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &ErrAddressID{ID: id}
+	}
+
+	return err
+}
@@ -0,0 +1,57 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Address is a delivery address saved by a user. A user can have at most one
+// default address, it's used for orders that don't specify an address.
+type Address struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint `gorm:"not null;index;uniqueIndex:idx_user_addresses_default,where:is_default"`
+	// Label is a name of the address that is shown to the user, e.g. "Home" or "Work".
+	Label string `gorm:"size:50;not null;default:''"`
+	// Recipient and Phone override name and phone from user's profile.
+	Recipient  string `gorm:"size:100;not null;default:''"`
+	Phone      string `gorm:"size:20;not null;default:''"`
+	Line1      string `gorm:"size:255;not null"`
+	Line2      string `gorm:"size:255;not null;default:''"`
+	City       string `gorm:"size:100;not null"`
+	PostalCode string `gorm:"size:20;not null;default:''"`
+	// Comment is an instruction for the courier, e.g. a door code.
+	Comment   string `gorm:"size:255;not null;default:''"`
+	IsDefault bool   `gorm:"not null;default:false"`
+	User      User   `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// ErrAddressID is returned when the user doesn't have an address with provided id.
+type ErrAddressID struct {
+	ID uint
+}
+
+func (e *ErrAddressID) Error() string {
+	return fmt.Sprintf("Address with id %d doesn't exist", e.ID)
+}
+
+var ErrNoDefaultAddress = errors.New("user doesn't have a default address")
+var ErrInvalidAddress = errors.New("invalid address")
+
+func (a Address) TableName() string {
+	return "user_addresses"
+}
+
+// Validate checks that required fields of the address aren't blank.
+func (a Address) Validate() error {
+	switch {
+	case a.Line1 == "":
+		return fmt.Errorf("%w: line1 is required", ErrInvalidAddress)
+	case a.City == "":
+		return fmt.Errorf("%w: city is required", ErrInvalidAddress)
+	}
+
+	return nil
+}
@@ -4,30 +4,76 @@ import (
 	"errors"
 	"food_ordering_backend/common"
 	"food_ordering_backend/config"
+	"food_ordering_backend/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const SessionCookieName = "access_token"
+const RefreshCookieName = "refresh_token"
+
+// RefreshCookiePath limits refresh cookie to the refresh route,
+// so that it isn't sent with other requests.
+const RefreshCookiePath = "/users/refresh"
+
 const ContextUserKey = "user"
+const ContextSessionKey = "session"
+const ContextTokenKey = "token"
+
+// maxUserAgentLength matches the size of Session.UserAgent column.
+const maxUserAgentLength = 512
 
 type API struct {
-	service *Service
+	service   *Service
+	addresses *AddressService
+	// ipLimiter limits requests that sign in, sign up or send emails by IP.
+	ipLimiter *services.Limiter
+	// emailLimiter limits sign in and password reset requests by email.
+	emailLimiter *services.Limiter
 }
 
-func ProvideAPI(s *Service) *API {
-	return &API{s}
+func ProvideAPI(s *Service, addresses *AddressService, store services.LimiterStore) *API {
+	return &API{
+		service:      s,
+		addresses:    addresses,
+		ipLimiter:    services.NewLimiter(store, "auth-ip", config.AuthRateLimit),
+		emailLimiter: services.NewLimiter(store, "auth-email", config.AuthEmailRateLimit),
+	}
 }
 
 func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 	auth := InitAuthMiddleware(db)
+	limitIP := api.ipLimiter.Middleware(services.KeyByIP)
 
-	router.GET("", auth(true), api.FindAll)
-	router.GET("/me", auth(false), api.Info)
-	router.GET("/logout", auth(false), api.Logout)
-	router.POST("/signup", api.Create)
-	router.POST("/signin", api.Login)
+	router.GET("", auth(PermUsersRead), api.FindAll)
+	router.GET("/roles", auth(PermUsersManage), api.FindRoles)
+	router.GET("/:id", auth(PermUsersRead), api.FindByID)
+	router.PATCH("/:id", auth(PermUsersManage), api.Patch)
+	router.DELETE("/:id", auth(PermUsersManage), api.Delete)
+	router.PUT("/:id/roles", auth(PermUsersManage), api.SetRoles)
+	router.GET("/me", auth(), api.Info)
+	router.PUT("/me", auth(), api.UpdateProfile)
+	router.GET("/me/addresses", auth(), api.FindAddresses)
+	router.POST("/me/addresses", auth(), api.CreateAddress)
+	router.PUT("/me/addresses/:id", auth(), api.UpdateAddress)
+	router.DELETE("/me/addresses/:id", auth(), api.DeleteAddress)
+	router.GET("/me/sessions", auth(), api.FindSessions)
+	router.DELETE("/me/sessions", auth(), api.DeleteAllSessions)
+	router.DELETE("/me/sessions/:id", auth(), api.DeleteSession)
+	router.PUT("/me/password", auth(), api.ChangePassword)
+	router.GET("/logout", auth(), api.Logout)
+	router.POST("/signup", limitIP, api.Create)
+	router.POST("/signin", limitIP, api.Login)
+	router.POST("/token", limitIP, api.Token)
+	router.POST("/refresh", api.Refresh)
+	router.POST("/password/forgot", limitIP, api.ForgotPassword)
+	router.POST("/password/reset", api.ResetPassword)
+	router.GET("/verify", api.VerifyEmail)
+	router.POST("/me/verify", limitIP, auth(), api.SendEmailVerification)
 }
 
 // Create godoc
@@ -38,7 +84,7 @@ func (api *API) Register(router *gin.RouterGroup, db *gorm.DB) {
 // @Param auth body AuthDTO true "User info"
 // @Produce json
 // @Success 201 {object} ResponseDTO
-// @Failure 409,422
+// @Failure 409,422,429
 // @Router /users/signup [post]
 func (api *API) Create(c *gin.Context) {
 	dto, err := api.bindAuthDTO(c)
@@ -71,28 +117,144 @@ func (api *API) Create(c *gin.Context) {
 }
 
 // FindAll godoc
-// @Summary Get all users. Requires admin rights.
+// @Summary Get all users. Requires users:read permission.
 // @ID user-get-all
 // @Tags user
 // @Param page query integer false "0-based page number"
 // @Param limit query integer false "amount of entries per page"
+// @Param email query string false "part of user's email"
+// @Param sort query string false "sort field" Enums(id, email, created_at)
+// @Param order query string false "sort direction" Enums(asc, desc)
 // @Produce json
 // @Success 200 {object} DTOsWithPagination
-// @Failure 401
+// @Failure 400,401,500
+// @Security BearerAuth
 // @Router /users [get]
 func (api *API) FindAll(c *gin.Context) {
 	p := common.ExtractPagination(c, 10)
-	users := api.service.FindAll(p)
+	f, err := FilterFromQuery(c.Request.URL.Query())
+
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := api.service.FindAll(f, p)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
 	c.JSON(http.StatusOK, DTOsWithPagination{
 		Users: ToResponseDTOs(users),
 		Pagination: common.PaginationDTO{
 			Page:  p.Page(),
 			Limit: p.Limit(),
-			Total: api.service.CountAll(),
+			Total: api.service.CountAll(f),
 		},
 	})
 }
 
+// FindByID godoc
+// @Summary Get user by id. Requires users:read permission.
+// @ID user-get-by-id
+// @Tags user
+// @Param id path integer true "User ID"
+// @Produce json
+// @Success 200 {object} ResponseDTO
+// @Failure 400,401,404,500
+// @Security BearerAuth
+// @Router /users/{id} [get]
+func (api *API) FindByID(c *gin.Context) {
+	u, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponseDTO(u))
+}
+
+// Patch godoc
+// @Summary Grant or revoke admin role and disable or enable user. Requires users:manage permission.
+// @Description Disabled users can't sign in and are logged out everywhere. Admins can't disable themselves.
+// @Description The last enabled admin can't lose admin role or be disabled.
+// @ID user-patch
+// @Tags user
+// @Accept json
+// @Param id path integer true "User ID"
+// @Param user body PatchDTO true "Fields to change"
+// @Produce json
+// @Success 200 {object} ResponseDTO
+// @Failure 400,401,404,409,422,500
+// @Security BearerAuth
+// @Router /users/{id} [patch]
+func (api *API) Patch(c *gin.Context) {
+	var dto PatchDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	u, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	admin := c.MustGet(ContextUserKey).(User)
+	u, err = api.service.Patch(u, dto, admin)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrLastAdmin) || errors.Is(err, ErrOwnAccount):
+			c.String(http.StatusConflict, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponseDTO(u))
+}
+
+// Delete godoc
+// @Summary Delete user by id. Requires users:manage permission.
+// @Description Orders of the user are kept without a customer. Admins can't delete themselves
+// @Description and the last enabled admin can't be deleted.
+// @ID user-delete
+// @Tags user
+// @Param id path integer true "User ID"
+// @Success 204
+// @Failure 400,401,404,409,500
+// @Security BearerAuth
+// @Router /users/{id} [delete]
+func (api *API) Delete(c *gin.Context) {
+	u, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	admin := c.MustGet(ContextUserKey).(User)
+
+	if err := api.service.Delete(u, admin); err != nil {
+		switch {
+		case errors.Is(err, ErrLastAdmin) || errors.Is(err, ErrOwnAccount):
+			c.String(http.StatusConflict, err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.Status(http.StatusNotFound)
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // Login godoc
 // @Summary Sign in
 // @ID user-login
@@ -100,7 +262,7 @@ func (api *API) FindAll(c *gin.Context) {
 // @Accept json
 // @Param auth body AuthDTO true "User login data"
 // @Success 200 {object} ResponseDTO
-// @Failure 404,500
+// @Failure 403,404,429,500
 // @Router /users/signin [post]
 func (api *API) Login(c *gin.Context) {
 	dto, err := api.bindAuthDTO(c)
@@ -118,40 +280,588 @@ func (api *API) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, ToResponseDTO(session.User))
 }
 
+// Token godoc
+// @Summary Get access token
+// @Description Creates a session like /users/signin does, but returns its tokens in the body instead of setting cookies.
+// @Description Access token should be sent in "Authorization: Bearer <token>" header. Once it expires,
+// @Description a new one can be obtained from /users/refresh with refresh token.
+// @ID user-token
+// @Tags user
+// @Accept json
+// @Param auth body AuthDTO true "User login data"
+// @Produce json
+// @Success 200 {object} TokenDTO
+// @Failure 403,404,422,429,500
+// @Router /users/token [post]
+func (api *API) Token(c *gin.Context) {
+	dto, err := api.bindAuthDTO(c)
+
+	if err != nil {
+		return
+	}
+
+	session, err := api.login(c, dto)
+
+	if err != nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, ToTokenDTO(session))
+}
+
+// Refresh godoc
+// @Summary Refresh access token
+// @Description Issues new access and refresh tokens for the session. Refresh token is taken from the body or,
+// @Description if the body is empty, from refresh cookie set by /users/signin. In the latter case new tokens
+// @Description are set as cookies too and user info is returned instead of tokens.
+// @Description Every refresh token can be used only once. Using it again ends the session.
+// @ID user-refresh
+// @Tags user
+// @Accept json
+// @Param refresh body RefreshDTO false "Refresh token"
+// @Produce json
+// @Success 200 {object} TokenDTO
+// @Failure 401,422,500
+// @Router /users/refresh [post]
+func (api *API) Refresh(c *gin.Context) {
+	var dto RefreshDTO
+	fromCookie := c.Request.ContentLength == 0
+
+	if fromCookie {
+		cookie, err := c.Request.Cookie(RefreshCookieName)
+
+		if err != nil || cookie.Value == "" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+
+		dto.RefreshToken = cookie.Value
+	} else if err := c.ShouldBindJSON(&dto); err != nil {
+		c.Status(http.StatusUnprocessableEntity)
+		return
+	}
+
+	session, err := api.service.Refresh(dto.RefreshToken, clientFromContext(c))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrUserDisabled):
+			if fromCookie {
+				clearSessionCookies(c)
+			}
+			c.String(http.StatusUnauthorized, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if fromCookie {
+		setSessionCookies(c, session)
+		c.JSON(http.StatusOK, ToResponseDTO(session.User))
+		return
+	}
+
+	c.JSON(http.StatusOK, ToTokenDTO(session))
+}
+
 // Logout godoc
 // @Summary Logout. Requires auth.
 // @ID user-logout
 // @Tags user
 // @Success 200
 // @Failure 401,500
+// @Security BearerAuth
 // @Router /users/logout [get]
 func (api *API) Logout(c *gin.Context) {
-	// Skipping error because cookie was already checked in auth middleware.
-	cookie, _ := c.Request.Cookie(SessionCookieName)
-
-	err := api.service.Logout(cookie.Value)
+	err := api.service.Logout(c.GetString(ContextTokenKey))
 
 	if err != nil {
 		c.Status(http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(c.Writer, SessionCookie("", -1))
+	clearSessionCookies(c)
 	c.Status(http.StatusOK)
 }
 
+// FindSessions godoc
+// @Summary Get active sessions of current user. Requires auth.
+// @ID user-sessions
+// @Tags user
+// @Produce json
+// @Success 200 {array} SessionDTO
+// @Failure 401,500
+// @Security BearerAuth
+// @Router /users/me/sessions [get]
+func (api *API) FindSessions(c *gin.Context) {
+	u := c.MustGet(ContextUserKey).(User)
+	current := c.MustGet(ContextSessionKey).(Session)
+	sessions, err := api.service.FindSessions(u)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToSessionDTOs(sessions, current.ID))
+}
+
+// DeleteSession godoc
+// @Summary Log out a session of current user. Requires auth.
+// @ID user-session-delete
+// @Tags user
+// @Param id path integer true "Session ID"
+// @Success 204
+// @Failure 400,401,404,500
+// @Security BearerAuth
+// @Router /users/me/sessions/{id} [delete]
+func (api *API) DeleteSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	u := c.MustGet(ContextUserKey).(User)
+	current := c.MustGet(ContextSessionKey).(Session)
+
+	if err := api.service.DeleteSession(u, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.Status(http.StatusNotFound)
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if current.ID == uint(id) {
+		clearSessionCookies(c)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteAllSessions godoc
+// @Summary Log out everywhere. Deletes all sessions of current user, including the current one. Requires auth.
+// @ID user-session-delete-all
+// @Tags user
+// @Success 204
+// @Failure 401,500
+// @Security BearerAuth
+// @Router /users/me/sessions [delete]
+func (api *API) DeleteAllSessions(c *gin.Context) {
+	u := c.MustGet(ContextUserKey).(User)
+
+	if err := api.service.LogoutEverywhere(u); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	clearSessionCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
 // Info godoc
 // @Summary Get info about current user. Requires auth.
 // @ID user-info
 // @Tags user
 // @Success 200 {object} ResponseDTO
 // @Failure 401
+// @Security BearerAuth
 // @Router /users/me [get]
 func (api *API) Info(c *gin.Context) {
 	user := c.MustGet(ContextUserKey).(User)
 	c.JSON(http.StatusOK, ToResponseDTO(user))
 }
 
+// UpdateProfile godoc
+// @Summary Replace name and phone of current user. Requires auth.
+// @ID user-update-profile
+// @Tags user
+// @Accept json
+// @Param profile body ProfileDTO true "Profile of the user"
+// @Produce json
+// @Success 200 {object} ResponseDTO
+// @Failure 401,422,500
+// @Security BearerAuth
+// @Router /users/me [put]
+func (api *API) UpdateProfile(c *gin.Context) {
+	var dto ProfileDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	u, err := api.service.UpdateProfile(c.MustGet(ContextUserKey).(User), dto)
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponseDTO(u))
+}
+
+// FindAddresses godoc
+// @Summary Get delivery addresses of current user, the default one first. Requires auth.
+// @ID user-addresses-get-all
+// @Tags user
+// @Produce json
+// @Success 200 {array} AddressResponseDTO
+// @Failure 401,500
+// @Security BearerAuth
+// @Router /users/me/addresses [get]
+func (api *API) FindAddresses(c *gin.Context) {
+	addresses, err := api.addresses.FindAll(c.MustGet(ContextUserKey).(User))
+
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ToAddressResponseDTOs(addresses))
+}
+
+// CreateAddress godoc
+// @Summary Add a delivery address to current user. Requires auth.
+// @Description The first address becomes default. If the new address is default, the previous default one stops being so.
+// @ID user-addresses-create
+// @Tags user
+// @Accept json
+// @Param address body AddressDTO true "Address"
+// @Produce json
+// @Success 201 {object} AddressResponseDTO
+// @Failure 401,422,500
+// @Security BearerAuth
+// @Router /users/me/addresses [post]
+func (api *API) CreateAddress(c *gin.Context) {
+	var dto AddressDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	a, err := api.addresses.Create(c.MustGet(ContextUserKey).(User), dto)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidAddress):
+			c.String(http.StatusUnprocessableEntity, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, ToAddressResponseDTO(a))
+}
+
+// UpdateAddress godoc
+// @Summary Replace a delivery address of current user. Requires auth.
+// @Description The default address stays default even if is_default is false, another address should be made default instead.
+// @ID user-addresses-update
+// @Tags user
+// @Accept json
+// @Param id path integer true "Address ID"
+// @Param address body AddressDTO true "Address"
+// @Produce json
+// @Success 200 {object} AddressResponseDTO
+// @Failure 400,401,404,422,500
+// @Security BearerAuth
+// @Router /users/me/addresses/{id} [put]
+func (api *API) UpdateAddress(c *gin.Context) {
+	var dto AddressDTO
+
+	id, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	a, err := api.addresses.Update(c.MustGet(ContextUserKey).(User), uint(id), dto)
+
+	if err != nil {
+		var errAddressID *ErrAddressID
+
+		switch {
+		case errors.As(err, &errAddressID):
+			c.String(http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrInvalidAddress):
+			c.String(http.StatusUnprocessableEntity, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ToAddressResponseDTO(a))
+}
+
+// DeleteAddress godoc
+// @Summary Delete a delivery address of current user. Requires auth.
+// @Description If the address was default, the oldest of remaining addresses becomes default.
+// @Description Orders keep their copy of the address.
+// @ID user-addresses-delete
+// @Tags user
+// @Param id path integer true "Address ID"
+// @Success 204
+// @Failure 400,401,404,500
+// @Security BearerAuth
+// @Router /users/me/addresses/{id} [delete]
+func (api *API) DeleteAddress(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if err := api.addresses.Delete(c.MustGet(ContextUserKey).(User), uint(id)); err != nil {
+		var errAddressID *ErrAddressID
+
+		switch {
+		case errors.As(err, &errAddressID):
+			c.String(http.StatusNotFound, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// FindRoles godoc
+// @Summary Get all roles with permissions they grant. Requires users:manage permission.
+// @ID user-roles-get-all
+// @Tags user
+// @Produce json
+// @Success 200 {array} RoleDTO
+// @Failure 401
+// @Security BearerAuth
+// @Router /users/roles [get]
+func (api *API) FindRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, ToRoleDTOs())
+}
+
+// SetRoles godoc
+// @Summary Replace roles of a user. Requires users:manage permission.
+// @Description Admin role can't be removed from the last admin.
+// @ID user-roles-set
+// @Tags user
+// @Accept json
+// @Param id path integer true "User ID"
+// @Param roles body RolesDTO true "New roles of the user"
+// @Produce json
+// @Success 200 {object} ResponseDTO
+// @Failure 400,401,404,409,422,500
+// @Security BearerAuth
+// @Router /users/{id}/roles [put]
+func (api *API) SetRoles(c *gin.Context) {
+	var dto RolesDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	u, err := api.findByID(c)
+
+	if err != nil {
+		return
+	}
+
+	u, err = api.service.SetRoles(u, dto.Roles)
+
+	if err != nil {
+		var unknown *ErrUnknownRole
+
+		switch {
+		case errors.As(err, &unknown):
+			c.String(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, ErrLastAdmin):
+			c.String(http.StatusConflict, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponseDTO(u))
+}
+
+// ChangePassword godoc
+// @Summary Change password of current user. Requires auth.
+// @Description All other sessions of the user are logged out.
+// @ID user-change-password
+// @Tags user
+// @Accept json
+// @Param password body ChangePasswordDTO true "Current and new passwords"
+// @Success 204
+// @Failure 401,403,422,500
+// @Security BearerAuth
+// @Router /users/me/password [put]
+func (api *API) ChangePassword(c *gin.Context) {
+	var dto ChangePasswordDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	session := c.MustGet(ContextSessionKey).(Session)
+
+	if err := api.service.ChangePassword(session, dto); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidPassword):
+			c.String(http.StatusForbidden, "Current password is incorrect")
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Sends an email with a password reset link to the user. The link contains a single-use token
+// @Description that should be sent to /users/password/reset. Responds with 202 even if there is no user
+// @Description with provided email.
+// @ID user-forgot-password
+// @Tags user
+// @Accept json
+// @Param email body ForgotPasswordDTO true "User email"
+// @Success 202
+// @Failure 422,429,500
+// @Router /users/password/forgot [post]
+func (api *API) ForgotPassword(c *gin.Context) {
+	var dto ForgotPasswordDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if ok, wait := api.emailLimiter.Allow(strings.ToLower(dto.Email)); !ok {
+		services.AbortWithRetryAfter(c, wait)
+		return
+	}
+
+	if err := api.service.ForgotPassword(dto.Email); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Sets a new password using a token from password reset email. All sessions of the user are logged out.
+// @ID user-reset-password
+// @Tags user
+// @Accept json
+// @Param reset body ResetPasswordDTO true "Reset token and new password"
+// @Success 204
+// @Failure 400,422,500
+// @Router /users/password/reset [post]
+func (api *API) ResetPassword(c *gin.Context) {
+	var dto ResetPasswordDTO
+
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if err := api.service.ResetPassword(dto); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidResetToken):
+			c.String(http.StatusBadRequest, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Confirms that the user owns their email using a token from the link sent on sign up.
+// @ID user-verify-email
+// @Tags user
+// @Param token query string true "Email verification token"
+// @Produce json
+// @Success 200 {object} ResponseDTO
+// @Failure 400,500
+// @Router /users/verify [get]
+func (api *API) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+
+	if token == "" {
+		c.String(http.StatusBadRequest, ErrInvalidVerificationToken.Error())
+		return
+	}
+
+	u, err := api.service.VerifyEmail(token)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidVerificationToken):
+			c.String(http.StatusBadRequest, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponseDTO(u))
+}
+
+// SendEmailVerification godoc
+// @Summary Send a new email verification link to current user. Requires auth.
+// @Description Links sent before stop working.
+// @ID user-send-email-verification
+// @Tags user
+// @Success 202
+// @Failure 401,409,429,500
+// @Security BearerAuth
+// @Router /users/me/verify [post]
+func (api *API) SendEmailVerification(c *gin.Context) {
+	u := c.MustGet(ContextUserKey).(User)
+
+	if ok, wait := api.emailLimiter.Allow(strings.ToLower(u.Email)); !ok {
+		services.AbortWithRetryAfter(c, wait)
+		return
+	}
+
+	if err := api.service.SendEmailVerification(u); err != nil {
+		switch {
+		case errors.Is(err, ErrEmailAlreadyVerified):
+			c.String(http.StatusConflict, err.Error())
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
 func (api *API) bindAuthDTO(c *gin.Context) (AuthDTO, error) {
 	var dto AuthDTO
 	if err := c.ShouldBindJSON(&dto); err != nil {
@@ -161,25 +871,90 @@ func (api *API) bindAuthDTO(c *gin.Context) (AuthDTO, error) {
 	return dto, nil
 }
 
+// authorize creates a new session and sets session cookies.
 func (api *API) authorize(c *gin.Context, dto AuthDTO) (Session, error) {
-	session, err := api.service.Login(dto)
+	session, err := api.login(c, dto)
 
 	if err != nil {
+		return Session{}, err
+	}
+
+	setSessionCookies(c, session)
+	return session, nil
+}
+
+// login creates a new session. If login fails, it responds with an appropriate status.
+func (api *API) login(c *gin.Context, dto AuthDTO) (Session, error) {
+	if ok, wait := api.emailLimiter.Allow(strings.ToLower(dto.Email)); !ok {
+		services.AbortWithRetryAfter(c, wait)
+		return Session{}, errors.New(http.StatusText(http.StatusTooManyRequests))
+	}
+
+	session, err := api.service.Login(dto, clientFromContext(c))
+
+	if err != nil {
+		var errLocked *ErrLoginLocked
+
 		switch {
 		case errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrInvalidPassword):
 			c.Status(http.StatusNotFound)
+		case errors.As(err, &errLocked):
+			services.AbortWithRetryAfter(c, errLocked.RetryAfter)
+		case errors.Is(err, ErrUserDisabled):
+			c.String(http.StatusForbidden, err.Error())
 		default:
 			c.Status(http.StatusInternalServerError)
 		}
 		return Session{}, err
 	}
 
-	cookie := SessionCookie(session.Token, 0)
-	http.SetCookie(c.Writer, cookie)
-
 	return session, nil
 }
 
+func (api *API) findByID(c *gin.Context) (User, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return User{}, err
+	}
+
+	u, err := api.service.FindByID(uint(id))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.Status(http.StatusNotFound)
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+func setSessionCookies(c *gin.Context, s Session) {
+	http.SetCookie(c.Writer, SessionCookie(s.Token, 0))
+	http.SetCookie(c.Writer, RefreshCookie(s.RefreshToken, int(time.Until(s.ExpiresAt).Seconds())))
+}
+
+func clearSessionCookies(c *gin.Context) {
+	http.SetCookie(c.Writer, SessionCookie("", -1))
+	http.SetCookie(c.Writer, RefreshCookie("", -1))
+}
+
+// clientFromContext describes the client that made the request.
+func clientFromContext(c *gin.Context) Client {
+	userAgent := c.Request.UserAgent()
+
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	return Client{UserAgent: userAgent, IP: c.ClientIP()}
+}
+
 func SessionCookie(token string, maxAge int) *http.Cookie {
 	return &http.Cookie{
 		Name:     SessionCookieName,
@@ -192,3 +967,10 @@ func SessionCookie(token string, maxAge int) *http.Cookie {
 		MaxAge:   maxAge,
 	}
 }
+
+func RefreshCookie(token string, maxAge int) *http.Cookie {
+	cookie := SessionCookie(token, maxAge)
+	cookie.Name = RefreshCookieName
+	cookie.Path = RefreshCookiePath
+	return cookie
+}
@@ -10,11 +10,108 @@ type AuthDTO struct {
 	Password string `json:"password" binding:"required,min=8,max=255"`
 }
 
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=255"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=255"`
+}
+
 type ResponseDTO struct {
 	ID        uint      `json:"id"`
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
-	IsAdmin   bool      `json:"is_admin"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	// IsAdmin is kept for clients that don't know about roles yet.
+	IsAdmin     bool         `json:"is_admin"`
+	Roles       []Role       `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+	Disabled    bool         `json:"disabled"`
+	// EmailVerified is false until the user follows the link sent on sign up.
+	EmailVerified bool `json:"email_verified"`
+}
+
+// PatchDTO changes the user on behalf of an admin. Omitted fields are left unchanged.
+type PatchDTO struct {
+	// IsAdmin grants or revokes admin role.
+	IsAdmin  *bool `json:"is_admin"`
+	Disabled *bool `json:"disabled"`
+}
+
+// ProfileDTO replaces profile of current user.
+type ProfileDTO struct {
+	Name string `json:"name" binding:"max=100"`
+	// Phone must be in E.164 format, e.g. +14155552671.
+	Phone string `json:"phone" binding:"omitempty,e164"`
+}
+
+// AddressDTO creates or replaces a delivery address. The first address
+// of a user becomes default regardless of IsDefault.
+type AddressDTO struct {
+	Label      string `json:"label" binding:"max=50"`
+	Recipient  string `json:"recipient" binding:"max=100"`
+	Phone      string `json:"phone" binding:"omitempty,e164"`
+	Line1      string `json:"line1" binding:"required,max=255"`
+	Line2      string `json:"line2" binding:"max=255"`
+	City       string `json:"city" binding:"required,max=100"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
+	Comment    string `json:"comment" binding:"max=255"`
+	IsDefault  bool   `json:"is_default"`
+}
+
+type AddressResponseDTO struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Label      string    `json:"label"`
+	Recipient  string    `json:"recipient"`
+	Phone      string    `json:"phone"`
+	Line1      string    `json:"line1"`
+	Line2      string    `json:"line2"`
+	City       string    `json:"city"`
+	PostalCode string    `json:"postal_code"`
+	Comment    string    `json:"comment"`
+	IsDefault  bool      `json:"is_default"`
+}
+
+type RoleDTO struct {
+	Name        Role         `json:"name"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type RolesDTO struct {
+	Roles []Role `json:"roles" binding:"required"`
+}
+
+type TokenDTO struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type RefreshDTO struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type SessionDTO struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is true for the session that made the request.
+	Current bool `json:"current"`
 }
 
 type DTOsWithPagination struct {
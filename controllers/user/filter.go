@@ -0,0 +1,83 @@
+package user
+
+import (
+	"fmt"
+	"food_ordering_backend/common"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+)
+
+// sortColumns maps values of "sort" query parameter to columns.
+var sortColumns = map[string]string{
+	"id":         "users.id",
+	"email":      "users.email",
+	"created_at": "users.created_at",
+}
+
+// Filter narrows down and sorts a list of users.
+// Zero values of the fields are ignored.
+type Filter struct {
+	// Email matches users whose email contains provided string.
+	Email string
+	// SortBy is one of the keys from sortColumns. Defaults to "id".
+	SortBy   string
+	SortDesc bool
+}
+
+// FilterFromQuery parses query parameters into Filter.
+//
+// Supported parameters: email, sort and order (asc or desc).
+func FilterFromQuery(q url.Values) (Filter, error) {
+	var f Filter
+
+	f.Email = strings.TrimSpace(q.Get("email"))
+
+	if f.SortBy = q.Get("sort"); f.SortBy != "" {
+		if _, ok := sortColumns[f.SortBy]; !ok {
+			return f, fmt.Errorf("can't sort by %q", f.SortBy)
+		}
+	}
+
+	switch strings.ToLower(q.Get("order")) {
+	case "", "asc":
+	case "desc":
+		f.SortDesc = true
+	default:
+		return f, fmt.Errorf("invalid order %q", q.Get("order"))
+	}
+
+	return f, nil
+}
+
+// Where applies filtering conditions to the query.
+func (f Filter) Where(db *gorm.DB) *gorm.DB {
+	if f.Email != "" {
+		db = db.Where(`users.email ILIKE ? ESCAPE '\'`, common.ContainsPattern(f.Email))
+	}
+
+	return db
+}
+
+// OrderBy applies sorting to the query. Users with equal values
+// of the sort column are sorted by id.
+func (f Filter) OrderBy(db *gorm.DB) *gorm.DB {
+	column, ok := sortColumns[f.SortBy]
+
+	if !ok {
+		column = sortColumns["id"]
+	}
+
+	direction := "ASC"
+	if f.SortDesc {
+		direction = "DESC"
+	}
+
+	db = db.Order(column + " " + direction)
+
+	if column != sortColumns["id"] {
+		db = db.Order("users.id ASC")
+	}
+
+	return db
+}
@@ -0,0 +1,37 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestFilterFromQuery(t *testing.T) {
+	t.Run("should parse all parameters", func(t *testing.T) {
+		it := assert.New(t)
+		q, _ := url.ParseQuery("email=%20john%20&sort=email&order=DESC")
+
+		f, err := FilterFromQuery(q)
+
+		if it.NoError(err) {
+			it.Equal(Filter{Email: "john", SortBy: "email", SortDesc: true}, f)
+		}
+	})
+
+	t.Run("should return empty filter if there are no parameters", func(t *testing.T) {
+		it := assert.New(t)
+		f, err := FilterFromQuery(url.Values{})
+
+		if it.NoError(err) {
+			it.Zero(f)
+		}
+	})
+
+	t.Run("should return error if parameters are invalid", func(t *testing.T) {
+		for _, query := range []string{"sort=password_hash", "order=up"} {
+			q, _ := url.ParseQuery(query)
+			_, err := FilterFromQuery(q)
+			assert.Error(t, err, query)
+		}
+	})
+}
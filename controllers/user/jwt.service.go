@@ -2,23 +2,48 @@ package user
 
 import (
 	"errors"
+	"food_ordering_backend/config"
 	"github.com/dgrijalva/jwt-go/v4"
 	"time"
 )
 
-const JWTSecret = "secret string"
-
 type AuthClaims struct {
-	IssuedAt int64 `json:"iat"`
-	UserID   uint  `json:"uid"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt *jwt.Time `json:"exp"`
+	UserID    uint      `json:"uid"`
 }
 
 var ErrInvalidUserID = errors.New("invalid user id")
+var ErrMissingExpiry = errors.New("token doesn't have expiration time")
+var ErrUnknownKeyID = errors.New("token is signed with unknown key")
 
-type JWTService struct{}
+// JWTService signs and verifies auth tokens. Tokens are signed with the active key
+// and have its id in "kid" header, so they stay valid while their key is in the
+// list of keys, even if another key has become active.
+type JWTService struct {
+	keys      map[string][]byte
+	activeKID string
+	expiry    time.Duration
+}
 
 func ProvideJWTService() *JWTService {
-	return &JWTService{}
+	return NewJWTService(config.JWTKeys, config.JWTActiveKeyID, config.JWTExpiry)
+}
+
+// NewJWTService creates JWTService that signs tokens with the key
+// identified by activeKID and verifies them with any of provided keys.
+func NewJWTService(keys map[string]string, activeKID string, expiry time.Duration) *JWTService {
+	s := &JWTService{
+		keys:      make(map[string][]byte, len(keys)),
+		activeKID: activeKID,
+		expiry:    expiry,
+	}
+
+	for kid, secret := range keys {
+		s.keys[kid] = []byte(secret)
+	}
+
+	return s
 }
 
 func (c AuthClaims) Valid(v *jwt.ValidationHelper) error {
@@ -26,29 +51,47 @@ func (c AuthClaims) Valid(v *jwt.ValidationHelper) error {
 		return ErrInvalidUserID
 	}
 
-	return nil
+	if c.ExpiresAt == nil {
+		return ErrMissingExpiry
+	}
+
+	return v.ValidateExpiresAt(c.ExpiresAt)
 }
 
-// Generate uses provided user id to create a token with AuthClaims and signs it with JWTSecret.
+// Generate uses provided user id to create a token with AuthClaims and signs it with the active key.
 //
-// Returns signed token.
-func (s *JWTService) Generate(uid uint) string {
+// Returns signed token and its expiration time.
+func (s *JWTService) Generate(uid uint) (string, time.Time) {
+	now := time.Now()
+	expiresAt := now.Add(s.expiry)
+
 	token := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
-		AuthClaims{UserID: uid, IssuedAt: time.Now().UnixNano()},
+		AuthClaims{UserID: uid, IssuedAt: now.UnixNano(), ExpiresAt: jwt.At(expiresAt)},
 	)
-	ss, _ := token.SignedString([]byte(JWTSecret))
-	return ss
+	token.Header["kid"] = s.activeKID
+
+	ss, _ := token.SignedString(s.keys[s.activeKID])
+	return ss, expiresAt
 }
 
-// Parse reads provided token string and parses it with AuthClaims and JWTSecret.
+// Parse reads provided token string and parses it with AuthClaims
+// and the key identified by token's "kid" header.
 func (s *JWTService) Parse(token string) (*jwt.Token, error) {
 	return jwt.ParseWithClaims(token, &AuthClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(JWTSecret), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys[kid]
+
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 }
 
 // AuthClaimsFromToken extracts AuthClaims from provided token.
+// Returns an error if token is invalid or has expired.
 func (s *JWTService) AuthClaimsFromToken(token string) (*AuthClaims, error) {
 	t, err := s.Parse(token)
 
@@ -8,17 +8,40 @@ import (
 	"time"
 )
 
+var testJWTKeys = map[string]string{
+	"current":  "current secret",
+	"previous": "previous secret",
+}
+
+func newTestJWTService() *JWTService {
+	return NewJWTService(testJWTKeys, "current", time.Hour)
+}
+
 func TestAuthClaims_Valid(t *testing.T) {
 	t.Run("should return error if UserID == 0", func(t *testing.T) {
 		it := assert.New(t)
-		claims := AuthClaims{UserID: 0}
+		claims := AuthClaims{UserID: 0, ExpiresAt: jwt.At(time.Now().Add(time.Hour))}
+
+		it.ErrorIs(claims.Valid(jwt.DefaultValidationHelper), ErrInvalidUserID)
+	})
+
+	t.Run("should return error if there is no expiration time", func(t *testing.T) {
+		it := assert.New(t)
+		claims := AuthClaims{UserID: 1}
+
+		it.ErrorIs(claims.Valid(jwt.DefaultValidationHelper), ErrMissingExpiry)
+	})
+
+	t.Run("should return error if token has expired", func(t *testing.T) {
+		it := assert.New(t)
+		claims := AuthClaims{UserID: 1, ExpiresAt: jwt.At(time.Now().Add(-time.Second))}
 
 		it.Error(claims.Valid(jwt.DefaultValidationHelper))
 	})
 }
 
 func TestJWTService_Generate(t *testing.T) {
-	service := &JWTService{}
+	service := newTestJWTService()
 	t.Run("should generate a valid token string with encoded AuthClaims", func(t *testing.T) {
 		it := assert.New(t)
 
@@ -26,14 +49,16 @@ func TestJWTService_Generate(t *testing.T) {
 			if uid == 0 {
 				uid++
 			}
-			ss := service.Generate(uid)
+			ss, expiresAt := service.Generate(uid)
 			it.NotZero(ss)
+			it.WithinDuration(time.Now().Add(time.Hour), expiresAt, time.Minute)
 
 			token, err := jwt.ParseWithClaims(ss, &AuthClaims{}, func(token *jwt.Token) (interface{}, error) {
-				return []byte(JWTSecret), nil
+				return []byte(testJWTKeys["current"]), nil
 			})
 
 			if it.NoError(err) {
+				it.Equal("current", token.Header["kid"])
 				validateToken(t, token, uid)
 			}
 		}
@@ -43,24 +68,24 @@ func TestJWTService_Generate(t *testing.T) {
 	t.Run("should generate new token everytime even for same id", func(t *testing.T) {
 		it := assert.New(t)
 
-		ss1 := service.Generate(5)
+		ss1, _ := service.Generate(5)
 
 		// Workaround for Windows clock that apparently doesn't support nanoseconds.
 		time.Sleep(time.Microsecond)
 
-		ss2 := service.Generate(5)
+		ss2, _ := service.Generate(5)
 
 		it.NotEqual(ss1, ss2)
 	})
 }
 
 func TestJWTService_Parse(t *testing.T) {
-	service := &JWTService{}
+	service := newTestJWTService()
 	t.Run("should parse the token", func(t *testing.T) {
 		it := assert.New(t)
 
 		for _, id := range generateUserIDs(100) {
-			ss := service.Generate(id)
+			ss, _ := service.Generate(id)
 			token, err := service.Parse(ss)
 
 			if it.NoError(err) {
@@ -69,17 +94,45 @@ func TestJWTService_Parse(t *testing.T) {
 		}
 	})
 
+	t.Run("should parse the token signed with a key that is no longer active", func(t *testing.T) {
+		it := assert.New(t)
+		ss, _ := NewJWTService(testJWTKeys, "previous", time.Hour).Generate(5)
+
+		token, err := service.Parse(ss)
+
+		if it.NoError(err) {
+			validateToken(t, token, 5)
+		}
+	})
+
+	t.Run("should return an error if token is signed with unknown key", func(t *testing.T) {
+		it := assert.New(t)
+		removed := map[string]string{"removed": "removed secret"}
+		ss, _ := NewJWTService(removed, "removed", time.Hour).Generate(5)
+
+		_, err := service.Parse(ss)
+		it.Error(err)
+	})
+
+	t.Run("should return an error if token has expired", func(t *testing.T) {
+		it := assert.New(t)
+		ss, _ := NewJWTService(testJWTKeys, "current", -time.Minute).Generate(5)
+
+		_, err := service.Parse(ss)
+		it.Error(err)
+	})
+
 	testValidationError(t)
 }
 
 func TestJWTService_AuthClaimsFromToken(t *testing.T) {
-	service := &JWTService{}
+	service := newTestJWTService()
 
 	t.Run("should extract AuthClaims from provided token", func(t *testing.T) {
 		it := assert.New(t)
 
 		for _, id := range generateUserIDs(100) {
-			ss := service.Generate(id)
+			ss, _ := service.Generate(id)
 			claims, err := service.AuthClaimsFromToken(ss)
 
 			if it.NoError(err) {
@@ -94,8 +147,10 @@ func TestJWTService_AuthClaimsFromToken(t *testing.T) {
 func testValidationError(t *testing.T) {
 	t.Run("should return an error if no AuthClaims in token", func(t *testing.T) {
 		it := assert.New(t)
-		service := &JWTService{}
-		ss, err := jwt.New(jwt.SigningMethodHS256).SignedString([]byte(JWTSecret))
+		service := newTestJWTService()
+		token := jwt.New(jwt.SigningMethodHS256)
+		token.Header["kid"] = "current"
+		ss, err := token.SignedString([]byte(testJWTKeys["current"]))
 
 		if it.NoError(err) {
 			_, err = service.Parse(ss)
@@ -112,6 +167,7 @@ func validateToken(t *testing.T, token *jwt.Token, uid uint) {
 		it.NoError(claims.Valid(jwt.DefaultValidationHelper))
 		it.Equal(uid, claims.UserID)
 		it.NotZero(claims.IssuedAt)
+		it.NotNil(claims.ExpiresAt)
 	}
 }
 
@@ -1,5 +1,10 @@
 package user
 
+import (
+	"sort"
+	"strings"
+)
+
 func CreateFromDTO(dto AuthDTO) User {
 	var user User
 	user.Email = dto.Email
@@ -9,10 +14,16 @@ func CreateFromDTO(dto AuthDTO) User {
 
 func ToResponseDTO(u User) ResponseDTO {
 	return ResponseDTO{
-		ID:        u.ID,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		IsAdmin:   u.IsAdmin,
+		ID:            u.ID,
+		Email:         u.Email,
+		CreatedAt:     u.CreatedAt,
+		Name:          u.Name,
+		Phone:         u.Phone,
+		IsAdmin:       u.HasRole(RoleAdmin),
+		Roles:         u.RoleNames(),
+		Permissions:   u.Permissions(),
+		Disabled:      u.Disabled,
+		EmailVerified: u.EmailVerified,
 	}
 }
 
@@ -25,3 +36,88 @@ func ToResponseDTOs(users []User) []ResponseDTO {
 
 	return dtos
 }
+
+func AddressFromDTO(dto AddressDTO) Address {
+	return Address{
+		Label:      strings.TrimSpace(dto.Label),
+		Recipient:  strings.TrimSpace(dto.Recipient),
+		Phone:      dto.Phone,
+		Line1:      strings.TrimSpace(dto.Line1),
+		Line2:      strings.TrimSpace(dto.Line2),
+		City:       strings.TrimSpace(dto.City),
+		PostalCode: strings.TrimSpace(dto.PostalCode),
+		Comment:    strings.TrimSpace(dto.Comment),
+		IsDefault:  dto.IsDefault,
+	}
+}
+
+func ToAddressResponseDTO(a Address) AddressResponseDTO {
+	return AddressResponseDTO{
+		ID:         a.ID,
+		CreatedAt:  a.CreatedAt,
+		UpdatedAt:  a.UpdatedAt,
+		Label:      a.Label,
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		PostalCode: a.PostalCode,
+		Comment:    a.Comment,
+		IsDefault:  a.IsDefault,
+	}
+}
+
+func ToAddressResponseDTOs(addresses []Address) []AddressResponseDTO {
+	dtos := make([]AddressResponseDTO, len(addresses))
+
+	for i, a := range addresses {
+		dtos[i] = ToAddressResponseDTO(a)
+	}
+
+	return dtos
+}
+
+// ToRoleDTOs maps all existing roles to DTOs sorted by name.
+func ToRoleDTOs() []RoleDTO {
+	dtos := make([]RoleDTO, 0, len(RolePermissions))
+
+	for role, perms := range RolePermissions {
+		dtos = append(dtos, RoleDTO{Name: role, Permissions: perms})
+	}
+
+	sort.Slice(dtos, func(i, j int) bool {
+		return dtos[i].Name < dtos[j].Name
+	})
+
+	return dtos
+}
+
+func ToTokenDTO(s Session) TokenDTO {
+	return TokenDTO{
+		AccessToken:           s.Token,
+		TokenType:             "Bearer",
+		ExpiresAt:             s.TokenExpiresAt,
+		RefreshToken:          s.RefreshToken,
+		RefreshTokenExpiresAt: s.ExpiresAt,
+	}
+}
+
+// ToSessionDTOs maps sessions to DTOs. currentID is the id of the session that made the request.
+func ToSessionDTOs(sessions []Session, currentID uint) []SessionDTO {
+	dtos := make([]SessionDTO, len(sessions))
+
+	for i, s := range sessions {
+		dtos[i] = SessionDTO{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == currentID,
+		}
+	}
+
+	return dtos
+}
@@ -1,46 +1,117 @@
 package user
 
 import (
+	"food_ordering_backend/config"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
+	"strings"
 )
 
-type AuthMiddlewareFunc func(isAdmin bool) gin.HandlerFunc
+// AuthMiddlewareFunc creates AuthMiddleware that requires provided permissions.
+type AuthMiddlewareFunc func(perms ...Permission) gin.HandlerFunc
 
 func ProvideAuthMiddleware(service *Service) AuthMiddlewareFunc {
-	return func(isAdmin bool) gin.HandlerFunc {
-		return AuthMiddleware(service, isAdmin)
+	return func(perms ...Permission) gin.HandlerFunc {
+		return AuthMiddleware(service, perms...)
 	}
 }
 
 // AuthMiddleware intercepts a request to check whether user is authorized
-// and has a valid session token. If any of checks fails request will be
-// automatically aborted with http.StatusUnauthorized.
+// and has a valid session token. Token is taken from "Authorization: Bearer <token>"
+// header or, if there is no such header, from session cookie.
+// If any of checks fails request will be automatically aborted with http.StatusUnauthorized.
 // If check is successful, middleware will set ContextUserKey with
-// authorized User in current gin.Context.
-// adminOnly flag indicates that a user must have admin rights to access the route.
-func AuthMiddleware(service *Service, adminOnly bool) gin.HandlerFunc {
+// authorized User, ContextSessionKey with its Session and ContextTokenKey
+// with the token in current gin.Context.
+// perms are permissions that user's roles must grant to access the route.
+func AuthMiddleware(service *Service, perms ...Permission) gin.HandlerFunc {
+	requirePerms := RequirePermission(perms...)
+
 	return func(c *gin.Context) {
-		cookie, err := c.Request.Cookie(SessionCookieName)
+		token, ok := TokenFromRequest(c.Request)
 
-		if err != nil {
+		if !ok {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		session, err := service.FindSessionByToken(cookie.Value)
+		session, err := service.FindSessionByToken(token)
 
 		if err != nil {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		if adminOnly && !session.User.IsAdmin {
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
+		if err := service.TouchSession(session); err != nil {
+			log.Println("[User] Error updating session usage time:", err)
 		}
 
 		c.Set(ContextUserKey, session.User)
+		c.Set(ContextSessionKey, session)
+		c.Set(ContextTokenKey, token)
+
+		if requirePerms(c); c.IsAborted() {
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// RequirePermission aborts the request with http.StatusUnauthorized unless the user
+// set by AuthMiddleware has all provided permissions. It allows to guard routes
+// of a group that is already guarded by AuthMiddleware.
+func RequirePermission(perms ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(ContextUserKey)
+		u, isUser := value.(User)
+
+		if !ok || !isUser {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		for _, p := range perms {
+			if !u.HasPermission(p) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+	}
+}
+
+// RequireVerifiedEmail aborts the request with http.StatusForbidden if config.RequireVerifiedEmail
+// is set and the user set by AuthMiddleware hasn't verified their email.
+func RequireVerifiedEmail(c *gin.Context) {
+	u := c.MustGet(ContextUserKey).(User)
+
+	if config.RequireVerifiedEmail && !u.EmailVerified {
+		c.String(http.StatusForbidden, "Email must be verified")
+		c.Abort()
+	}
+}
+
+// TokenFromRequest extracts session token from Authorization header with Bearer
+// scheme or from session cookie. Returns false if there is no token or
+// Authorization header has another scheme.
+func TokenFromRequest(req *http.Request) (string, bool) {
+	if header := req.Header.Get("Authorization"); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return "", false
+		}
+
+		token := strings.TrimSpace(parts[1])
+		return token, token != ""
+	}
+
+	cookie, err := req.Cookie(SessionCookieName)
+
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
@@ -0,0 +1,116 @@
+package user
+
+import (
+	"food_ordering_backend/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenFromRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		cookie        string
+		expected      string
+		expectedOk    bool
+	}{
+		{"should take token from Authorization header", "Bearer header-token", "", "header-token", true},
+		{"should accept scheme in any case", "bearer header-token", "", "header-token", true},
+		{"should prefer Authorization header to cookie", "Bearer header-token", "cookie-token", "header-token", true},
+		{"should take token from cookie", "", "cookie-token", "cookie-token", true},
+		{"should reject other schemes", "Basic dXNlcjpwYXNz", "cookie-token", "", false},
+		{"should reject empty bearer token", "Bearer ", "", "", false},
+		{"should reject request without token", "", "", "", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			it := assert.New(t)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: tc.cookie})
+			}
+
+			token, ok := TokenFromRequest(req)
+
+			it.Equal(tc.expectedOk, ok)
+			it.Equal(tc.expected, token)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	kitchen := User{ID: 1, Roles: []UserRole{{Role: RoleKitchen}}}
+	tests := []struct {
+		name     string
+		user     interface{}
+		perms    []Permission
+		expected int
+	}{
+		{"should pass user that has all permissions", kitchen, []Permission{PermOrdersReadAll, PermOrdersUpdateStatus}, http.StatusOK},
+		{"should pass any user if there are no permissions", User{ID: 1}, nil, http.StatusOK},
+		{"should reject user that lacks any of permissions", kitchen, []Permission{PermOrdersReadAll, PermMenuWrite}, http.StatusUnauthorized},
+		{"should reject request without user", nil, nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				if tc.user != nil {
+					c.Set(ContextUserKey, tc.user)
+				}
+			}, RequirePermission(tc.perms...), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		required bool
+		verified bool
+		expected int
+	}{
+		{"should pass user with verified email", true, true, http.StatusOK},
+		{"should reject user with unverified email", true, false, http.StatusForbidden},
+		{"should pass any user if verification isn't required", false, false, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			old := config.RequireVerifiedEmail
+			config.RequireVerifiedEmail = tc.required
+			t.Cleanup(func() {
+				config.RequireVerifiedEmail = old
+			})
+
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				c.Set(ContextUserKey, User{ID: 1, EmailVerified: tc.verified})
+			}, RequireVerifiedEmail, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
@@ -1,37 +1,120 @@
 package user
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
 type User struct {
 	ID           uint `gorm:"primaryKey"`
 	CreatedAt    time.Time
-	Email        string `gorm:"size:255;uniqueIndex;not null"`
-	PasswordHash []byte `gorm:"not null"`
-	IsAdmin      bool   `gorm:"default:false"`
+	Email        string     `gorm:"size:255;uniqueIndex;not null"`
+	PasswordHash []byte     `gorm:"not null"`
+	Roles        []UserRole `gorm:"foreignKey:UserID"`
+	Name         string     `gorm:"size:100;not null;default:''"`
+	// Phone is in E.164 format, e.g. +14155552671.
+	Phone string `gorm:"size:20;not null;default:''"`
+	// Disabled users can't sign in. They are logged out once they are disabled.
+	Disabled bool `gorm:"not null;default:false"`
+	// EmailVerified is set once the user follows the link sent to their email.
+	EmailVerified bool `gorm:"not null;default:false"`
 }
 
+// Session is a signed in device of a user. It's authorized by a short-lived access
+// token and can issue new access tokens using a refresh token until it expires.
+// Only a hash of the refresh token is stored, the token itself is set to
+// RefreshToken once it's issued and must be sent to the client.
 type Session struct {
-	Token     string `gorm:"primaryKey"`
-	UserID    uint
-	CreatedAt time.Time
-	User      User `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+	ID     uint `gorm:"primaryKey"`
+	UserID uint `gorm:"index"`
+	// Token is the current access token.
+	Token          string `gorm:"uniqueIndex"`
+	TokenExpiresAt time.Time
+	// RefreshTokenHash is a hash of the current refresh token.
+	RefreshTokenHash string `gorm:"uniqueIndex"`
+	// PreviousRefreshTokenHash is a hash of the refresh token that was replaced by
+	// the current one. It's kept to detect reuse of rotated refresh tokens.
+	PreviousRefreshTokenHash string `gorm:"index"`
+	RefreshToken             string `gorm:"-"`
+	UserAgent                string `gorm:"size:512"`
+	IP                       string `gorm:"size:45"`
+	CreatedAt                time.Time
+	LastUsedAt               time.Time
+	ExpiresAt                time.Time `gorm:"index"`
+	User                     User      `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// Client describes the device that signs in or uses a session.
+type Client struct {
+	UserAgent string
+	IP        string
 }
 
+// tokenSize is the amount of random bytes in tokens generated by NewToken.
+const tokenSize = 32
+
 var ErrInvalidPassword = errors.New("invalid password")
 
 func (u *User) SetPassword(password string) {
-	// Basically, an error can only occur if we provide an invalid cost so we ignore it.
-	hashedPass, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	// Basically, an error can only occur if we provide an invalid cost
+	// or run out of randomness, so we ignore it.
+	hashedPass, _ := DefaultPasswordHasher.Hash(password)
 	u.PasswordHash = hashedPass
 }
 
 func (u *User) ValidatePassword(password string) error {
-	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)); err != nil {
-		return ErrInvalidPassword
+	return ComparePassword(u.PasswordHash, password)
+}
+
+// PasswordNeedsRehash reports whether password hash should be replaced
+// with a hash made by DefaultPasswordHasher.
+func (u *User) PasswordNeedsRehash() bool {
+	return DefaultPasswordHasher.NeedsRehash(u.PasswordHash)
+}
+
+// PasswordResetToken allows to set a new password without knowing the current one.
+// Only a hash of the token is stored, the token itself is sent to the user.
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// EmailVerificationToken confirms that the user owns their email.
+// Only a hash of the token is stored, the token itself is sent to the user.
+type EmailVerificationToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// NewToken generates a random token, like a refresh token, a password reset token
+// or an email verification token.
+// Returns the token and its hash.
+func NewToken() (string, string, error) {
+	b := make([]byte, tokenSize)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
 	}
-	return nil
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns a hash of the token generated by NewToken.
+// Such tokens are random, so they don't need a slow hash function like passwords do.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
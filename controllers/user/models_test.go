@@ -47,3 +47,46 @@ func TestUser_ValidatePassword(t *testing.T) {
 		it.ErrorIs(user.ValidatePassword("notpass"), ErrInvalidPassword)
 	})
 }
+
+func TestNewToken(t *testing.T) {
+	t.Run("should generate a random token and its hash", func(t *testing.T) {
+		it := assert.New(t)
+		token1, hash1, err := NewToken()
+
+		if it.NoError(err) {
+			it.NotEmpty(token1)
+			it.Equal(HashToken(token1), hash1)
+			it.NotEqual(token1, hash1)
+		}
+
+		token2, hash2, err := NewToken()
+
+		if it.NoError(err) {
+			it.NotEqual(token1, token2)
+			it.NotEqual(hash1, hash2)
+		}
+	})
+}
+
+func TestAddress_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		address Address
+		valid   bool
+	}{
+		{name: "complete address", address: Address{Line1: "1 Main St", City: "Springfield"}, valid: true},
+		{name: "address without line1", address: Address{City: "Springfield"}},
+		{name: "address without city", address: Address{Line1: "1 Main St"}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.valid {
+				assert.NoError(t, tc.address.Validate())
+			} else {
+				assert.ErrorIs(t, tc.address.Validate(), ErrInvalidAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package user
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"food_ordering_backend/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// PasswordHasher hashes passwords. Hashes are verified with ComparePassword,
+// which supports hashes made by any PasswordHasher, so that the hasher
+// can be changed without invalidating existing passwords.
+type PasswordHasher interface {
+	Hash(password string) ([]byte, error)
+	// NeedsRehash reports whether the hash was made by another hasher
+	// or with a lower cost than the hasher uses.
+	NeedsRehash(hash []byte) bool
+}
+
+// DefaultPasswordHasher hashes passwords of all users.
+var DefaultPasswordHasher = NewPasswordHasher(config.PasswordHasher)
+
+// NewPasswordHasher returns a hasher for provided algorithm,
+// which is either config.HasherBcrypt or config.HasherArgon2id.
+func NewPasswordHasher(algorithm string) PasswordHasher {
+	if algorithm == config.HasherArgon2id {
+		return Argon2idHasher{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLength: 32, SaltLength: 16}
+	}
+
+	return BcryptHasher{Cost: config.BcryptCost}
+}
+
+// ComparePassword checks whether password matches the hash. Returns ErrInvalidPassword if it doesn't.
+func ComparePassword(hash []byte, password string) error {
+	if bytes.HasPrefix(hash, []byte(argon2idPrefix)) {
+		return compareArgon2id(hash, password)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+}
+
+func (h BcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost < h.Cost
+}
+
+// Argon2idHasher hashes passwords with argon2id and encodes them
+// in PHC string format, e.g. "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>".
+type Argon2idHasher struct {
+	Time       uint32
+	Memory     uint32 // in KiB
+	Threads    uint8
+	KeyLength  uint32
+	SaltLength uint32
+}
+
+func (h Argon2idHasher) Hash(password string) ([]byte, error) {
+	salt := make([]byte, h.SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLength)
+	params := argon2idParams{h.Time, h.Memory, h.Threads}
+
+	return []byte(params.encode(salt, key)), nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash []byte) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	return err != nil || params.time < h.Time || params.memory < h.Memory || params.threads < h.Threads
+}
+
+type argon2idParams struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func (p argon2idParams) encode(salt, key []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(hash []byte) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+	var version int
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	fields := bytes.Split(hash, []byte("$"))
+
+	if len(fields) != 6 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(string(fields[2]), "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errMalformedHash
+	}
+
+	_, err := fmt.Sscanf(string(fields[3]), "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+
+	if err != nil || params.time == 0 || params.threads == 0 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(string(fields[4]))
+
+	if err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(string(fields[5]))
+
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	return params, salt, key, nil
+}
+
+func compareArgon2id(hash []byte, password string) error {
+	params, salt, key, err := decodeArgon2id(hash)
+
+	if err != nil {
+		return ErrInvalidPassword
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrInvalidPassword
+	}
+
+	return nil
+}
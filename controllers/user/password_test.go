@@ -0,0 +1,61 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+var testArgon2idHasher = Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16}
+
+func TestComparePassword(t *testing.T) {
+	hashers := map[string]PasswordHasher{
+		"bcrypt":   BcryptHasher{Cost: bcrypt.MinCost},
+		"argon2id": testArgon2idHasher,
+	}
+
+	for name, hasher := range hashers {
+		t.Run("should verify "+name+" hashes", func(t *testing.T) {
+			it := assert.New(t)
+			hash, err := hasher.Hash("password")
+
+			if it.NoError(err) {
+				it.NoError(ComparePassword(hash, "password"))
+				it.ErrorIs(ComparePassword(hash, "another password"), ErrInvalidPassword)
+			}
+		})
+	}
+
+	t.Run("should return ErrInvalidPassword if hash is malformed", func(t *testing.T) {
+		it := assert.New(t)
+
+		for _, hash := range []string{"", "hash", "$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA"} {
+			it.ErrorIs(ComparePassword([]byte(hash), "password"), ErrInvalidPassword, hash)
+		}
+	})
+}
+
+func TestBcryptHasher_NeedsRehash(t *testing.T) {
+	it := assert.New(t)
+	hasher := BcryptHasher{Cost: bcrypt.MinCost + 1}
+	weak, _ := BcryptHasher{Cost: bcrypt.MinCost}.Hash("password")
+	same, _ := hasher.Hash("password")
+	argon, _ := testArgon2idHasher.Hash("password")
+
+	it.True(hasher.NeedsRehash(weak), "expected hash with lower cost to need rehash")
+	it.False(hasher.NeedsRehash(same))
+	it.True(hasher.NeedsRehash(argon), "expected hash made by another hasher to need rehash")
+}
+
+func TestArgon2idHasher_NeedsRehash(t *testing.T) {
+	it := assert.New(t)
+	hasher := testArgon2idHasher
+	hasher.Memory *= 2
+	weak, _ := testArgon2idHasher.Hash("password")
+	same, _ := hasher.Hash("password")
+	bcryptHash, _ := BcryptHasher{Cost: bcrypt.MinCost}.Hash("password")
+
+	it.True(hasher.NeedsRehash(weak), "expected hash with lower cost to need rehash")
+	it.False(hasher.NeedsRehash(same))
+	it.True(hasher.NeedsRehash(bcryptHash), "expected hash made by another hasher to need rehash")
+}
@@ -3,6 +3,8 @@ package user
 import (
 	"food_ordering_backend/common"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type Repository struct {
@@ -13,6 +15,11 @@ func ProvideRepository(db *gorm.DB) *Repository {
 	return &Repository{db}
 }
 
+// WithTx returns a copy of the repository that runs all queries in provided transaction.
+func (r *Repository) WithTx(tx *gorm.DB) *Repository {
+	return &Repository{tx}
+}
+
 func (r *Repository) Create(u User) (User, error) {
 	err := r.db.Create(&u).Error
 	return u, err
@@ -20,7 +27,7 @@ func (r *Repository) Create(u User) (User, error) {
 
 func (r *Repository) FindByID(id uint) (User, error) {
 	var u User
-	if err := r.db.Find(&u, id).Error; err != nil {
+	if err := r.db.Preload("Roles").First(&u, id).Error; err != nil {
 		return u, err
 	}
 	return u, nil
@@ -28,32 +35,157 @@ func (r *Repository) FindByID(id uint) (User, error) {
 
 func (r *Repository) FindByEmail(email string) (User, error) {
 	var u User
-	err := r.db.Where("email = ?", email).Find(&u).Error
+	err := r.db.Preload("Roles").Where("email = ?", email).Find(&u).Error
 	return u, err
 }
 
-func (r *Repository) FindAll(p common.Paginator) []User {
+func (r *Repository) FindRoles(userID uint) ([]UserRole, error) {
+	var roles []UserRole
+	err := r.db.Where("user_id = ?", userID).Find(&roles).Error
+	return roles, err
+}
+
+// SetRoles replaces roles of the user with provided ones.
+// It should be run in a transaction.
+func (r *Repository) SetRoles(u User, roles []Role) error {
+	if err := r.db.Where("user_id = ?", u.ID).Delete(&UserRole{}).Error; err != nil {
+		return err
+	}
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	userRoles := make([]UserRole, len(roles))
+
+	for i, role := range roles {
+		userRoles[i] = UserRole{UserID: u.ID, Role: role}
+	}
+
+	return r.db.Omit("User").Create(&userRoles).Error
+}
+
+// AddRole assigns the role to the user unless it's already assigned.
+func (r *Repository) AddRole(u User, role Role) error {
+	return r.db.Omit("User").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserRole{UserID: u.ID, Role: role}).Error
+}
+
+func (r *Repository) RemoveRole(u User, role Role) error {
+	return r.db.Where("user_id = ? AND role = ?", u.ID, role).Delete(&UserRole{}).Error
+}
+
+// CountWithRole returns the amount of enabled users that have provided role. When
+// run in a transaction, it locks these users until the transaction ends, so that
+// concurrent transactions can't remove the role from all of them or disable them.
+func (r *Repository) CountWithRole(role Role) (int, error) {
+	var ids []uint
+	err := r.db.Model(&UserRole{}).
+		Joins("JOIN users ON users.id = user_roles.user_id AND NOT users.disabled").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_roles.role = ?", role).
+		Pluck("user_roles.user_id", &ids).Error
+	return len(ids), err
+}
+
+func (r *Repository) SetDisabled(u User, disabled bool) error {
+	return r.db.Model(&User{ID: u.ID}).Update("disabled", disabled).Error
+}
+
+// Delete deletes the user. Sessions, roles and password reset tokens of the user
+// are deleted along with it by the database. Returns gorm.ErrRecordNotFound if
+// the user doesn't exist.
+func (r *Repository) Delete(u User) error {
+	res := r.db.Delete(&User{}, u.ID)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *Repository) FindAll(f Filter, p common.Paginator) ([]User, error) {
 	var users []User
-	tx := r.db
+	tx := r.db.Preload("Roles").Scopes(f.Where, f.OrderBy)
 
 	if p != nil {
-		r.db.Scopes(common.WithPagination(p))
+		tx = tx.Scopes(common.WithPagination(p))
 	}
 
-	tx.Find(&users)
-	return users
+	err := tx.Find(&users).Error
+	return users, err
+}
+
+// UpdatePassword saves password hash of the user.
+func (r *Repository) UpdatePassword(u User) error {
+	return r.db.Model(&u).Update("password_hash", u.PasswordHash).Error
 }
 
-func (r *Repository) CreateSession(s Session) error {
-	return r.db.Create(&s).Error
+func (r *Repository) CreateSession(s Session) (Session, error) {
+	err := r.db.Create(&s).Error
+	return s, err
 }
 
+// FindSessionByToken returns session with provided token
+// unless it has expired.
 func (r *Repository) FindSessionByToken(token string) (Session, error) {
 	var session Session
-	err := r.db.Where("token = ?", token).Joins("User").Find(&session).Error
+	err := r.db.Where("token = ? AND expires_at > ?", token, time.Now()).Joins("User").First(&session).Error
+	return session, err
+}
+
+// FindSessionByRefreshTokenHash returns session with provided hash
+// of the current refresh token unless it has expired.
+func (r *Repository) FindSessionByRefreshTokenHash(hash string) (Session, error) {
+	var session Session
+	err := r.db.Where("refresh_token_hash = ? AND expires_at > ?", hash, time.Now()).Joins("User").First(&session).Error
 	return session, err
 }
 
+// FindSessions returns active sessions of the user, recently used first.
+func (r *Repository) FindSessions(u User) ([]Session, error) {
+	var sessions []Session
+	err := r.db.Where("user_id = ? AND expires_at > ?", u.ID, time.Now()).Order("last_used_at DESC").Order("id DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// RotateSession saves new tokens and client info of the session, unless its refresh
+// token has already been replaced with another one after previousHash was read.
+// Returns gorm.ErrRecordNotFound in that case.
+func (r *Repository) RotateSession(s Session, previousHash string) error {
+	res := r.db.Model(&Session{}).Where("id = ? AND refresh_token_hash = ?", s.ID, previousHash).Updates(map[string]interface{}{
+		"token":                       s.Token,
+		"token_expires_at":            s.TokenExpiresAt,
+		"refresh_token_hash":          s.RefreshTokenHash,
+		"previous_refresh_token_hash": s.PreviousRefreshTokenHash,
+		"user_agent":                  s.UserAgent,
+		"ip":                          s.IP,
+		"last_used_at":                s.LastUsedAt,
+		"expires_at":                  s.ExpiresAt,
+	})
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// TouchSession sets last usage time of the session.
+func (r *Repository) TouchSession(id uint, at time.Time) error {
+	return r.db.Model(&Session{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
 func (r *Repository) DeleteSession(token string) error {
 	return r.db.Delete(&Session{}, "token = ?", token).Error
 }
@@ -63,8 +195,178 @@ func (r *Repository) DeleteAllSessions(u User) error {
 	return r.db.Where("user_id = ?", u.ID).Delete(&s).Error
 }
 
-func (r *Repository) CountAll() int {
+// DeleteOtherSessions deletes all sessions of the user except the one with provided id.
+func (r *Repository) DeleteOtherSessions(u User, keepID uint) error {
+	return r.db.Where("user_id = ? AND id <> ?", u.ID, keepID).Delete(&Session{}).Error
+}
+
+// DeleteUserSession deletes session with provided id if it belongs to the user.
+// Returns gorm.ErrRecordNotFound if there is no such session.
+func (r *Repository) DeleteUserSession(u User, id uint) error {
+	res := r.db.Where("user_id = ?", u.ID).Delete(&Session{}, id)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteSessionByPreviousRefreshTokenHash deletes session whose previous
+// refresh token has provided hash. Returns the amount of deleted sessions.
+func (r *Repository) DeleteSessionByPreviousRefreshTokenHash(hash string) (int64, error) {
+	res := r.db.Where("previous_refresh_token_hash = ?", hash).Delete(&Session{})
+	return res.RowsAffected, res.Error
+}
+
+// DeleteExpiredSessions deletes sessions that expired before provided time.
+// Returns the amount of deleted sessions.
+func (r *Repository) DeleteExpiredSessions(before time.Time) (int64, error) {
+	res := r.db.Where("expires_at <= ?", before).Delete(&Session{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *Repository) CreatePasswordResetToken(t PasswordResetToken) error {
+	return r.db.Create(&t).Error
+}
+
+// FindPasswordResetToken returns password reset token with provided hash unless it has expired.
+func (r *Repository) FindPasswordResetToken(hash string) (PasswordResetToken, error) {
+	var t PasswordResetToken
+	err := r.db.Where("token_hash = ? AND expires_at > ?", hash, time.Now()).Joins("User").First(&t).Error
+	return t, err
+}
+
+// DeletePasswordResetToken deletes password reset token with provided id.
+// Returns gorm.ErrRecordNotFound if it has already been deleted.
+func (r *Repository) DeletePasswordResetToken(id uint) error {
+	res := r.db.Delete(&PasswordResetToken{}, id)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeletePasswordResetTokens deletes all password reset tokens of the user.
+func (r *Repository) DeletePasswordResetTokens(u User) error {
+	return r.db.Where("user_id = ?", u.ID).Delete(&PasswordResetToken{}).Error
+}
+
+// DeleteExpiredPasswordResetTokens deletes password reset tokens that expired before provided time.
+func (r *Repository) DeleteExpiredPasswordResetTokens(before time.Time) error {
+	return r.db.Where("expires_at <= ?", before).Delete(&PasswordResetToken{}).Error
+}
+
+func (r *Repository) CreateEmailVerificationToken(t EmailVerificationToken) error {
+	return r.db.Omit("User").Create(&t).Error
+}
+
+// FindEmailVerificationToken returns email verification token with provided hash unless it has expired.
+func (r *Repository) FindEmailVerificationToken(hash string) (EmailVerificationToken, error) {
+	var t EmailVerificationToken
+	err := r.db.Where("token_hash = ? AND expires_at > ?", hash, time.Now()).Joins("User").First(&t).Error
+	return t, err
+}
+
+// DeleteEmailVerificationTokens deletes all email verification tokens of the user.
+func (r *Repository) DeleteEmailVerificationTokens(u User) error {
+	return r.db.Where("user_id = ?", u.ID).Delete(&EmailVerificationToken{}).Error
+}
+
+// DeleteExpiredEmailVerificationTokens deletes email verification tokens that expired before provided time.
+func (r *Repository) DeleteExpiredEmailVerificationTokens(before time.Time) error {
+	return r.db.Where("expires_at <= ?", before).Delete(&EmailVerificationToken{}).Error
+}
+
+// MarkEmailVerified sets EmailVerified flag of the user.
+func (r *Repository) MarkEmailVerified(u User) error {
+	return r.db.Model(&User{ID: u.ID}).Update("email_verified", true).Error
+}
+
+// UpdateProfile saves name and phone of the user.
+func (r *Repository) UpdateProfile(u User) error {
+	return r.db.Model(&User{ID: u.ID}).Updates(map[string]interface{}{
+		"name":  u.Name,
+		"phone": u.Phone,
+	}).Error
+}
+
+// LockUser locks the user row until the transaction ends, so that
+// concurrent transactions changing the same user are run one by one.
+func (r *Repository) LockUser(id uint) error {
+	var ids []uint
+	return r.db.Model(&User{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Pluck("id", &ids).Error
+}
+
+// FindAddresses returns addresses of the user, the default one first.
+func (r *Repository) FindAddresses(userID uint) ([]Address, error) {
+	var addresses []Address
+	err := r.db.Where("user_id = ?", userID).Order("is_default DESC").Order("id").Find(&addresses).Error
+	return addresses, err
+}
+
+// FindAddress returns address of the user with provided id.
+// Returns gorm.ErrRecordNotFound if the user doesn't have such address.
+func (r *Repository) FindAddress(userID, id uint) (Address, error) {
+	var a Address
+	err := r.db.Where("user_id = ?", userID).First(&a, id).Error
+	return a, err
+}
+
+// FindDefaultAddress returns default address of the user.
+// Returns gorm.ErrRecordNotFound if the user doesn't have one.
+func (r *Repository) FindDefaultAddress(userID uint) (Address, error) {
+	var a Address
+	err := r.db.Where("user_id = ? AND is_default", userID).First(&a).Error
+	return a, err
+}
+
+func (r *Repository) CountAddresses(userID uint) (int, error) {
+	var count int64
+	err := r.db.Model(&Address{}).Where("user_id = ?", userID).Count(&count).Error
+	return int(count), err
+}
+
+func (r *Repository) CreateAddress(a Address) (Address, error) {
+	err := r.db.Omit("User").Create(&a).Error
+	return a, err
+}
+
+func (r *Repository) SaveAddress(a Address) (Address, error) {
+	err := r.db.Omit("User").Save(&a).Error
+	return a, err
+}
+
+func (r *Repository) DeleteAddress(a Address) error {
+	return r.db.Delete(&Address{}, a.ID).Error
+}
+
+// UnsetDefaultAddress makes the default address of the user non-default,
+// unless it's the address with provided id.
+func (r *Repository) UnsetDefaultAddress(userID, keepID uint) error {
+	return r.db.Model(&Address{}).
+		Where("user_id = ? AND is_default AND id <> ?", userID, keepID).
+		Update("is_default", false).Error
+}
+
+// PromoteOldestAddress makes the oldest address of the user default.
+func (r *Repository) PromoteOldestAddress(userID uint) error {
+	oldest := r.db.Model(&Address{}).Select("id").Where("user_id = ?", userID).Order("id").Limit(1)
+	return r.db.Model(&Address{}).Where("id = (?)", oldest).Update("is_default", true).Error
+}
+
+func (r *Repository) CountAll(f Filter) int {
 	var count int64
-	r.db.Model(&User{}).Count(&count)
+	r.db.Model(&User{}).Scopes(f.Where).Count(&count)
 	return int(count)
 }
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Permission allows a user to perform a group of actions.
+type Permission string
+
+const (
+	// PermOrdersReadAll allows to see orders of all users.
+	PermOrdersReadAll Permission = "orders:read_all"
+	// PermOrdersUpdateStatus allows to move orders of all users to another status.
+	PermOrdersUpdateStatus Permission = "orders:update_status"
+	// PermOrdersWrite allows to edit items and adjustments of orders of all users.
+	PermOrdersWrite Permission = "orders:write"
+	// PermMenuWrite allows to create, edit and delete categories and dishes.
+	PermMenuWrite Permission = "menu:write"
+	// PermUsersRead allows to see all users.
+	PermUsersRead Permission = "users:read"
+	// PermUsersManage allows to assign roles to users.
+	PermUsersManage Permission = "users:manage"
+)
+
+// Role is a named set of permissions that can be assigned to users.
+type Role string
+
+const (
+	RoleAdmin       Role = "admin"
+	RoleKitchen     Role = "kitchen"
+	RoleMenuManager Role = "menu_manager"
+)
+
+// RolePermissions lists permissions granted by each role.
+var RolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermOrdersReadAll,
+		PermOrdersUpdateStatus,
+		PermOrdersWrite,
+		PermMenuWrite,
+		PermUsersRead,
+		PermUsersManage,
+	},
+	RoleKitchen:     {PermOrdersReadAll, PermOrdersUpdateStatus},
+	RoleMenuManager: {PermMenuWrite},
+}
+
+// ErrUnknownRole is returned when a role isn't in RolePermissions.
+type ErrUnknownRole struct {
+	Role Role
+}
+
+func (e *ErrUnknownRole) Error() string {
+	return fmt.Sprintf("Role %q doesn't exist", e.Role)
+}
+
+// UserRole assigns a role to a user.
+type UserRole struct {
+	UserID    uint `gorm:"primaryKey"`
+	Role      Role `gorm:"primaryKey;size:50"`
+	CreatedAt time.Time
+	User      User `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+// IsValidRole reports whether the role exists.
+func IsValidRole(r Role) bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+// HasRole reports whether the role is assigned to the user.
+func (u User) HasRole(r Role) bool {
+	for _, ur := range u.Roles {
+		if ur.Role == r {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission reports whether any of user's roles grants the permission.
+func (u User) HasPermission(p Permission) bool {
+	for _, ur := range u.Roles {
+		for _, granted := range RolePermissions[ur.Role] {
+			if granted == p {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RoleNames returns names of user's roles sorted alphabetically.
+func (u User) RoleNames() []Role {
+	roles := make([]Role, len(u.Roles))
+
+	for i, ur := range u.Roles {
+		roles[i] = ur.Role
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i] < roles[j]
+	})
+
+	return roles
+}
+
+// Permissions returns all permissions granted by user's roles sorted alphabetically.
+func (u User) Permissions() []Permission {
+	set := make(map[Permission]struct{})
+
+	for _, ur := range u.Roles {
+		for _, p := range RolePermissions[ur.Role] {
+			set[p] = struct{}{}
+		}
+	}
+
+	perms := make([]Permission, 0, len(set))
+
+	for p := range set {
+		perms = append(perms, p)
+	}
+
+	sort.Slice(perms, func(i, j int) bool {
+		return perms[i] < perms[j]
+	})
+
+	return perms
+}
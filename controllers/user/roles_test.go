@@ -0,0 +1,28 @@
+package user
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUser_HasPermission(t *testing.T) {
+	it := assert.New(t)
+	u := User{Roles: []UserRole{{Role: RoleKitchen}, {Role: RoleMenuManager}}}
+
+	it.True(u.HasPermission(PermOrdersUpdateStatus))
+	it.True(u.HasPermission(PermMenuWrite))
+	it.False(u.HasPermission(PermUsersManage))
+	it.False(User{}.HasPermission(PermOrdersReadAll), "expected user without roles to have no permissions")
+	it.False(User{Roles: []UserRole{{Role: "removed"}}}.HasPermission(PermOrdersReadAll), "expected unknown roles to grant nothing")
+}
+
+func TestUser_Permissions(t *testing.T) {
+	it := assert.New(t)
+	u := User{Roles: []UserRole{{Role: RoleMenuManager}, {Role: RoleKitchen}}}
+
+	it.Equal([]Role{RoleKitchen, RoleMenuManager}, u.RoleNames())
+	it.Equal([]Permission{PermMenuWrite, PermOrdersReadAll, PermOrdersUpdateStatus}, u.Permissions())
+
+	admin := User{Roles: []UserRole{{Role: RoleAdmin}, {Role: RoleKitchen}}}
+	it.Len(admin.Permissions(), len(RolePermissions[RoleAdmin]), "expected permissions to be deduplicated")
+}
@@ -1,47 +1,257 @@
 package user
 
-import "food_ordering_backend/common"
+import (
+	"errors"
+	"fmt"
+	"food_ordering_backend/common"
+	"food_ordering_backend/config"
+	"food_ordering_backend/services"
+	"gorm.io/gorm"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// sessionTouchInterval limits how often last usage time of a session is updated,
+// so that not every request results in a write.
+const sessionTouchInterval = time.Minute
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+var ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+var ErrEmailAlreadyVerified = errors.New("email has already been verified")
+var ErrLastAdmin = errors.New("the last admin can't lose admin role, be disabled or deleted")
+var ErrUserDisabled = errors.New("user is disabled")
+var ErrOwnAccount = errors.New("admins can't disable or delete their own account")
+
+// ErrLoginLocked means that there were too many failed sign in attempts with the email.
+type ErrLoginLocked struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrLoginLocked) Error() string {
+	return "Too many failed sign in attempts, try again later"
+}
+
+// passwordResetPath is the path of the frontend page that sets a new password
+// using the token from its "token" query parameter.
+const passwordResetPath = "/reset-password"
+
+// emailVerificationPath is the path of the route that verifies email, relative to config.HostURL.
+const emailVerificationPath = "/users/verify"
 
 type Service struct {
 	jwtService *JWTService
 	repo       *Repository
+	mailer     services.Mailer
+	transactor *common.Transactor
+	// lockout counts failed sign in attempts by email.
+	lockout *services.Limiter
 }
 
-func ProvideService(r *Repository, jwtService *JWTService) *Service {
-	return &Service{jwtService, r}
+func ProvideService(r *Repository, jwtService *JWTService, mailer services.Mailer, transactor *common.Transactor, store services.LimiterStore) *Service {
+	lockout := services.NewLimiter(store, "login-lockout", config.LoginLockout)
+	return &Service{jwtService, r, mailer, transactor, lockout}
 }
 
+// Create creates a new user and sends a link that verifies their email.
+// Failing to send the link isn't an error, since it can be sent again.
 func (s *Service) Create(dto AuthDTO) (User, error) {
 	user := CreateFromDTO(dto)
-	return s.repo.Create(user)
+	var token string
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		var err error
+		repo := s.repo.WithTx(tx)
+
+		if user, err = repo.Create(user); err != nil {
+			return err
+		}
+
+		token, err = createEmailVerificationToken(repo, user)
+		return err
+	})
+
+	if err != nil {
+		return user, err
+	}
+
+	if err := s.mailer.Send(emailVerificationMail(user, token)); err != nil {
+		log.Println("[User] Error sending email verification link:", err)
+	}
+
+	return user, nil
+}
+
+// SendEmailVerification sends a new email verification link to the user.
+// Only the latest link is valid. Returns ErrEmailAlreadyVerified if there is nothing to verify.
+func (s *Service) SendEmailVerification(u User) error {
+	if u.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	var token string
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = createEmailVerificationToken(s.repo.WithTx(tx), u)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(emailVerificationMail(u, token))
+}
+
+// VerifyEmail marks email of the user that owns provided verification token as verified.
+// Returns ErrInvalidVerificationToken if the token doesn't exist or has expired.
+func (s *Service) VerifyEmail(token string) (User, error) {
+	t, err := s.repo.FindEmailVerificationToken(HashToken(token))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return User{}, ErrInvalidVerificationToken
+	}
+
+	if err != nil {
+		return User{}, err
+	}
+
+	err = s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.MarkEmailVerified(t.User); err != nil {
+			return err
+		}
+
+		return repo.DeleteEmailVerificationTokens(t.User)
+	})
+
+	if err != nil {
+		return User{}, err
+	}
+
+	return s.repo.FindByID(t.UserID)
 }
 
-func (s *Service) FindAll(p common.Paginator) []User {
-	return s.repo.FindAll(p)
+func (s *Service) FindAll(f Filter, p common.Paginator) ([]User, error) {
+	return s.repo.FindAll(f, p)
 }
 
 func (s *Service) FindByID(id uint) (User, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *Service) Login(dto AuthDTO) (Session, error) {
-	var session Session
+// Login creates a new session for the user with provided credentials.
+// Returned session has its refresh token set. After too many failed attempts
+// signing in with the email is locked and ErrLoginLocked is returned.
+func (s *Service) Login(dto AuthDTO, client Client) (Session, error) {
+	email := strings.ToLower(dto.Email)
+
+	if wait := s.lockout.Wait(email); wait > 0 {
+		return Session{}, &ErrLoginLocked{wait}
+	}
+
 	u, err := s.repo.FindByEmail(dto.Email)
 
 	if err != nil {
-		return session, err
+		return Session{}, err
 	}
 
+	// unknown emails are counted too, so that locking doesn't reveal which emails are registered
 	if err := u.ValidatePassword(dto.Password); err != nil {
-		return session, err
+		s.lockout.Allow(email)
+		return Session{}, err
+	}
+
+	s.lockout.Reset(email)
+
+	if u.Disabled {
+		return Session{}, ErrUserDisabled
+	}
+
+	if u.PasswordNeedsRehash() {
+		u.SetPassword(dto.Password)
+
+		// old hash is still valid, so signing in shouldn't fail
+		if err := s.repo.UpdatePassword(u); err != nil {
+			log.Println("[User] Error rehashing password:", err)
+		}
+	}
+
+	session := Session{
+		UserID:     u.ID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastUsedAt: time.Now(),
+	}
+
+	if err := s.issueTokens(&session); err != nil {
+		return Session{}, err
+	}
+
+	refreshToken := session.RefreshToken
+	session, err = s.repo.CreateSession(session)
+
+	if err != nil {
+		return Session{}, err
 	}
 
-	session.UserID = u.ID
 	session.User = u
-	session.Token = s.jwtService.Generate(u.ID)
+	session.RefreshToken = refreshToken
+	return session, nil
+}
 
-	if err := s.repo.CreateSession(session); err != nil {
-		return session, err
+// Refresh issues new access and refresh tokens for the session that owns provided
+// refresh token and extends its expiration time. Every refresh token can be used
+// only once. If a token that has already been replaced is used again, it might have
+// been stolen, so the session is deleted and ErrRefreshTokenReused is returned.
+func (s *Service) Refresh(refreshToken string, client Client) (Session, error) {
+	hash := HashToken(refreshToken)
+	session, err := s.repo.FindSessionByRefreshTokenHash(hash)
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		n, err := s.repo.DeleteSessionByPreviousRefreshTokenHash(hash)
+
+		if err != nil {
+			return Session{}, err
+		}
+
+		if n > 0 {
+			return Session{}, ErrRefreshTokenReused
+		}
+
+		return Session{}, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return Session{}, err
+	}
+
+	if session.User.Disabled {
+		return Session{}, ErrUserDisabled
+	}
+
+	session.PreviousRefreshTokenHash = hash
+	session.UserAgent = client.UserAgent
+	session.IP = client.IP
+	session.LastUsedAt = time.Now()
+
+	if err := s.issueTokens(&session); err != nil {
+		return Session{}, err
+	}
+
+	if err := s.repo.RotateSession(session, hash); err != nil {
+		// token was used by a concurrent request
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Session{}, ErrInvalidRefreshToken
+		}
+
+		return Session{}, err
 	}
 
 	return session, nil
@@ -51,10 +261,409 @@ func (s *Service) Logout(token string) error {
 	return s.repo.DeleteSession(token)
 }
 
+// LogoutEverywhere deletes all sessions of the user.
+func (s *Service) LogoutEverywhere(u User) error {
+	return s.repo.DeleteAllSessions(u)
+}
+
+// FindSessions returns active sessions of the user.
+func (s *Service) FindSessions(u User) ([]Session, error) {
+	return s.repo.FindSessions(u)
+}
+
+// DeleteSession deletes session of the user with provided id.
+// Returns gorm.ErrRecordNotFound if user doesn't have such session.
+func (s *Service) DeleteSession(u User, id uint) error {
+	return s.repo.DeleteUserSession(u, id)
+}
+
+// TouchSession updates last usage time of the session.
+func (s *Service) TouchSession(session Session) error {
+	now := time.Now()
+
+	if now.Sub(session.LastUsedAt) < sessionTouchInterval {
+		return nil
+	}
+
+	return s.repo.TouchSession(session.ID, now)
+}
+
+// ChangePassword sets a new password for the user of provided session if the current
+// password is correct. All other sessions of the user are deleted.
+func (s *Service) ChangePassword(session Session, dto ChangePasswordDTO) error {
+	u := session.User
+
+	if err := u.ValidatePassword(dto.CurrentPassword); err != nil {
+		return err
+	}
+
+	u.SetPassword(dto.NewPassword)
+
+	return s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.UpdatePassword(u); err != nil {
+			return err
+		}
+
+		if err := repo.DeletePasswordResetTokens(u); err != nil {
+			return err
+		}
+
+		return repo.DeleteOtherSessions(u, session.ID)
+	})
+}
+
+// ForgotPassword sends a password reset link to the user with provided email.
+// Only the latest link is valid. If there is no such user or the user is disabled,
+// ForgotPassword does nothing, so that clients can't find out which emails are registered.
+func (s *Service) ForgotPassword(email string) error {
+	u, err := s.repo.FindByEmail(email)
+
+	if err != nil || u.ID == 0 || u.Disabled {
+		return err
+	}
+
+	token, hash, err := NewToken()
+
+	if err != nil {
+		return err
+	}
+
+	t := PasswordResetToken{
+		UserID:    u.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.PasswordResetTokenExpiry),
+	}
+
+	err = s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := repo.DeletePasswordResetTokens(u); err != nil {
+			return err
+		}
+
+		return repo.CreatePasswordResetToken(t)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(passwordResetMail(u, token))
+}
+
+// ResetPassword sets a new password for the user that owns provided reset token.
+// Token can be used only once. All sessions of the user are deleted.
+func (s *Service) ResetPassword(dto ResetPasswordDTO) error {
+	t, err := s.repo.FindPasswordResetToken(HashToken(dto.Token))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	u := t.User
+	u.SetPassword(dto.Password)
+
+	return s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		// fails if the token was used by a concurrent request
+		if err := repo.DeletePasswordResetToken(t.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+
+			return err
+		}
+
+		if err := repo.UpdatePassword(u); err != nil {
+			return err
+		}
+
+		if err := repo.DeletePasswordResetTokens(u); err != nil {
+			return err
+		}
+
+		return repo.DeleteAllSessions(u)
+	})
+}
+
+// FindSessionByToken returns session with provided token. Returns an error
+// if the token is invalid, e.g. has expired or is signed with a key that
+// has been removed, if session doesn't exist or its user is disabled.
 func (s *Service) FindSessionByToken(token string) (Session, error) {
-	return s.repo.FindSessionByToken(token)
+	if _, err := s.jwtService.AuthClaimsFromToken(token); err != nil {
+		return Session{}, err
+	}
+
+	session, err := s.repo.FindSessionByToken(token)
+
+	if err != nil {
+		return session, err
+	}
+
+	if session.User.Disabled {
+		return Session{}, ErrUserDisabled
+	}
+
+	session.User.Roles, err = s.repo.FindRoles(session.UserID)
+	return session, err
+}
+
+// SetRoles replaces roles of the user with provided ones. Returns ErrUnknownRole
+// if any of roles doesn't exist and ErrLastAdmin if the user is the only admin
+// and RoleAdmin isn't among provided roles.
+func (s *Service) SetRoles(u User, roles []Role) (User, error) {
+	unique := make([]Role, 0, len(roles))
+	seen := make(map[Role]bool)
+
+	for _, role := range roles {
+		if !IsValidRole(role) {
+			return u, &ErrUnknownRole{role}
+		}
+
+		if !seen[role] {
+			seen[role] = true
+			unique = append(unique, role)
+		}
+	}
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if !seen[RoleAdmin] {
+			if err := ensureNotLastAdmin(repo, u); err != nil {
+				return err
+			}
+		}
+
+		return repo.SetRoles(u, unique)
+	})
+
+	if err != nil {
+		return u, err
+	}
+
+	return s.repo.FindByID(u.ID)
+}
+
+// Patch grants or revokes admin role and disables or enables the user on behalf
+// of admin by. Disabled users are logged out. Returns ErrOwnAccount if admin
+// tries to disable themselves and ErrLastAdmin if the user is the last admin.
+func (s *Service) Patch(u User, dto PatchDTO, by User) (User, error) {
+	if dto.Disabled != nil && *dto.Disabled && u.ID == by.ID {
+		return u, ErrOwnAccount
+	}
+
+	err := s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+		revokesAdmin := dto.IsAdmin != nil && !*dto.IsAdmin
+		disables := dto.Disabled != nil && *dto.Disabled
+
+		if revokesAdmin || disables {
+			if err := ensureNotLastAdmin(repo, u); err != nil {
+				return err
+			}
+		}
+
+		if dto.IsAdmin != nil {
+			var err error
+
+			if *dto.IsAdmin {
+				err = repo.AddRole(u, RoleAdmin)
+			} else {
+				err = repo.RemoveRole(u, RoleAdmin)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if dto.Disabled == nil {
+			return nil
+		}
+
+		if err := repo.SetDisabled(u, *dto.Disabled); err != nil {
+			return err
+		}
+
+		if !disables {
+			return nil
+		}
+
+		if err := repo.DeleteAllSessions(u); err != nil {
+			return err
+		}
+
+		return repo.DeletePasswordResetTokens(u)
+	})
+
+	if err != nil {
+		return u, err
+	}
+
+	return s.repo.FindByID(u.ID)
 }
 
-func (s *Service) CountAll() int {
-	return s.repo.CountAll()
+// Delete deletes the user on behalf of admin by. Orders of the user are kept
+// without a customer. Returns ErrOwnAccount if admin tries to delete themselves
+// and ErrLastAdmin if the user is the last admin.
+func (s *Service) Delete(u User, by User) error {
+	if u.ID == by.ID {
+		return ErrOwnAccount
+	}
+
+	return s.transactor.Transaction(func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		if err := ensureNotLastAdmin(repo, u); err != nil {
+			return err
+		}
+
+		return repo.Delete(u)
+	})
+}
+
+// UpdateProfile replaces name and phone of the user.
+func (s *Service) UpdateProfile(u User, dto ProfileDTO) (User, error) {
+	u.Name = strings.TrimSpace(dto.Name)
+	u.Phone = dto.Phone
+
+	if err := s.repo.UpdateProfile(u); err != nil {
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// PruneSessions deletes expired sessions, password reset tokens and email
+// verification tokens. Returns the amount of deleted sessions.
+func (s *Service) PruneSessions() (int64, error) {
+	now := time.Now()
+
+	if err := s.repo.DeleteExpiredPasswordResetTokens(now); err != nil {
+		return 0, err
+	}
+
+	if err := s.repo.DeleteExpiredEmailVerificationTokens(now); err != nil {
+		return 0, err
+	}
+
+	return s.repo.DeleteExpiredSessions(now)
+}
+
+// PruneSessionsEvery calls PruneSessions with provided interval. It never returns,
+// so it should be run in a separate goroutine.
+func (s *Service) PruneSessionsEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		n, err := s.PruneSessions()
+
+		if err != nil {
+			log.Println("[User] Error pruning sessions:", err)
+			continue
+		}
+
+		if n > 0 {
+			log.Printf("[User] Pruned %d expired sessions\n", n)
+		}
+	}
+}
+
+// issueTokens sets new access and refresh tokens to the session
+// and extends its expiration time.
+func (s *Service) issueTokens(session *Session) error {
+	refreshToken, hash, err := NewToken()
+
+	if err != nil {
+		return err
+	}
+
+	session.Token, session.TokenExpiresAt = s.jwtService.Generate(session.UserID)
+	session.RefreshToken = refreshToken
+	session.RefreshTokenHash = hash
+	session.ExpiresAt = time.Now().Add(config.RefreshTokenExpiry)
+
+	return nil
+}
+
+func (s *Service) CountAll(f Filter) int {
+	return s.repo.CountAll(f)
+}
+
+// ensureNotLastAdmin returns ErrLastAdmin if u is the only enabled admin.
+// It must be run in a transaction that removes admin role from u, disables or deletes u.
+func ensureNotLastAdmin(repo *Repository, u User) error {
+	if !u.HasRole(RoleAdmin) || u.Disabled {
+		return nil
+	}
+
+	admins, err := repo.CountWithRole(RoleAdmin)
+
+	if err != nil {
+		return err
+	}
+
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// createEmailVerificationToken replaces email verification tokens of the user with
+// a new one. Returns the token. It should be run in a transaction.
+func createEmailVerificationToken(repo *Repository, u User) (string, error) {
+	token, hash, err := NewToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := repo.DeleteEmailVerificationTokens(u); err != nil {
+		return "", err
+	}
+
+	err = repo.CreateEmailVerificationToken(EmailVerificationToken{
+		UserID:    u.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.EmailVerificationTokenExpiry),
+	})
+
+	return token, err
+}
+
+func emailVerificationMail(u User, token string) services.Mail {
+	link := *config.HostURL
+	link.Path = path.Join("/", link.Path, emailVerificationPath)
+	link.RawQuery = url.Values{"token": {token}}.Encode()
+
+	return services.Mail{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"To verify your email, follow the link:\n%s\n\nThe link expires in %s. If you didn't sign up, ignore this email.",
+			link.String(), config.EmailVerificationTokenExpiry,
+		),
+	}
+}
+
+func passwordResetMail(u User, token string) services.Mail {
+	link := *config.ClientURL
+	link.Path = path.Join("/", link.Path, passwordResetPath)
+	link.RawQuery = url.Values{"token": {token}}.Encode()
+
+	return services.Mail{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"To set a new password, follow the link:\n%s\n\nThe link expires in %s. If you didn't request a password reset, ignore this email.",
+			link.String(), config.PasswordResetTokenExpiry,
+		),
+	}
 }
@@ -3,11 +3,16 @@
 package user
 
 import (
+	"food_ordering_backend/common"
+	"food_ordering_backend/services"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
 
-var set = wire.NewSet(ProvideService, ProvideRepository, ProvideJWTService)
+// AddressServiceSet provides AddressService. It requires *common.Transactor.
+var AddressServiceSet = wire.NewSet(ProvideAddressService, ProvideRepository)
+
+var set = wire.NewSet(ProvideService, AddressServiceSet, ProvideJWTService, services.ProvideMailer, services.ProvideLimiterStore, common.ProvideTransactor)
 
 func InitAPI(db *gorm.DB) *API {
 	wire.Build(ProvideAPI, set)
@@ -18,3 +23,8 @@ func InitAuthMiddleware(db *gorm.DB) AuthMiddlewareFunc {
 	wire.Build(ProvideAuthMiddleware, set)
 	return nil
 }
+
+func InitService(db *gorm.DB) *Service {
+	wire.Build(set)
+	return nil
+}
@@ -6,6 +6,8 @@
 package user
 
 import (
+	"food_ordering_backend/common"
+	"food_ordering_backend/services"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
@@ -15,19 +17,39 @@ import (
 func InitAPI(db *gorm.DB) *API {
 	repository := ProvideRepository(db)
 	jwtService := ProvideJWTService()
-	service := ProvideService(repository, jwtService)
-	api := ProvideAPI(service)
+	mailer := services.ProvideMailer()
+	transactor := common.ProvideTransactor(db)
+	limiterStore := services.ProvideLimiterStore()
+	service := ProvideService(repository, jwtService, mailer, transactor, limiterStore)
+	addressService := ProvideAddressService(repository, transactor)
+	api := ProvideAPI(service, addressService, limiterStore)
 	return api
 }
 
 func InitAuthMiddleware(db *gorm.DB) AuthMiddlewareFunc {
 	repository := ProvideRepository(db)
 	jwtService := ProvideJWTService()
-	service := ProvideService(repository, jwtService)
+	mailer := services.ProvideMailer()
+	transactor := common.ProvideTransactor(db)
+	limiterStore := services.ProvideLimiterStore()
+	service := ProvideService(repository, jwtService, mailer, transactor, limiterStore)
 	authMiddlewareFunc := ProvideAuthMiddleware(service)
 	return authMiddlewareFunc
 }
 
+func InitService(db *gorm.DB) *Service {
+	repository := ProvideRepository(db)
+	jwtService := ProvideJWTService()
+	mailer := services.ProvideMailer()
+	transactor := common.ProvideTransactor(db)
+	limiterStore := services.ProvideLimiterStore()
+	service := ProvideService(repository, jwtService, mailer, transactor, limiterStore)
+	return service
+}
+
 // wire.go:
 
-var set = wire.NewSet(ProvideService, ProvideRepository, ProvideJWTService)
+// AddressServiceSet provides AddressService. It requires *common.Transactor.
+var AddressServiceSet = wire.NewSet(ProvideAddressService, ProvideRepository)
+
+var set = wire.NewSet(ProvideService, AddressServiceSet, ProvideJWTService, services.ProvideMailer, services.ProvideLimiterStore, common.ProvideTransactor)
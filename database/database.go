@@ -76,7 +76,9 @@ func initDB(connStr string) error {
 		return err
 	}
 
+	runMigrations(db, preMigrations)
 	autoMigrate(db)
+	runMigrations(db, migrations)
 
 	database = db
 	return nil
@@ -86,10 +88,21 @@ func autoMigrate(db *gorm.DB) {
 	models := []interface{}{
 		&category.Category{},
 		&dish.Dish{},
+		&dish.OptionGroup{},
+		&dish.Option{},
+		&dish.Image{},
 		&user.User{},
+		&user.UserRole{},
 		&user.Session{},
+		&user.PasswordResetToken{},
+		&user.EmailVerificationToken{},
+		&user.Address{},
 		&order.Order{},
 		&order.Item{},
+		&order.ItemOption{},
+		&order.StatusChange{},
+		&order.Adjustment{},
+		&order.IdempotencyKey{},
 	}
 
 	for _, model := range models {
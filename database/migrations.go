@@ -0,0 +1,216 @@
+package database
+
+import (
+	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/order"
+	"food_ordering_backend/controllers/user"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// migration changes data in a way that AutoMigrate can't handle by itself.
+// Migrations run on every start after AutoMigrate, so each of them must be
+// idempotent and should check whether it's still needed.
+type migration func(tx *gorm.DB) error
+
+var migrations = []migration{
+	migrateMoneyColumns,
+	migrateOrderItemsSnapshot,
+	deleteSessionsWithoutExpiry,
+	migrateSessionsToRefreshTokens,
+	migrateAdminsToRoles,
+	createDishSearchIndex,
+	migrateDishImagesToGallery,
+}
+
+// preMigrations run before AutoMigrate, so that they can see the schema
+// it's going to change. Tables might not exist yet.
+var preMigrations = []migration{
+	addEmailVerifiedColumn,
+	addDishAvailableColumn,
+}
+
+func runMigrations(db *gorm.DB, migrations []migration) {
+	for _, m := range migrations {
+		if err := db.Transaction(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// migrateMoneyColumns moves legacy float prices and totals into
+// integer minor units columns created for common.Money.
+func migrateMoneyColumns(tx *gorm.DB) error {
+	columns := []struct {
+		model  interface{}
+		table  string
+		legacy string
+		amount string
+	}{
+		{&dish.Dish{}, "dishes", "price", "price_amount"},
+		{&order.Order{}, "orders", "total", "total_amount"},
+	}
+
+	for _, col := range columns {
+		if !tx.Migrator().HasColumn(col.model, col.legacy) {
+			continue
+		}
+
+		err := tx.Exec("UPDATE " + col.table + " SET " + col.amount + " = ROUND(" + col.legacy + " * 100) WHERE " + col.legacy + " IS NOT NULL").Error
+
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().DropColumn(col.model, col.legacy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateOrderItemsSnapshot fills snapshot of dish data for order items
+// created before snapshots were introduced and makes order items keep
+// existing when the dish is deleted.
+func migrateOrderItemsSnapshot(tx *gorm.DB) error {
+	var onDelete string
+	err := tx.Raw("SELECT confdeltype FROM pg_constraint WHERE conname = ?", "fk_order_items_dish").Scan(&onDelete).Error
+
+	if err != nil {
+		return err
+	}
+
+	// "n" stands for ON DELETE SET NULL
+	if onDelete != "" && onDelete != "n" {
+		if err := tx.Migrator().DropConstraint(&order.Item{}, "Dish"); err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().CreateConstraint(&order.Item{}, "Dish"); err != nil {
+			return err
+		}
+	}
+
+	return tx.Exec(`
+		UPDATE order_items
+		SET unit_price_amount = dishes.price_amount,
+			unit_price_currency = dishes.price_currency,
+			dish_title = dishes.title,
+			category_title = categories.title
+		FROM dishes JOIN categories ON categories.id = dishes.category_id
+		WHERE order_items.dish_id = dishes.id AND order_items.dish_title = ''
+	`).Error
+}
+
+// deleteSessionsWithoutExpiry deletes sessions created before sessions had
+// expiration time. Their tokens don't have "kid" header and "exp" claim,
+// so they can't be used anyway.
+func deleteSessionsWithoutExpiry(tx *gorm.DB) error {
+	return tx.Where("expires_at IS NULL").Delete(&user.Session{}).Error
+}
+
+// migrateSessionsToRefreshTokens deletes sessions created before sessions had
+// refresh tokens, since they can't be refreshed, and replaces token with id
+// as the primary key of sessions table.
+func migrateSessionsToRefreshTokens(tx *gorm.DB) error {
+	if err := tx.Where("refresh_token_hash IS NULL").Delete(&user.Session{}).Error; err != nil {
+		return err
+	}
+
+	var primaryKey string
+	err := tx.Raw(`
+		SELECT a.attname FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = 'sessions'::regclass AND i.indisprimary
+	`).Scan(&primaryKey).Error
+
+	if err != nil {
+		return err
+	}
+
+	if primaryKey != "token" {
+		return nil
+	}
+
+	return tx.Exec("ALTER TABLE sessions DROP CONSTRAINT sessions_pkey, ADD PRIMARY KEY (id)").Error
+}
+
+// migrateAdminsToRoles assigns admin role to users that had is_admin flag
+// before roles were introduced and drops the flag.
+func migrateAdminsToRoles(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&user.User{}, "is_admin") {
+		return nil
+	}
+
+	err := tx.Exec(`
+		INSERT INTO user_roles (user_id, role, created_at)
+		SELECT id, ?, NOW() FROM users WHERE is_admin
+		ON CONFLICT DO NOTHING
+	`, user.RoleAdmin).Error
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Migrator().DropColumn(&user.User{}, "is_admin")
+}
+
+// addEmailVerifiedColumn adds email_verified column to users table, treating users
+// that signed up before email verification was introduced as verified.
+func addEmailVerifiedColumn(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(&user.User{}) || tx.Migrator().HasColumn(&user.User{}, "email_verified") {
+		return nil
+	}
+
+	// default true fills existing rows, new users get the default from the model
+	if err := tx.Exec("ALTER TABLE users ADD COLUMN email_verified boolean NOT NULL DEFAULT true").Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT false").Error
+}
+
+// addDishAvailableColumn adds available column to dishes table, making dishes
+// created before availability was introduced available.
+func addDishAvailableColumn(tx *gorm.DB) error {
+	if !tx.Migrator().HasTable(&dish.Dish{}) || tx.Migrator().HasColumn(&dish.Dish{}, "available") {
+		return nil
+	}
+
+	return tx.Exec("ALTER TABLE dishes ADD COLUMN available boolean NOT NULL DEFAULT true").Error
+}
+
+// createDishSearchIndex creates an index for full-text search of dishes. The expression
+// the index is built with is kept in its comment, so that the index is rebuilt once
+// dish.SearchDocument changes.
+func createDishSearchIndex(tx *gorm.DB) error {
+	var document string
+	err := tx.Raw("SELECT COALESCE(obj_description(to_regclass('idx_dishes_search'), 'pg_class'), '')").Scan(&document).Error
+
+	if err != nil || document == dish.SearchDocument {
+		return err
+	}
+
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_dishes_search").Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("CREATE INDEX idx_dishes_search ON dishes USING GIN ((" + dish.SearchDocument + "))").Error; err != nil {
+		return err
+	}
+
+	// utility statements don't accept parameters, so the comment is quoted by hand
+	comment := "'" + strings.ReplaceAll(dish.SearchDocument, "'", "''") + "'"
+	return tx.Exec("COMMENT ON INDEX idx_dishes_search IS " + comment).Error
+}
+
+// migrateDishImagesToGallery adds images of dishes created before galleries were
+// introduced to their galleries as the primary images.
+func migrateDishImagesToGallery(tx *gorm.DB) error {
+	return tx.Exec(`
+		INSERT INTO dish_images (dish_id, name, position)
+		SELECT id, image, 0 FROM dishes
+		WHERE image IS NOT NULL AND NOT EXISTS (SELECT 1 FROM dish_images WHERE dish_images.dish_id = dishes.id)
+	`).Error
+}
@@ -60,6 +60,11 @@ var doc = `{
                 }
             },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "consumes": [
                     "application/json"
                 ],
@@ -69,7 +74,7 @@ var doc = `{
                 "tags": [
                     "category"
                 ],
-                "summary": "Create new category. Requires admin rights.",
+                "summary": "Create new category. Requires menu:write permission.",
                 "operationId": "category-create",
                 "parameters": [
                     {
@@ -136,6 +141,11 @@ var doc = `{
                 }
             },
             "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "consumes": [
                     "application/json"
                 ],
@@ -145,7 +155,7 @@ var doc = `{
                 "tags": [
                     "category"
                 ],
-                "summary": "Replace category. Requires admin rights.",
+                "summary": "Replace category. Requires menu:write permission.",
                 "operationId": "category-update",
                 "parameters": [
                     {
@@ -187,13 +197,18 @@ var doc = `{
                 }
             },
             "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "category"
                 ],
-                "summary": "Delete category by id. Requires admin rights.",
+                "summary": "Delete category by id. Requires menu:write permission.",
                 "operationId": "category-delete",
                 "parameters": [
                     {
@@ -228,6 +243,11 @@ var doc = `{
         },
         "/categories/:id/upload": {
             "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -237,7 +257,7 @@ var doc = `{
                 "tags": [
                     "category"
                 ],
-                "summary": "Upload image for category. Requires admin rights.",
+                "summary": "Upload image for category. Requires menu:write permission.",
                 "operationId": "category-upload",
                 "parameters": [
                     {
@@ -285,6 +305,7 @@ var doc = `{
         },
         "/dishes": {
             "get": {
+                "description": "Returns an array of dishes. If page or limit is provided, dishes are paginated\nand returned in an object along with pagination info.\nSearch matches dishes whose title or description contains all words of q, the last word can be incomplete.",
                 "produces": [
                     "application/json"
                 ],
@@ -295,31 +316,126 @@ var doc = `{
                 "operationId": "dish-all",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "filter dishes by category id",
+                        "type": "string",
+                        "description": "search query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "category ids, can be repeated or comma-separated",
                         "name": "cid",
                         "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "minimal price in minor units",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximal price in minor units",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true for dishes that can be ordered, false for sold out ones",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "allergens dishes must not contain, can be repeated or comma-separated",
+                        "name": "exclude_allergens",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "dietary tags dishes must have, can be repeated or comma-separated",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximal spicy level from 0 to 3",
+                        "name": "max_spicy_level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximal calories, dishes with unknown calories are excluded",
+                        "name": "max_calories",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "title",
+                            "price"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort direction",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "0-based page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "amount of entries per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dish.DTO"
-                            }
+                            "$ref": "#/definitions/dish.DTOsWithPagination"
                         }
                     },
-                    "403": {
+                    "400": {
                         "description": ""
                     },
-                    "404": {
+                    "500": {
                         "description": ""
                     }
                 }
             },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "consumes": [
                     "application/json"
                 ],
@@ -329,7 +445,7 @@ var doc = `{
                 "tags": [
                     "dish"
                 ],
-                "summary": "Create new dish. Requires admin rights.",
+                "summary": "Create new dish. Requires menu:write permission.",
                 "operationId": "dish-create",
                 "parameters": [
                     {
@@ -393,6 +509,12 @@ var doc = `{
                 }
             },
             "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Omitted availability makes the dish available, omitted stock makes it unlimited.\nOmitted description, ingredients, allergens, dietary tags, spicy level and calories are cleared.",
                 "consumes": [
                     "application/json"
                 ],
@@ -402,7 +524,7 @@ var doc = `{
                 "tags": [
                     "dish"
                 ],
-                "summary": "Replace dish. Requires admin rights.",
+                "summary": "Replace dish. Requires menu:write permission.",
                 "operationId": "dish-update",
                 "parameters": [
                     {
@@ -447,13 +569,18 @@ var doc = `{
                 }
             },
             "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "dish"
                 ],
-                "summary": "Delete dish by id. Requires admin rights.",
+                "summary": "Delete dish by id. Requires menu:write permission.",
                 "operationId": "dish-delete",
                 "parameters": [
                     {
@@ -474,31 +601,100 @@ var doc = `{
                     "401": {
                         "description": ""
                     },
+                    "404": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/dishes/:id/images": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "ids must list every image of the gallery exactly once, the first one becomes the primary image.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dish"
+                ],
+                "summary": "Reorder the dish gallery. Requires menu:write permission.",
+                "operationId": "dish-images-reorder",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Image ids in the new order",
+                        "name": "dto",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dish.ImageOrderDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dish.ImageDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
                     "403": {
                         "description": ""
                     },
                     "404": {
                         "description": ""
                     },
+                    "422": {
+                        "description": ""
+                    },
                     "500": {
                         "description": ""
                     }
                 }
-            }
-        },
-        "/dishes/:id/upload": {
-            "patch": {
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The first image of the gallery is the primary image of the dish.",
                 "consumes": [
                     "multipart/form-data"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
                     "dish"
                 ],
-                "summary": "Upload image for dish. Requires admin rights.",
-                "operationId": "dish-upload",
+                "summary": "Add an image to the end of the dish gallery. Requires menu:write permission.",
+                "operationId": "dish-images-add",
                 "parameters": [
                     {
                         "type": "integer",
@@ -516,10 +712,10 @@ var doc = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Link to uploaded image",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/dish.ImageDTO"
                         }
                     },
                     "400": {
@@ -528,6 +724,9 @@ var doc = `{
                     "401": {
                         "description": ""
                     },
+                    "403": {
+                        "description": ""
+                    },
                     "404": {
                         "description": ""
                     },
@@ -543,37 +742,41 @@ var doc = `{
                 }
             }
         },
-        "/orders": {
-            "get": {
-                "description": "If requester is admin, it returns all orders. Otherwise, it returns orders only for that user.",
-                "produces": [
-                    "application/json"
+        "/dishes/:id/images/:iid": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "The next image becomes the primary one if the deleted image was primary.",
                 "tags": [
-                    "order"
+                    "dish"
                 ],
-                "summary": "Get all orders. Requires auth.",
-                "operationId": "order-all",
+                "summary": "Delete an image from the dish gallery. Requires menu:write permission.",
+                "operationId": "dish-images-delete",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "0-based page number",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "amount of entries per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Image id",
+                        "name": "iid",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/order.DTOsWithPagination"
-                        }
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": ""
                     },
                     "401": {
                         "description": ""
@@ -588,8 +791,55 @@ var doc = `{
                         "description": ""
                     }
                 }
+            }
+        },
+        "/dishes/:id/options": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "dish"
+                ],
+                "summary": "Get option groups of the dish along with their options",
+                "operationId": "dish-options-get-all",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dish.OptionGroupDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
             },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Customers choose from min_select to max_select options of the group for every ordered dish.\nPrice delta of an option is added to the price of the dish, it defaults to the currency of the dish.",
                 "consumes": [
                     "application/json"
                 ],
@@ -597,18 +847,25 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "order"
+                    "dish"
                 ],
-                "summary": "Create new order. Requires auth.",
-                "operationId": "order-create",
+                "summary": "Add an option group to the dish. Requires menu:write permission.",
+                "operationId": "dish-options-create",
                 "parameters": [
                     {
-                        "description": "Create order DTO",
+                        "type": "integer",
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Option group",
                         "name": "dto",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/order.CreateDTO"
+                            "$ref": "#/definitions/dish.OptionGroupDTO"
                         }
                     }
                 ],
@@ -616,15 +873,21 @@ var doc = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/order.ResponseDTO"
+                            "$ref": "#/definitions/dish.OptionGroupDTO"
                         }
                     },
+                    "400": {
+                        "description": ""
+                    },
                     "401": {
                         "description": ""
                     },
                     "403": {
                         "description": ""
                     },
+                    "404": {
+                        "description": ""
+                    },
                     "422": {
                         "description": ""
                     },
@@ -634,8 +897,14 @@ var doc = `{
                 }
             }
         },
-        "/orders/:id": {
+        "/dishes/:id/options/:gid": {
             "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Options that aren't present in the group are deleted, options without id are created.\nOrders keep their copies of chosen options.",
                 "consumes": [
                     "application/json"
                 ],
@@ -643,35 +912,45 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "order"
+                    "dish"
                 ],
-                "summary": "Replace order. Requires admin rights.",
-                "operationId": "order-update",
+                "summary": "Replace an option group of the dish. Requires menu:write permission.",
+                "operationId": "dish-options-update",
                 "parameters": [
                     {
-                        "description": "Order update DTO",
-                        "name": "dto",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/order.UpdateDTO"
-                        }
+                        "type": "integer",
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "integer",
-                        "description": "Order id",
-                        "name": "id",
+                        "description": "Option group id",
+                        "name": "gid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Option group",
+                        "name": "dto",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dish.OptionGroupDTO"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/order.ResponseDTO"
+                            "$ref": "#/definitions/dish.OptionGroupDTO"
                         }
                     },
+                    "400": {
+                        "description": ""
+                    },
                     "401": {
                         "description": ""
                     },
@@ -681,23 +960,39 @@ var doc = `{
                     "404": {
                         "description": ""
                     },
+                    "422": {
+                        "description": ""
+                    },
                     "500": {
                         "description": ""
                     }
                 }
             },
-            "patch": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Orders keep their copies of chosen options.",
                 "tags": [
-                    "order"
+                    "dish"
                 ],
-                "summary": "Patch order. Requires admin rights.",
-                "operationId": "order-patch",
+                "summary": "Delete an option group of the dish along with its options. Requires menu:write permission.",
+                "operationId": "dish-options-delete",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "New order status",
-                        "name": "status",
-                        "in": "query",
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Option group id",
+                        "name": "gid",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -705,6 +1000,9 @@ var doc = `{
                     "204": {
                         "description": ""
                     },
+                    "400": {
+                        "description": ""
+                    },
                     "401": {
                         "description": ""
                     },
@@ -720,55 +1018,61 @@ var doc = `{
                 }
             }
         },
-        "/users": {
-            "get": {
+        "/dishes/:id/upload": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds the image to the gallery if it's empty, the rest of the gallery is kept.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "user"
+                    "dish"
                 ],
-                "summary": "Get all users. Requires admin rights.",
-                "operationId": "user-get-all",
+                "summary": "Replace the primary image of dish. Requires menu:write permission.",
+                "operationId": "dish-upload",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "0-based page number",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Dish id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "amount of entries per page",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "file",
+                        "description": "Dish image",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Link to uploaded image",
                         "schema": {
-                            "$ref": "#/definitions/user.DTOsWithPagination"
+                            "type": "string"
                         }
                     },
+                    "400": {
+                        "description": ""
+                    },
                     "401": {
                         "description": ""
-                    }
-                }
-            }
-        },
-        "/users/logout": {
-            "get": {
-                "tags": [
-                    "user"
-                ],
-                "summary": "Logout. Requires auth.",
-                "operationId": "user-logout",
-                "responses": {
-                    "200": {
+                    },
+                    "404": {
                         "description": ""
                     },
-                    "401": {
+                    "413": {
+                        "description": ""
+                    },
+                    "415": {
                         "description": ""
                     },
                     "500": {
@@ -777,54 +1081,162 @@ var doc = `{
                 }
             }
         },
-        "/users/me": {
+        "/kitchen/ws": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "After connection is established, server sends {\"type\": \"queue\", \"orders\": [...]} message\nwith all created and in progress orders, and sends it again every time any order changes.\nClient can change order status by sending {\"type\": \"set_status\", \"order_id\": 1, \"status\": 1}.\nIf the command fails, server responds with {\"type\": \"error\", \"order_id\": 1, \"error\": \"...\"}.\nSession and permissions are checked again before every message, server sends an error\nand closes the connection once the session is revoked or permissions are lost.",
                 "tags": [
-                    "user"
+                    "kitchen"
                 ],
-                "summary": "Get info about current user. Requires auth.",
-                "operationId": "user-info",
+                "summary": "Open kitchen display WebSocket. Requires orders:read_all and orders:update_status permissions.",
+                "operationId": "kitchen-ws",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "101": {
+                        "description": "Switching Protocols",
                         "schema": {
-                            "$ref": "#/definitions/user.ResponseDTO"
+                            "$ref": "#/definitions/kitchen.QueueDTO"
                         }
                     },
                     "401": {
                         "description": ""
+                    },
+                    "403": {
+                        "description": ""
                     }
                 }
             }
         },
-        "/users/signin": {
-            "post": {
-                "consumes": [
+        "/orders": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "If requester has orders:read_all permission, it returns all orders. Otherwise, it returns orders only for that user.\nDates can be either RFC 3339 timestamps or YYYY-MM-DD dates, both bounds are inclusive.",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "order"
                 ],
-                "summary": "Sign in",
-                "operationId": "user-login",
+                "summary": "Get all orders. Requires auth.",
+                "operationId": "order-all",
                 "parameters": [
                     {
-                        "description": "User login data",
-                        "name": "auth",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/user.AuthDTO"
-                        }
+                        "type": "integer",
+                        "description": "0-based page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "amount of entries per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "order statuses, can be repeated or comma-separated",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "lower bound of creation date",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "upper bound of creation date",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "lower bound of last update date",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "upper bound of last update date",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "part of customer's email, requires orders:read_all permission",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "id of a dish that order contains",
+                        "name": "dish_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "minimal total in minor units",
+                        "name": "min_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximal total in minor units",
+                        "name": "max_total",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "created_at",
+                            "updated_at",
+                            "status",
+                            "total"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort direction",
+                        "name": "order",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.ResponseDTO"
+                            "$ref": "#/definitions/order.DTOsWithPagination"
                         }
                     },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
                     "404": {
                         "description": ""
                     },
@@ -832,10 +1244,14 @@ var doc = `{
                         "description": ""
                     }
                 }
-            }
-        },
-        "/users/signup": {
+            },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "If Idempotency-Key header is provided, repeated requests with the same key and body\nmade within a retention period return the original response instead of creating another order.\nSuch responses have Idempotent-Replayed header set to true. While the original request is being\nprocessed, repeated ones get 409. If it doesn't complete within a lease period, the key is taken over\nby the next repeated request.\nResponds with 403 if verified email is required and the user hasn't verified it.\nThe order is delivered to the address with address_id or, if it's omitted, to the default address\nof the user. A copy of the address is saved with the order. Responds with 422 if the user has no addresses.\nResponds with 409 if any of dishes is sold out or doesn't have enough portions left.\nChosen options of items must satisfy option groups of dishes, otherwise responds with 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -843,59 +1259,1402 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "order"
                 ],
-                "summary": "Create new user",
-                "operationId": "user-create",
+                "summary": "Create new order. Requires auth.",
+                "operationId": "order-create",
                 "parameters": [
                     {
-                        "description": "User info",
-                        "name": "auth",
+                        "description": "Create order DTO",
+                        "name": "dto",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/user.AuthDTO"
+                            "$ref": "#/definitions/order.CreateDTO"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the request, up to 255 characters",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/user.ResponseDTO"
+                            "$ref": "#/definitions/order.ResponseDTO"
                         }
                     },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
                     "409": {
                         "description": ""
                     },
                     "422": {
                         "description": ""
+                    },
+                    "500": {
+                        "description": ""
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "category.DTO": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "image": {
-                    "type": "string"
-                },
-                "removable": {
-                    "type": "boolean"
-                },
-                "title": {
-                    "type": "string"
-                }
-            }
         },
-        "common.PaginationDTO": {
-            "type": "object",
-            "properties": {
+        "/orders/:id": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Total is calculated from items and adjustments. To keep existing adjustment, provide only its id. Adjustments that aren't present in the list will be removed. Portions of previous items return to stock and new items take it, unless the order is canceled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "order"
+                ],
+                "summary": "Replace order. Requires orders:write permission.",
+                "operationId": "order-update",
+                "parameters": [
+                    {
+                        "description": "Order update DTO",
+                        "name": "dto",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/order.UpdateDTO"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Order status can only be changed according to the status transitions table: Created -\u003e In Progress | Canceled, In Progress -\u003e Done | Canceled.",
+                "tags": [
+                    "order"
+                ],
+                "summary": "Patch order. Requires orders:update_status permission.",
+                "operationId": "order-patch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "New order status",
+                        "name": "status",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/orders/:id/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Order can be canceled only by its owner, while it hasn't been taken into work and within a grace window after creation.\nItems of the order return to stock of dishes.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "order"
+                ],
+                "summary": "Cancel own order. Requires auth.",
+                "operationId": "order-cancel",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/orders/:id/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Users with orders:read_all permission can access history of any order. Other users can access history only of their own orders.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "order"
+                ],
+                "summary": "Get status history of the order. Requires auth.",
+                "operationId": "order-history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/order.StatusChangeDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/orders/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Server-Sent Events stream. Event name is one of \"created\", \"updated\" or \"status_changed\", data is the order in JSON.\nUsers with orders:read_all permission receive events of all orders. Other users receive events only of their own orders.\nHeartbeat comments are sent periodically to keep connection open.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "order"
+                ],
+                "summary": "Subscribe to order events. Requires auth.",
+                "operationId": "order-stream",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/order.ResponseDTO"
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get all users. Requires users:read permission.",
+                "operationId": "user-get-all",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "0-based page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "amount of entries per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "part of user's email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "email",
+                            "created_at"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort direction",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.DTOsWithPagination"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/logout": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Logout. Requires auth.",
+                "operationId": "user-logout",
+                "responses": {
+                    "200": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get info about current user. Requires auth.",
+                "operationId": "user-info",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Replace name and phone of current user. Requires auth.",
+                "operationId": "user-update-profile",
+                "parameters": [
+                    {
+                        "description": "Profile of the user",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.ProfileDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me/addresses": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get delivery addresses of current user, the default one first. Requires auth.",
+                "operationId": "user-addresses-get-all",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/user.AddressResponseDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The first address becomes default. If the new address is default, the previous default one stops being so.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Add a delivery address to current user. Requires auth.",
+                "operationId": "user-addresses-create",
+                "parameters": [
+                    {
+                        "description": "Address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.AddressDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/user.AddressResponseDTO"
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me/addresses/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The default address stays default even if is_default is false, another address should be made default instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Replace a delivery address of current user. Requires auth.",
+                "operationId": "user-addresses-update",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Address",
+                        "name": "address",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.AddressDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.AddressResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "If the address was default, the oldest of remaining addresses becomes default.\nOrders keep their copy of the address.",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Delete a delivery address of current user. Requires auth.",
+                "operationId": "user-addresses-delete",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Address ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "All other sessions of the user are logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Change password of current user. Requires auth.",
+                "operationId": "user-change-password",
+                "parameters": [
+                    {
+                        "description": "Current and new passwords",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.ChangePasswordDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get active sessions of current user. Requires auth.",
+                "operationId": "user-sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/user.SessionDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Log out everywhere. Deletes all sessions of current user, including the current one. Requires auth.",
+                "operationId": "user-session-delete-all",
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Log out a session of current user. Requires auth.",
+                "operationId": "user-session-delete",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/me/verify": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Links sent before stop working.",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Send a new email verification link to current user. Requires auth.",
+                "operationId": "user-send-email-verification",
+                "responses": {
+                    "202": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "429": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/password/forgot": {
+            "post": {
+                "description": "Sends an email with a password reset link to the user. The link contains a single-use token\nthat should be sent to /users/password/reset. Responds with 202 even if there is no user\nwith provided email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Request password reset",
+                "operationId": "user-forgot-password",
+                "parameters": [
+                    {
+                        "description": "User email",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.ForgotPasswordDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "429": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/password/reset": {
+            "post": {
+                "description": "Sets a new password using a token from password reset email. All sessions of the user are logged out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Reset password",
+                "operationId": "user-reset-password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.ResetPasswordDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/refresh": {
+            "post": {
+                "description": "Issues new access and refresh tokens for the session. Refresh token is taken from the body or,\nif the body is empty, from refresh cookie set by /users/signin. In the latter case new tokens\nare set as cookies too and user info is returned instead of tokens.\nEvery refresh token can be used only once. Using it again ends the session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Refresh access token",
+                "operationId": "user-refresh",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "refresh",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/user.RefreshDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.TokenDTO"
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get all roles with permissions they grant. Requires users:manage permission.",
+                "operationId": "user-roles-get-all",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/user.RoleDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/signin": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Sign in",
+                "operationId": "user-login",
+                "parameters": [
+                    {
+                        "description": "User login data",
+                        "name": "auth",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.AuthDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "429": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/signup": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Create new user",
+                "operationId": "user-create",
+                "parameters": [
+                    {
+                        "description": "User info",
+                        "name": "auth",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.AuthDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "429": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/token": {
+            "post": {
+                "description": "Creates a session like /users/signin does, but returns its tokens in the body instead of setting cookies.\nAccess token should be sent in \"Authorization: Bearer \u003ctoken\u003e\" header. Once it expires,\na new one can be obtained from /users/refresh with refresh token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get access token",
+                "operationId": "user-token",
+                "parameters": [
+                    {
+                        "description": "User login data",
+                        "name": "auth",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.AuthDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.TokenDTO"
+                        }
+                    },
+                    "403": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "429": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/verify": {
+            "get": {
+                "description": "Confirms that the user owns their email using a token from the link sent on sign up.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Verify email",
+                "operationId": "user-verify-email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email verification token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get user by id. Requires users:read permission.",
+                "operationId": "user-get-by-id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Orders of the user are kept without a customer. Admins can't delete themselves\nand the last enabled admin can't be deleted.",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Delete user by id. Requires users:manage permission.",
+                "operationId": "user-delete",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disabled users can't sign in and are logged out everywhere. Admins can't disable themselves.\nThe last enabled admin can't lose admin role or be disabled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Grant or revoke admin role and disable or enable user. Requires users:manage permission.",
+                "operationId": "user-patch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.PatchDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/users/{id}/roles": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Admin role can't be removed from the last admin.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Replace roles of a user. Requires users:manage permission.",
+                "operationId": "user-roles-set",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New roles of the user",
+                        "name": "roles",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.RolesDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.ResponseDTO"
+                        }
+                    },
+                    "400": {
+                        "description": ""
+                    },
+                    "401": {
+                        "description": ""
+                    },
+                    "404": {
+                        "description": ""
+                    },
+                    "409": {
+                        "description": ""
+                    },
+                    "422": {
+                        "description": ""
+                    },
+                    "500": {
+                        "description": ""
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "category.DTO": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "removable": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "common.Money": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "common.PaginationDTO": {
+            "type": "object",
+            "properties": {
                 "limit": {
                     "type": "integer"
                 },
@@ -909,36 +2668,288 @@ var doc = `{
         },
         "dish.DTO": {
             "type": "object",
+            "required": [
+                "ingredients"
+            ],
             "properties": {
+                "allergens": {
+                    "description": "Allergens are names of the 14 EU allergens the dish contains.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "available": {
+                    "description": "Available defaults to true if it's omitted.",
+                    "type": "boolean"
+                },
+                "calories": {
+                    "description": "Calories is energy value of a portion in kcal, null means unknown.",
+                    "type": "integer"
+                },
                 "category": {
                     "$ref": "#/definitions/category.DTO"
                 },
                 "category_id": {
                     "type": "integer"
                 },
+                "description": {
+                    "type": "string"
+                },
+                "dietary_tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "id": {
                     "type": "integer"
                 },
                 "image": {
                     "type": "string"
                 },
+                "images": {
+                    "description": "Images are the gallery of the dish, the first one is the primary image.\nThey are ignored in requests.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dish.ImageDTO"
+                    }
+                },
+                "ingredients": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "price": {
-                    "type": "number"
+                    "$ref": "#/definitions/common.Money"
                 },
                 "removable": {
                     "type": "boolean"
                 },
+                "sold_out": {
+                    "description": "SoldOut is true if the dish isn't available or there are no portions left.\nIt's ignored in requests.",
+                    "type": "boolean"
+                },
+                "spicy_level": {
+                    "type": "integer"
+                },
+                "stock": {
+                    "description": "Stock is the amount of portions left for today, null means unlimited.",
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dish.DTOsWithPagination": {
+            "type": "object",
+            "properties": {
+                "dishes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dish.DTO"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/common.PaginationDTO"
+                }
+            }
+        },
+        "dish.ImageDTO": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dish.ImageOrderDTO": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "dish.OptionDTO": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "price_delta": {
+                    "$ref": "#/definitions/common.Money"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "dish.OptionGroupDTO": {
+            "type": "object",
+            "required": [
+                "max_select",
+                "options",
+                "title"
+            ],
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "max_select": {
+                    "type": "integer"
+                },
+                "min_select": {
+                    "type": "integer"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dish.OptionDTO"
+                    }
+                },
                 "title": {
                     "type": "string"
                 }
             }
         },
+        "kitchen.ItemDTO": {
+            "type": "object",
+            "properties": {
+                "category_title": {
+                    "type": "string"
+                },
+                "dish_id": {
+                    "type": "integer"
+                },
+                "dish_title": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options are titles of chosen options, e.g. \"Large\" or \"No onions\".",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "kitchen.OrderDTO": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/kitchen.ItemDTO"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "kitchen.QueueDTO": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/kitchen.OrderDTO"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "order.AdjustmentCreateDTO": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "value": {
+                    "$ref": "#/definitions/common.Money"
+                }
+            }
+        },
+        "order.AdjustmentResponseDTO": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "$ref": "#/definitions/user.ResponseDTO"
+                },
+                "created_by_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "revoked_by": {
+                    "$ref": "#/definitions/user.ResponseDTO"
+                },
+                "revoked_by_id": {
+                    "type": "integer"
+                },
+                "value": {
+                    "$ref": "#/definitions/common.Money"
+                }
+            }
+        },
         "order.CreateDTO": {
             "type": "object",
             "required": [
                 "items"
             ],
             "properties": {
+                "address_id": {
+                    "description": "AddressID is an id of the address of the user the order is delivered to.\nIf it's omitted, the default address of the user is used.",
+                    "type": "integer"
+                },
                 "items": {
                     "type": "array",
                     "items": {
@@ -947,61 +2958,141 @@ var doc = `{
                 }
             }
         },
-        "order.DTOsWithPagination": {
+        "order.DTOsWithPagination": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.ResponseDTO"
+                    }
+                },
+                "pagination": {
+                    "$ref": "#/definitions/common.PaginationDTO"
+                }
+            }
+        },
+        "order.DeliveryAddressDTO": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "line1": {
+                    "type": "string"
+                },
+                "line2": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                }
+            }
+        },
+        "order.ItemCreateDTO": {
             "type": "object",
+            "required": [
+                "quantity"
+            ],
             "properties": {
-                "orders": {
+                "id": {
+                    "type": "integer"
+                },
+                "item_id": {
+                    "description": "ItemID is an id of an existing item of the order. It's used only when the order\nis replaced, such item keeps its snapshot of the dish and chosen options\nand only its quantity is changed. ID and Options are ignored for it.",
+                    "type": "integer"
+                },
+                "options": {
+                    "description": "Options are ids of chosen options of the dish. Each option group\nof the dish limits how many of its options can be chosen.",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/order.ResponseDTO"
+                        "type": "integer"
                     }
                 },
-                "pagination": {
-                    "$ref": "#/definitions/common.PaginationDTO"
+                "quantity": {
+                    "type": "integer"
                 }
             }
         },
-        "order.ItemCreateDTO": {
+        "order.ItemOptionResponseDTO": {
             "type": "object",
-            "required": [
-                "id",
-                "quantity"
-            ],
             "properties": {
-                "id": {
-                    "type": "integer"
+                "group_title": {
+                    "type": "string"
                 },
-                "quantity": {
+                "option_id": {
                     "type": "integer"
+                },
+                "price_delta": {
+                    "$ref": "#/definitions/common.Money"
+                },
+                "title": {
+                    "type": "string"
                 }
             }
         },
         "order.ItemResponseDTO": {
             "type": "object",
             "properties": {
+                "cost": {
+                    "description": "Cost includes price deltas of chosen options.",
+                    "$ref": "#/definitions/common.Money"
+                },
                 "dish": {
+                    "description": "Dish contains title, price and category title of the dish\nat the moment the order was created.",
                     "$ref": "#/definitions/dish.DTO"
                 },
                 "dish_id": {
+                    "description": "DishID is 0 if the dish has been deleted.",
                     "type": "integer"
                 },
                 "id": {
                     "type": "integer"
                 },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.ItemOptionResponseDTO"
+                    }
+                },
                 "order_id": {
                     "type": "integer"
                 },
                 "quantity": {
                     "type": "integer"
+                },
+                "unit_price": {
+                    "description": "UnitPrice is the price of the dish without options.",
+                    "$ref": "#/definitions/common.Money"
                 }
             }
         },
         "order.ResponseDTO": {
             "type": "object",
             "properties": {
+                "adjustments": {
+                    "description": "Adjustments explain the difference between the total and the cost of items.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.AdjustmentResponseDTO"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "delivery_address": {
+                    "description": "DeliveryAddress is empty for orders created before addresses were introduced.",
+                    "$ref": "#/definitions/order.DeliveryAddressDTO"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -1011,11 +3102,15 @@ var doc = `{
                         "$ref": "#/definitions/order.ItemResponseDTO"
                     }
                 },
+                "last_transition": {
+                    "description": "LastTransition is the latest status change or null if status has never been changed.",
+                    "$ref": "#/definitions/order.StatusChangeDTO"
+                },
                 "status": {
                     "type": "integer"
                 },
                 "total": {
-                    "type": "number"
+                    "$ref": "#/definitions/common.Money"
                 },
                 "updated_at": {
                     "type": "string"
@@ -1028,15 +3123,46 @@ var doc = `{
                 }
             }
         },
+        "order.StatusChangeDTO": {
+            "type": "object",
+            "properties": {
+                "changed_by": {
+                    "$ref": "#/definitions/user.ResponseDTO"
+                },
+                "changed_by_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
         "order.UpdateDTO": {
             "type": "object",
             "required": [
                 "items",
                 "status",
-                "total",
                 "user_id"
             ],
             "properties": {
+                "adjustments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/order.AdjustmentCreateDTO"
+                    }
+                },
                 "items": {
                     "type": "array",
                     "items": {
@@ -1046,14 +3172,88 @@ var doc = `{
                 "status": {
                     "type": "integer"
                 },
-                "total": {
-                    "type": "number"
-                },
                 "user_id": {
                     "type": "integer"
                 }
             }
         },
+        "user.AddressDTO": {
+            "type": "object",
+            "required": [
+                "city",
+                "line1"
+            ],
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "is_default": {
+                    "type": "boolean"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "line1": {
+                    "type": "string"
+                },
+                "line2": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.AddressResponseDTO": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_default": {
+                    "type": "boolean"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "line1": {
+                    "type": "string"
+                },
+                "line2": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
         "user.AuthDTO": {
             "type": "object",
             "required": [
@@ -1069,6 +3269,21 @@ var doc = `{
                 }
             }
         },
+        "user.ChangePasswordDTO": {
+            "type": "object",
+            "required": [
+                "current_password",
+                "new_password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
         "user.DTOsWithPagination": {
             "type": "object",
             "properties": {
@@ -1083,22 +3298,191 @@ var doc = `{
                 }
             }
         },
+        "user.ForgotPasswordDTO": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.PatchDTO": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "type": "boolean"
+                },
+                "is_admin": {
+                    "description": "IsAdmin grants or revokes admin role.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "user.ProfileDTO": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "description": "Phone must be in E.164 format, e.g. +14155552671.",
+                    "type": "string"
+                }
+            }
+        },
+        "user.RefreshDTO": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.ResetPasswordDTO": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
         "user.ResponseDTO": {
             "type": "object",
             "properties": {
                 "created_at": {
                     "type": "string"
                 },
+                "disabled": {
+                    "type": "boolean"
+                },
                 "email": {
                     "type": "string"
                 },
+                "email_verified": {
+                    "description": "EmailVerified is false until the user follows the link sent on sign up.",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "is_admin": {
+                    "description": "IsAdmin is kept for clients that don't know about roles yet.",
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "user.RoleDTO": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "user.RolesDTO": {
+            "type": "object",
+            "required": [
+                "roles"
+            ],
+            "properties": {
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "user.SessionDTO": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Current is true for the session that made the request.",
                     "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
                 }
             }
+        },
+        "user.TokenDTO": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "refresh_token_expires_at": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"fmt"
 	"food_ordering_backend/config"
+	"food_ordering_backend/controllers/user"
 	"food_ordering_backend/database"
 	"food_ordering_backend/docs"
 	"food_ordering_backend/router"
@@ -30,12 +31,18 @@ var robotsTxt []byte
 
 // @BasePath /
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
 func main() {
 	updateSwaggerDoc()
 
 	db := database.MustGet()
 	r := router.Setup(db)
 
+	go user.InitService(db).PruneSessionsEvery(config.SessionPruneInterval)
+
 	r.GET("/", serveEmbedded("text/html", indexHTML))
 	r.GET("/robots.txt", serveEmbedded("text/plain", robotsTxt))
 
@@ -60,8 +67,8 @@ func updateSwaggerDoc() {
 	docs.SwaggerInfo.Description = fmt.Sprintf(`Golang backend for Food Ordering App.
 Frontend available [here](%s).
 
-*Since Swagger doesn't support cookie-based authorizations you should **Sign In** in the [frontend app](%s) and then comeback here to be able to interact with guarded routes.* `,
-		config.ClientURL.String(), config.ClientURL.String(),
+*To interact with guarded routes, get a token from **POST /users/token** and use **Authorize** button with "Bearer <token>" value.* `,
+		config.ClientURL.String(),
 	)
 }
 
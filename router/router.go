@@ -5,8 +5,10 @@ import (
 	"food_ordering_backend/config"
 	"food_ordering_backend/controllers/category"
 	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/kitchen"
 	"food_ordering_backend/controllers/order"
 	"food_ordering_backend/controllers/user"
+	"food_ordering_backend/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"strings"
@@ -22,6 +24,7 @@ func Setup(db *gorm.DB) *gin.Engine {
 	r.Use(LogsFormatter())
 	r.Use(gin.Recovery())
 	r.Use(CORSMiddleware())
+	r.Use(services.NewLimiter(services.DefaultLimiterStore, "ip", config.RateLimit).Middleware(services.KeyByIP))
 
 	r.Static("/"+config.StaticDir, config.StaticDirAbs)
 
@@ -30,6 +33,7 @@ func Setup(db *gorm.DB) *gin.Engine {
 		"/dishes":     dish.InitAPI(db),
 		"/users":      user.InitAPI(db),
 		"/orders":     order.InitAPI(db),
+		"/kitchen":    kitchen.InitAPI(db),
 	}
 
 	for route, api := range routes {
@@ -44,10 +48,12 @@ func CORSMiddleware() gin.HandlerFunc {
 		"Accept",
 		"Accept-Encoding",
 		"Accept-Language",
+		"Authorization",
 		"Cache-Control",
 		"Connection",
 		"Content-Type",
 		"Content-Length",
+		"Idempotency-Key",
 	}
 	joinedHeaders := strings.Join(allowedHeaders, ",")
 
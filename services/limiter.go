@@ -0,0 +1,216 @@
+package services
+
+import (
+	"food_ordering_backend/config"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is an interval between removals of full buckets from MemoryLimiterStore.
+const sweepInterval = time.Minute
+
+// LimiterStore keeps token buckets of rate limiters. Buckets start full, hold up to
+// rate.Limit tokens and regain them evenly, rate.Limit tokens per rate.Period.
+// Implementations must be safe for concurrent use.
+type LimiterStore interface {
+	// Take removes a token from the bucket with provided key. If the bucket is
+	// empty, Take returns false and the time until it regains a token.
+	Take(key string, rate config.Rate) (bool, time.Duration)
+	// Wait returns the time until the bucket with provided key has a token
+	// without taking it. Returns 0 if the bucket isn't empty.
+	Wait(key string, rate config.Rate) time.Duration
+	// Reset refills the bucket with provided key.
+	Reset(key string)
+}
+
+// DefaultLimiterStore is shared by all limiters, so that limits are
+// enforced across all APIs.
+var DefaultLimiterStore LimiterStore = NewMemoryLimiterStore()
+
+func ProvideLimiterStore() LimiterStore {
+	return DefaultLimiterStore
+}
+
+// Limiter limits the rate of events with the same key, e.g. requests from the same IP.
+type Limiter struct {
+	store LimiterStore
+	name  string
+	rate  config.Rate
+}
+
+// NewLimiter creates a limiter that keeps its buckets in provided store.
+// Name must be unique among limiters sharing the store. Zero rate disables the limiter.
+func NewLimiter(store LimiterStore, name string, rate config.Rate) *Limiter {
+	return &Limiter{store, name, rate}
+}
+
+// Allow takes a token for provided key. If there are no tokens left, Allow returns
+// false and the time until a token is available. Empty key is never limited.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.rate.IsZero() || key == "" {
+		return true, 0
+	}
+
+	return l.store.Take(l.key(key), l.rate)
+}
+
+// Wait returns the time until a token is available for provided key without taking it.
+func (l *Limiter) Wait(key string) time.Duration {
+	if l.rate.IsZero() || key == "" {
+		return 0
+	}
+
+	return l.store.Wait(l.key(key), l.rate)
+}
+
+// Reset removes the limit for provided key until it's exceeded again.
+func (l *Limiter) Reset(key string) {
+	if key != "" {
+		l.store.Reset(l.key(key))
+	}
+}
+
+// Middleware aborts requests with http.StatusTooManyRequests once the limit
+// for the key returned by keyFunc is exceeded.
+func (l *Limiter) Middleware(keyFunc func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, wait := l.Allow(keyFunc(c)); !ok {
+			AbortWithRetryAfter(c, wait)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (l *Limiter) key(key string) string {
+	return l.name + ":" + key
+}
+
+// KeyByIP makes Limiter.Middleware limit requests by IP of the remote end of
+// the connection. Forwarding headers are ignored, since any client can set them
+// to get a fresh bucket for every request.
+func KeyByIP(c *gin.Context) string {
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+
+	if err != nil {
+		return c.Request.RemoteAddr
+	}
+
+	return host
+}
+
+// AbortWithRetryAfter aborts the request with http.StatusTooManyRequests
+// and Retry-After header set to provided duration rounded up to seconds.
+func AbortWithRetryAfter(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatus(http.StatusTooManyRequests)
+}
+
+// MemoryLimiterStore keeps buckets in memory, so limits are per app instance.
+type MemoryLimiterStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryLimiterStore() *MemoryLimiterStore {
+	return &MemoryLimiterStore{buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (s *MemoryLimiterStore) Take(key string, rate config.Rate) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	b := s.bucket(key, rate, now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, b.wait()
+}
+
+func (s *MemoryLimiterStore) Wait(key string, rate config.Rate) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.buckets[key]; !ok {
+		return 0
+	}
+
+	return s.bucket(key, rate, time.Now()).wait()
+}
+
+func (s *MemoryLimiterStore) Reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets, key)
+}
+
+// bucket returns refilled bucket with provided key, creating a full one if it doesn't exist.
+func (s *MemoryLimiterStore) bucket(key string, rate config.Rate, now time.Time) *bucket {
+	b, ok := s.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: float64(rate.Limit), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.rate = rate
+	b.refill(now)
+
+	return b
+}
+
+// sweep removes full buckets, since they are the same as missing ones.
+func (s *MemoryLimiterStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, b := range s.buckets {
+		b.refill(now)
+
+		if b.tokens >= float64(b.rate.Limit) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	rate    config.Rate
+}
+
+func (b *bucket) refill(now time.Time) {
+	regained := now.Sub(b.updated).Seconds() * float64(b.rate.Limit) / b.rate.Period.Seconds()
+	b.tokens = math.Min(float64(b.rate.Limit), b.tokens+regained)
+	b.updated = now
+}
+
+// wait returns the time until the bucket has a token.
+func (b *bucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) * float64(b.rate.Period) / float64(b.rate.Limit))
+}
@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"food_ordering_backend/config"
+	"food_ordering_backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("should allow up to Limit events and then return time until next token", func(t *testing.T) {
+		it := assert.New(t)
+		limiter := services.NewLimiter(services.NewMemoryLimiterStore(), "test", config.Rate{Limit: 2, Period: time.Hour})
+
+		for i := 0; i < 2; i++ {
+			ok, _ := limiter.Allow("key")
+			it.True(ok)
+		}
+
+		ok, wait := limiter.Allow("key")
+		it.False(ok)
+		it.InDelta(30*time.Minute, wait, float64(time.Second))
+		it.InDelta(30*time.Minute, limiter.Wait("key"), float64(time.Second))
+
+		ok, _ = limiter.Allow("another key")
+		it.True(ok, "expected keys to be limited separately")
+	})
+
+	t.Run("should regain tokens over time", func(t *testing.T) {
+		it := assert.New(t)
+		limiter := services.NewLimiter(services.NewMemoryLimiterStore(), "test", config.Rate{Limit: 1, Period: 50 * time.Millisecond})
+
+		ok, _ := limiter.Allow("key")
+		it.True(ok)
+		ok, _ = limiter.Allow("key")
+		it.False(ok)
+
+		time.Sleep(60 * time.Millisecond)
+
+		ok, _ = limiter.Allow("key")
+		it.True(ok)
+	})
+
+	t.Run("should refill the bucket on Reset", func(t *testing.T) {
+		it := assert.New(t)
+		limiter := services.NewLimiter(services.NewMemoryLimiterStore(), "test", config.Rate{Limit: 1, Period: time.Hour})
+
+		limiter.Allow("key")
+		limiter.Reset("key")
+
+		it.Zero(limiter.Wait("key"))
+		ok, _ := limiter.Allow("key")
+		it.True(ok)
+	})
+
+	t.Run("should not limit anything if rate is zero", func(t *testing.T) {
+		it := assert.New(t)
+		limiter := services.NewLimiter(services.NewMemoryLimiterStore(), "test", config.Rate{})
+
+		for i := 0; i < 100; i++ {
+			ok, _ := limiter.Allow("key")
+			it.True(ok)
+		}
+	})
+
+	t.Run("should not share buckets between limiters with different names", func(t *testing.T) {
+		it := assert.New(t)
+		store := services.NewMemoryLimiterStore()
+		rate := config.Rate{Limit: 1, Period: time.Hour}
+
+		ok, _ := services.NewLimiter(store, "first", rate).Allow("key")
+		it.True(ok)
+		ok, _ = services.NewLimiter(store, "second", rate).Allow("key")
+		it.True(ok)
+	})
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	t.Run("should abort requests with 429 and Retry-After header once limit is exceeded", func(t *testing.T) {
+		it := assert.New(t)
+		limiter := services.NewLimiter(services.NewMemoryLimiterStore(), "test", config.Rate{Limit: 1, Period: time.Minute})
+		r := gin.New()
+		r.GET("/", limiter.Middleware(services.KeyByIP), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		it.Equal(http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if it.Equal(http.StatusTooManyRequests, w.Code) {
+			it.Equal("60", w.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("should limit requests by remote address regardless of forwarding headers", func(t *testing.T) {
+		it := assert.New(t)
+		limiter := services.NewLimiter(services.NewMemoryLimiterStore(), "test", config.Rate{Limit: 1, Period: time.Minute})
+		r := gin.New()
+		r.GET("/", limiter.Middleware(services.KeyByIP), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		send := func(remoteAddr, forwardedFor string) int {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		it.Equal(http.StatusOK, send("10.0.0.1:1234", "1.1.1.1"))
+		it.Equal(http.StatusTooManyRequests, send("10.0.0.1:5678", "2.2.2.2"))
+		it.Equal(http.StatusOK, send("10.0.0.2:1234", "1.1.1.1"))
+	})
+}
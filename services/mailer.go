@@ -0,0 +1,71 @@
+package services
+
+import (
+	"log"
+	"sync"
+)
+
+// Mail is a plain text email message.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users.
+type Mailer interface {
+	Send(m Mail) error
+}
+
+// DefaultMailer is used by all services that send emails.
+// Tests replace it with MemoryMailer to read sent messages.
+var DefaultMailer Mailer = LogMailer{}
+
+func ProvideMailer() Mailer {
+	return DefaultMailer
+}
+
+// LogMailer writes emails to the log instead of sending them.
+// It's meant for development, since emails may contain secrets like reset tokens.
+type LogMailer struct{}
+
+func (LogMailer) Send(m Mail) error {
+	log.Printf("[Mail] To: %s\nSubject: %s\n\n%s\n", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// MemoryMailer keeps sent emails in memory.
+type MemoryMailer struct {
+	mu    sync.Mutex
+	mails []Mail
+}
+
+func (mm *MemoryMailer) Send(m Mail) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.mails = append(mm.mails, m)
+	return nil
+}
+
+// Sent returns all emails sent to provided address in the order they were sent.
+func (mm *MemoryMailer) Sent(to string) []Mail {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	var mails []Mail
+
+	for _, m := range mm.mails {
+		if m.To == to {
+			mails = append(mails, m)
+		}
+	}
+
+	return mails
+}
+
+// Reset removes all sent emails.
+func (mm *MemoryMailer) Reset() {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.mails = nil
+}
@@ -0,0 +1,34 @@
+package services_test
+
+import (
+	"food_ordering_backend/services"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	t.Run("should keep sent emails by recipient", func(t *testing.T) {
+		it := assert.New(t)
+		var mailer services.MemoryMailer
+
+		first := services.Mail{To: "first@example.com", Subject: "1"}
+		second := services.Mail{To: "second@example.com", Subject: "2"}
+		third := services.Mail{To: "first@example.com", Subject: "3"}
+
+		for _, m := range []services.Mail{first, second, third} {
+			it.NoError(mailer.Send(m))
+		}
+
+		it.Equal([]services.Mail{first, third}, mailer.Sent("first@example.com"))
+		it.Equal([]services.Mail{second}, mailer.Sent("second@example.com"))
+		it.Empty(mailer.Sent("third@example.com"))
+	})
+
+	t.Run("should remove sent emails on Reset", func(t *testing.T) {
+		var mailer services.MemoryMailer
+		_ = mailer.Send(services.Mail{To: "first@example.com"})
+
+		mailer.Reset()
+		assert.Empty(t, mailer.Sent("first@example.com"))
+	})
+}
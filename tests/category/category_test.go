@@ -386,6 +386,7 @@ func TestCategories(t *testing.T) {
 			testCat.Image = nil
 			require.NoError(t, db.Save(&testCat).Error)
 			require.NoError(t, db.Exec("UPDATE dishes SET image = NULL").Error)
+			require.NoError(t, db.Exec("DELETE FROM dish_images").Error)
 
 			_, c := testutils.LoginAsRandomAdmin(t)
 			resp := sendWithParam(testCat.ID, c)
@@ -411,6 +412,7 @@ func TestCategories(t *testing.T) {
 			cat.Image = nil
 			require.NoError(t, db.Save(&cat).Error)
 			require.NoError(t, db.Exec("UPDATE dishes SET image = NULL").Error)
+			require.NoError(t, db.Exec("DELETE FROM dish_images").Error)
 
 			img, err := os.Open(testutils.PathToFile("./img/pizza.png"))
 			require.NoError(t, err)
@@ -476,6 +478,22 @@ func TestCategories(t *testing.T) {
 			}
 		})
 
+		t.Run("should delete a category even if image files are missing", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			cat := testutils.FindTestCategoryByID(3)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := sendWithParam(cat.ID, c)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var count int64
+				db.Table("dishes").Where("category_id = ?", cat.ID).Count(&count)
+				it.Zero(count, "expected dishes of the category to be deleted")
+			}
+		})
+
 		t.Run("should return 403 if category isn't removable", func(t *testing.T) {
 			testutils.SetupUsersDB(t)
 			testutils.SetupDishesAndCategories(t)
@@ -489,18 +507,21 @@ func TestCategories(t *testing.T) {
 			}
 		})
 
-		t.Run("should return 403 if the dish from corresponding category has already been used in some order", func(t *testing.T) {
+		t.Run("should delete a category even if its dishes have already been used in some order", func(t *testing.T) {
 			testutils.SetupOrdersDB(t)
 			it := assert.New(t)
 			require.NoError(t, db.Exec("UPDATE categories SET image = NULL").Error)
 			require.NoError(t, db.Exec("UPDATE dishes SET image = NULL").Error)
+			require.NoError(t, db.Exec("DELETE FROM dish_images").Error)
 
 			cat := testutils.FindTestCategoryByID(1)
 			_, c := testutils.LoginAsRandomAdmin(t)
 			resp := sendWithParam(cat.ID, c)
 
-			if it.Equal(http.StatusForbidden, resp.Code) {
-				it.NotEmpty(resp.Body.String())
+			if it.Equal(http.StatusOK, resp.Code) {
+				var count int64
+				db.Table("order_items").Where("category_title = ?", cat.Title).Count(&count)
+				it.NotZero(count, "expected order items to keep category title")
 			}
 		})
 
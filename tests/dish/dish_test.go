@@ -7,6 +7,7 @@ import (
 	"food_ordering_backend/config"
 	"food_ordering_backend/controllers/category"
 	"food_ordering_backend/controllers/dish"
+	"food_ordering_backend/controllers/order"
 	"food_ordering_backend/database"
 	"food_ordering_backend/tests/testutils"
 	"github.com/stretchr/testify/assert"
@@ -117,6 +118,127 @@ func TestDishes(t *testing.T) {
 			it.Equal(http.StatusOK, resp.Code)
 			it.Equal("[]", resp.Body.String())
 		})
+
+		t.Run("should search, filter and sort dishes", func(t *testing.T) {
+			testutils.SetupDishesAndCategories(t)
+			tests := []struct {
+				query    string
+				expected []uint
+			}{
+				{"q=salad", []uint{1, 2}},
+				{"q=chee", []uint{4, 6}},
+				{"q=ORANGE%202", []uint{8}},
+				{"q=salad%20pizza", []uint{}},
+				{"q=%20%2B", []uint{1, 2, 3, 4, 5, 6, 7, 8}},
+				{"cid=1,4", []uint{1, 2, 7, 8}},
+				{"cid=1&cid=4", []uint{1, 2, 7, 8}},
+				{"min_price=200&max_price=322", []uint{1, 2, 4, 8}},
+				{"sort=price&order=desc", []uint{6, 5, 2, 1, 4, 8, 3, 7}},
+				{"q=2l&cid=4&sort=price", []uint{7, 8}},
+				{"q=mozzarel", []uint{5}},
+				{"exclude_allergens=gluten", []uint{1, 2, 4, 6, 7, 8}},
+				{"exclude_allergens=milk,nuts", []uint{1, 3, 4, 6, 7, 8}},
+				{"tags=vegetarian", []uint{1, 2, 5}},
+				{"tags=vegetarian&tags=gluten_free", []uint{1}},
+				{"max_spicy_level=0", []uint{1, 4, 5, 6, 7, 8}},
+				{"max_calories=500", []uint{1, 2}},
+			}
+
+			for _, tc := range tests {
+				resp := testutils.SendReq(http.MethodGet, "/dishes?"+tc.query)("")
+
+				if assert.Equal(t, http.StatusOK, resp.Code, tc.query) {
+					var dtos []dish.DTO
+					require.NoError(t, json.NewDecoder(resp.Body).Decode(&dtos))
+
+					ids := make([]uint, len(dtos))
+					for i, dto := range dtos {
+						ids[i] = dto.ID
+					}
+
+					assert.Equal(t, tc.expected, ids, tc.query)
+				}
+			}
+		})
+
+		t.Run("should filter dishes by availability", func(t *testing.T) {
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			require.NoError(t, db.Model(&dish.Dish{ID: 2}).Update("available", false).Error)
+			require.NoError(t, db.Model(&dish.Dish{ID: 5}).Update("stock", 0).Error)
+			require.NoError(t, db.Model(&dish.Dish{ID: 7}).Update("stock", 3).Error)
+
+			find := func(query string) []dish.DTO {
+				resp := testutils.SendReq(http.MethodGet, "/dishes?"+query)("")
+				require.Equal(t, http.StatusOK, resp.Code)
+
+				var dtos []dish.DTO
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&dtos))
+				return dtos
+			}
+
+			soldOut := find("available=false")
+
+			if it.Len(soldOut, 2) {
+				it.Equal(uint(2), soldOut[0].ID)
+				it.False(*soldOut[0].Available)
+				it.True(soldOut[0].SoldOut)
+				it.Equal(uint(5), soldOut[1].ID)
+				it.True(*soldOut[1].Available)
+				it.Equal(0, *soldOut[1].Stock)
+				it.True(soldOut[1].SoldOut)
+			}
+
+			available := find("available=true&cid=4")
+
+			if it.Len(available, 2) {
+				it.Equal(uint(7), available[0].ID)
+				it.Equal(3, *available[0].Stock)
+				it.False(available[0].SoldOut)
+				it.Nil(available[1].Stock)
+			}
+		})
+
+		t.Run("should return paginated dishes if page or limit is provided", func(t *testing.T) {
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			resp := testutils.SendReq(http.MethodGet, "/dishes?sort=price&page=1&limit=3")("")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto dish.DTOsWithPagination
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(common.PaginationDTO{Page: 1, Limit: 3, Total: len(testutils.TestDishes)}, dto.Pagination)
+
+					if it.Len(dto.Dishes, 3) {
+						it.Equal(uint(4), dto.Dishes[0].ID)
+						it.Equal(uint(1), dto.Dishes[1].ID)
+						it.Equal(uint(2), dto.Dishes[2].ID)
+						it.Equal(category.ToDTO(testutils.FindTestCategoryByID(2)), dto.Dishes[0].Category)
+					}
+				}
+			}
+
+			resp = testutils.SendReq(http.MethodGet, "/dishes?cid=1&limit=1")("")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto dish.DTOsWithPagination
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(common.PaginationDTO{Page: 0, Limit: 1, Total: 2}, dto.Pagination)
+					it.Len(dto.Dishes, 1)
+				}
+			}
+		})
+
+		t.Run("should return 400 if filter parameters are invalid", func(t *testing.T) {
+			queries := []string{"cid=1,", "min_price=1.5", "max_price=abc", "sort=category", "order=up"}
+
+			for _, query := range queries {
+				resp := testutils.SendReq(http.MethodGet, "/dishes?"+query)("")
+				assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+			}
+		})
 	})
 
 	t.Run("GET /dishes/:id", func(t *testing.T) {
@@ -159,11 +281,11 @@ func TestDishes(t *testing.T) {
 			testutils.SetupDishesAndCategories(t)
 			it := assert.New(t)
 			initialLen := len(testutils.TestDishes)
-			reqJSON := `{"id":69,"title":"Double Cheeseburger","price":4.56,"category_id":2}`
+			reqJSON := `{"id":69,"title":"Double Cheeseburger","price":{"amount":456,"currency":"USD"},"category_id":2}`
 			testCategory := testutils.FindTestCategoryByID(2)
 			categoryJSON, _ := json.Marshal(category.ToDTO(testCategory))
 			respJSON := fmt.Sprintf(
-				`{"id":69,"title":"Double Cheeseburger","price":4.56,"removable":true,"category_id":2,"category":%s}`,
+				`{"id":69,"title":"Double Cheeseburger","price":{"amount":456,"currency":"USD"},"removable":true,"category_id":2,"category":%s,"description":"","ingredients":[],"allergens":[],"dietary_tags":[],"spicy_level":0,"calories":null,"available":true,"stock":null,"sold_out":false,"images":[]}`,
 				categoryJSON,
 			)
 
@@ -180,7 +302,7 @@ func TestDishes(t *testing.T) {
 			err := db.Preload("Category").Last(&last).Error
 			require.NoError(t, err)
 
-			it.Equal(4.56, last.Price)
+			it.Equal(common.NewMoney(456), last.Price)
 			it.Equal("Double Cheeseburger", last.Title)
 			it.Equal(uint(69), last.ID)
 			it.Equal(uint(2), last.CategoryID)
@@ -201,7 +323,7 @@ func TestDishes(t *testing.T) {
 
 			tests := []string{"  Crunchy Cashew Salad", "Crunchy Cashew Salad   ", "   Crunchy Cashew Salad   "}
 			for _, tc := range tests {
-				json := fmt.Sprintf(`{"title":%q,"category_id":3,"price":2.28}`, tc)
+				json := fmt.Sprintf(`{"title":%q,"category_id":3,"price":{"amount":228,"currency":"USD"}}`, tc)
 				resp := send(c, json)
 				it.Equal(http.StatusConflict, resp.Code)
 			}
@@ -222,7 +344,7 @@ func TestDishes(t *testing.T) {
 			testutils.SetupUsersDB(t)
 			testutils.SetupDishesAndCategories(t)
 			it := assert.New(t)
-			json := `{"title":"Royal Cheeseburger","price":4.56,"category_id":2}`
+			json := `{"title":"Royal Cheeseburger","price":{"amount":456,"currency":"USD"},"category_id":2}`
 			_, c := testutils.LoginAsRandomAdmin(t)
 
 			resp := send(c, json)
@@ -232,6 +354,56 @@ func TestDishes(t *testing.T) {
 			it.Equal(http.StatusConflict, resp.Code)
 		})
 
+		t.Run("should save description, ingredients, allergens and dietary tags", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			reqJSON := `{"id":70,"title":"Pad Thai","price":{"amount":890},"category_id":1,"description":" Rice noodles with tofu ","ingredients":[" rice noodles ","tofu","peanuts"],"allergens":["soy","peanuts"],"dietary_tags":["vegan","vegetarian"],"spicy_level":2,"calories":650}`
+
+			resp := send(c, reqJSON)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				var dto dish.DTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal("Rice noodles with tofu", dto.Description)
+					it.Equal([]string{"rice noodles", "tofu", "peanuts"}, dto.Ingredients)
+					it.Equal([]string{"peanuts", "soy"}, dto.Allergens)
+					it.Equal([]string{"vegetarian", "vegan"}, dto.DietaryTags)
+					it.Equal(2, dto.SpicyLevel)
+
+					if it.NotNil(dto.Calories) {
+						it.Equal(650, *dto.Calories)
+					}
+				}
+
+				var created dish.Dish
+				require.NoError(t, db.Where("title = ?", "Pad Thai").First(&created).Error)
+				it.Equal(dish.AllergenSoy|dish.AllergenPeanuts, created.Allergens)
+				it.Equal(dish.TagVegan|dish.TagVegetarian, created.DietaryTags)
+				it.Equal(dish.Ingredients{"rice noodles", "tofu", "peanuts"}, created.Ingredients)
+			}
+		})
+
+		t.Run("should return 400 if details are invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			details := []string{
+				`"allergens":["bacon"]`,
+				`"dietary_tags":["keto"]`,
+				`"spicy_level":4`,
+				`"calories":-1`,
+				`"ingredients":[""]`,
+			}
+
+			for _, d := range details {
+				resp := send(c, `{"title":"Pad Thai","price":{"amount":890},"category_id":1,`+d+`}`)
+				assert.Equal(t, http.StatusBadRequest, resp.Code, d)
+			}
+		})
+
 		testutils.RunAuthTests(t, http.MethodPost, "/dishes", true)
 		negativePriceTest(t, http.MethodPost)
 	})
@@ -382,7 +554,7 @@ func TestDishes(t *testing.T) {
 			testutils.SetupDishesAndCategories(t)
 			it := assert.New(t)
 			testCategory := testutils.FindTestCategoryByID(2)
-			updateJSON := `{"title":"Double Cheeseburger","price":4.56,"category_id":2}`
+			updateJSON := `{"title":"Double Cheeseburger","price":{"amount":456,"currency":"USD"},"category_id":2}`
 			_, c := testutils.LoginAsRandomAdmin(t)
 
 			resp := sendWithParam(4, c, updateJSON)
@@ -391,7 +563,7 @@ func TestDishes(t *testing.T) {
 				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
 					it.Equal(uint(4), dto.ID)
 					it.Equal("Double Cheeseburger", dto.Title)
-					it.Equal(4.56, dto.Price)
+					it.Equal(common.NewMoney(456), dto.Price)
 					it.Equal(uint(2), dto.CategoryID)
 					it.Equal(imgURL("4.png"), *dto.Image)
 
@@ -405,7 +577,7 @@ func TestDishes(t *testing.T) {
 			testutils.SetupDishesAndCategories(t)
 			it := assert.New(t)
 			testCategory := testutils.FindTestCategoryByID(2)
-			updateJSON := `{"id":69,"title":"Double Cheeseburger","price":4.56,"category_id":2}`
+			updateJSON := `{"id":69,"title":"Double Cheeseburger","price":{"amount":456,"currency":"USD"},"category_id":2}`
 			_, c := testutils.LoginAsRandomAdmin(t)
 
 			resp := sendWithParam(4, c, updateJSON)
@@ -414,7 +586,7 @@ func TestDishes(t *testing.T) {
 				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
 					it.Equal(uint(4), dto.ID)
 					it.Equal("Double Cheeseburger", dto.Title)
-					it.Equal(4.56, dto.Price)
+					it.Equal(common.NewMoney(456), dto.Price)
 					it.Equal(uint(2), dto.CategoryID)
 					it.Equal(imgURL("4.png"), *dto.Image)
 
@@ -423,12 +595,86 @@ func TestDishes(t *testing.T) {
 			}
 		})
 
+		t.Run("should replace details of the dish", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			d := testutils.FindTestDishByID(5)
+			body := func(details string) string {
+				return fmt.Sprintf(`{"title":%q,"price":{"amount":420,"currency":"USD"},"category_id":3%s}`, d.Title, details)
+			}
+
+			resp := sendWithParam(5, c, body(`,"description":"With buffalo mozzarella","ingredients":["tomato sauce","buffalo mozzarella"],"allergens":["milk"],"dietary_tags":["gluten_free"],"spicy_level":1,"calories":700`))
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var updated dish.Dish
+				require.NoError(t, db.First(&updated, 5).Error)
+				it.Equal("With buffalo mozzarella", updated.Description)
+				it.Equal(dish.Ingredients{"tomato sauce", "buffalo mozzarella"}, updated.Ingredients)
+				it.Equal(dish.AllergenMilk, updated.Allergens)
+				it.Equal(dish.TagGlutenFree, updated.DietaryTags)
+				it.Equal(1, updated.SpicyLevel)
+
+				if it.NotNil(updated.Calories) {
+					it.Equal(700, *updated.Calories)
+				}
+			}
+
+			resp = sendWithParam(5, c, body(""))
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var updated dish.Dish
+				require.NoError(t, db.First(&updated, 5).Error)
+				it.Empty(updated.Description)
+				it.Empty(updated.Ingredients)
+				it.Zero(updated.Allergens)
+				it.Zero(updated.DietaryTags)
+				it.Zero(updated.SpicyLevel)
+				it.Nil(updated.Calories)
+			}
+		})
+
+		t.Run("should replace availability and stock", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			d := testutils.FindTestDishByID(4)
+			body := func(extra string) string {
+				return fmt.Sprintf(`{"title":%q,"price":{"amount":228,"currency":"USD"},"category_id":2%s}`, d.Title, extra)
+			}
+
+			resp := sendWithParam(4, c, body(`,"available":false,"stock":5`))
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var updated dish.Dish
+				require.NoError(t, db.First(&updated, 4).Error)
+				it.False(updated.Available)
+
+				if it.NotNil(updated.Stock) {
+					it.Equal(5, *updated.Stock)
+				}
+			}
+
+			resp = sendWithParam(4, c, body(""))
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var updated dish.Dish
+				require.NoError(t, db.First(&updated, 4).Error)
+				it.True(updated.Available)
+				it.Nil(updated.Stock)
+			}
+
+			it.Equal(http.StatusBadRequest, sendWithParam(4, c, body(`,"stock":-1`)).Code)
+		})
+
 		t.Run("should correctly handle category change", func(t *testing.T) {
 			testutils.SetupUsersDB(t)
 			testutils.SetupDishesAndCategories(t)
 			it := assert.New(t)
 			testCategory := testutils.FindTestCategoryByID(3)
-			updateJSON := `{"id":69,"title":"Meat Supreme","price":3.22,"category_id":3}`
+			updateJSON := `{"id":69,"title":"Meat Supreme","price":{"amount":322,"currency":"USD"},"category_id":3}`
 			_, c := testutils.LoginAsRandomAdmin(t)
 
 			resp := sendWithParam(4, c, updateJSON)
@@ -437,7 +683,7 @@ func TestDishes(t *testing.T) {
 				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
 					it.Equal(uint(4), dto.ID)
 					it.Equal("Meat Supreme", dto.Title)
-					it.Equal(3.22, dto.Price)
+					it.Equal(common.NewMoney(322), dto.Price)
 					it.Equal(uint(3), dto.CategoryID)
 					it.Equal(imgURL("4.png"), *dto.Image)
 
@@ -455,7 +701,7 @@ func TestDishes(t *testing.T) {
 
 			tests := []string{"  Crunchy Cashew Salad", "Crunchy Cashew Salad   ", "   Crunchy Cashew Salad   "}
 			for _, tc := range tests {
-				json := fmt.Sprintf(`{"title":%q,"category_id":3,"price":2.28}`, tc)
+				json := fmt.Sprintf(`{"title":%q,"category_id":3,"price":{"amount":228,"currency":"USD"}}`, tc)
 				resp := sendWithParam(1, c, json)
 				it.Equal(http.StatusConflict, resp.Code)
 			}
@@ -480,7 +726,7 @@ func TestDishes(t *testing.T) {
 			testutils.SetupUsersDB(t)
 			testutils.SetupDishesAndCategories(t)
 			_, c := testutils.LoginAsRandomAdmin(t)
-			json := fmt.Sprintf(`{"title":"Hamburger","category_id":3,"price":2.28}`)
+			json := fmt.Sprintf(`{"title":"Hamburger","category_id":3,"price":{"amount":228,"currency":"USD"}}`)
 			resp := sendWithParam(5, c, json)
 			assert.Equal(t, http.StatusConflict, resp.Code)
 		})
@@ -554,7 +800,7 @@ func TestDishes(t *testing.T) {
 			assert.Equal(t, http.StatusBadRequest, resp.Code)
 		})
 
-		t.Run("should return 403 if the dish has already been used in the order", func(t *testing.T) {
+		t.Run("should delete a dish that has already been used in the order and keep order items", func(t *testing.T) {
 			testutils.SetupOrdersDB(t)
 			it := assert.New(t)
 			require.NoError(t, db.Exec("UPDATE dishes SET image = NULL").Error)
@@ -563,8 +809,18 @@ func TestDishes(t *testing.T) {
 			_, c := testutils.LoginAsRandomAdmin(t)
 			resp := sendWithParam(d.ID, c)
 
-			if it.Equal(http.StatusForbidden, resp.Code) {
-				it.NotEmpty(resp.Body.String())
+			if it.Equal(http.StatusOK, resp.Code) {
+				var items []order.Item
+
+				if it.NoError(db.Where("dish_title = ?", d.Title).Find(&items).Error) {
+					it.Len(items, 3)
+
+					for _, item := range items {
+						it.Zero(item.DishID)
+						it.Equal(d.Price, item.UnitPrice)
+						it.Equal(d.Category.Title, item.CategoryTitle)
+					}
+				}
 			}
 		})
 
@@ -580,13 +836,479 @@ func TestDishes(t *testing.T) {
 	})
 }
 
+func TestOptions(t *testing.T) {
+	findAll := func(t *testing.T, dishID uint) []dish.OptionGroupDTO {
+		resp := testutils.SendReq(http.MethodGet, fmt.Sprintf("/dishes/%d/options", dishID))("")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var groups []dish.OptionGroupDTO
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&groups))
+		return groups
+	}
+
+	t.Run("GET /dishes/:id/options", func(t *testing.T) {
+		t.Run("should return option groups of the dish with their options", func(t *testing.T) {
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+
+			assert.Equal(t, dish.ToOptionGroupDTOs(testutils.TestOptionGroups), findAll(t, 6))
+		})
+
+		t.Run("should return an empty array if the dish doesn't have options", func(t *testing.T) {
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+
+			resp := testutils.SendReq(http.MethodGet, "/dishes/1/options")("")
+
+			if assert.Equal(t, http.StatusOK, resp.Code) {
+				assert.Equal(t, "[]", resp.Body.String())
+			}
+		})
+
+		t.Run("should return 404 if dish doesn't exist", func(t *testing.T) {
+			testutils.SetupDishesAndCategories(t)
+			resp := testutils.SendReq(http.MethodGet, "/dishes/1337/options")("")
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+	})
+
+	t.Run("POST /dishes/:id/options", func(t *testing.T) {
+		send := testutils.ReqWithCookie(http.MethodPost, "/dishes/5/options")
+
+		t.Run("should add option group to the dish and return it", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := send(c, `{"title": " Size ", "min_select": 1, "max_select": 1, "options": [{"title": "Small"}, {"title": "Large", "price_delta": {"amount": 200}}]}`)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				var dto dish.OptionGroupDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.NotZero(dto.ID)
+					it.Equal("Size", dto.Title)
+					it.Equal(1, dto.MinSelect)
+					it.Equal(1, dto.MaxSelect)
+
+					if it.Len(dto.Options, 2) {
+						it.NotZero(dto.Options[0].ID)
+						it.Equal("Small", dto.Options[0].Title)
+						it.Equal(common.NewMoney(0), dto.Options[0].PriceDelta)
+						it.Equal("Large", dto.Options[1].Title)
+						it.Equal(common.NewMoney(200), dto.Options[1].PriceDelta)
+					}
+				}
+
+				it.Equal([]dish.OptionGroupDTO{dto}, findAll(t, 5))
+			}
+		})
+
+		t.Run("should return 422 if option group is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			tests := []struct {
+				reason  string
+				reqJSON string
+			}{
+				{"title is missing", `{"max_select": 1, "options": [{"title": "Small"}]}`},
+				{"there are no options", `{"title": "Size", "max_select": 1, "options": []}`},
+				{"max_select is missing", `{"title": "Size", "options": [{"title": "Small"}]}`},
+				{"min_select is greater than max_select", `{"title": "Size", "min_select": 2, "max_select": 1, "options": [{"title": "Small"}, {"title": "Large"}]}`},
+				{"max_select is greater than the number of options", `{"title": "Size", "max_select": 2, "options": [{"title": "Small"}]}`},
+				{"option title is blank", `{"title": "Size", "max_select": 1, "options": [{"title": "  "}]}`},
+				{"price delta is negative", `{"title": "Size", "max_select": 1, "options": [{"title": "Small", "price_delta": {"amount": -50}}]}`},
+			}
+
+			for _, tc := range tests {
+				resp := send(c, tc.reqJSON)
+				assert.Equalf(t, http.StatusUnprocessableEntity, resp.Code, "expected 422 if %s", tc.reason)
+			}
+
+			assert.Empty(t, findAll(t, 5))
+		})
+
+		t.Run("should return 400 if options are priced in another currency", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := send(c, `{"title": "Size", "max_select": 1, "options": [{"title": "Large", "price_delta": {"amount": 200, "currency": "EUR"}}]}`)
+
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+
+		t.Run("should return 404 if dish doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := testutils.ReqWithCookie(http.MethodPost, "/dishes/1337/options")(c, `{"title": "Size", "max_select": 1, "options": [{"title": "Small"}]}`)
+
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPost, "/dishes/5/options", true)
+	})
+
+	t.Run("PUT /dishes/:id/options/:gid", func(t *testing.T) {
+		sendWithParam := func(dishID, groupID uint, c *http.Cookie, body string) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodPut, fmt.Sprintf("/dishes/%d/options/%d", dishID, groupID))(c, body)
+		}
+
+		t.Run("should replace option group and keep only listed options", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			g := testutils.TestOptionGroups[1]
+			cheese := g.Options[0]
+			body := fmt.Sprintf(`{"title": "Toppings", "max_select": 1, "options": [{"id": %d, "title": "More cheese", "price_delta": {"amount": 70}}, {"title": "Bacon", "price_delta": {"amount": 90}}]}`, cheese.ID)
+
+			resp := sendWithParam(6, g.ID, c, body)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto dish.OptionGroupDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(g.ID, dto.ID)
+					it.Equal("Toppings", dto.Title)
+					it.Equal(1, dto.MaxSelect)
+
+					if it.Len(dto.Options, 2) {
+						it.Equal(dish.OptionDTO{ID: cheese.ID, Title: "More cheese", PriceDelta: common.NewMoney(70)}, dto.Options[0])
+						it.NotZero(dto.Options[1].ID)
+						it.Equal("Bacon", dto.Options[1].Title)
+					}
+				}
+
+				groups := findAll(t, 6)
+
+				if it.Len(groups, 2) {
+					it.Equal(dto, groups[1])
+				}
+
+				var count int64
+				require.NoError(t, db.Model(&dish.Option{}).Where("group_id = ?", g.ID).Count(&count).Error)
+				it.Equal(int64(2), count)
+			}
+		})
+
+		t.Run("should return 400 if option doesn't belong to the group", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			g := testutils.TestOptionGroups[1]
+			other := testutils.TestOptionGroups[0].Options[0]
+			body := fmt.Sprintf(`{"title": "Extras", "max_select": 1, "options": [{"id": %d, "title": "Small"}]}`, other.ID)
+
+			resp := sendWithParam(6, g.ID, c, body)
+
+			if it.Equal(http.StatusBadRequest, resp.Code) {
+				it.Equal(dish.ToOptionGroupDTOs(testutils.TestOptionGroups), findAll(t, 6))
+			}
+		})
+
+		t.Run("should return 404 if the dish doesn't have the group", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			body := `{"title": "Size", "max_select": 1, "options": [{"title": "Small"}]}`
+
+			assert.Equal(t, http.StatusNotFound, sendWithParam(5, testutils.TestOptionGroups[0].ID, c, body).Code)
+			assert.Equal(t, http.StatusNotFound, sendWithParam(6, 1337, c, body).Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPut, "/dishes/6/options/1", true)
+	})
+
+	t.Run("DELETE /dishes/:id/options/:gid", func(t *testing.T) {
+		sendWithParam := func(dishID, groupID uint, c *http.Cookie) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodDelete, fmt.Sprintf("/dishes/%d/options/%d", dishID, groupID))(c, "")
+		}
+
+		t.Run("should delete option group along with its options", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			g := testutils.TestOptionGroups[0]
+
+			if it.Equal(http.StatusNoContent, sendWithParam(6, g.ID, c).Code) {
+				it.Equal(dish.ToOptionGroupDTOs(testutils.TestOptionGroups[1:]), findAll(t, 6))
+
+				var count int64
+				require.NoError(t, db.Model(&dish.Option{}).Where("group_id = ?", g.ID).Count(&count).Error)
+				it.Zero(count)
+			}
+		})
+
+		t.Run("should return 404 if the dish doesn't have the group", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			assert.Equal(t, http.StatusNotFound, sendWithParam(5, testutils.TestOptionGroups[0].ID, c).Code)
+			assert.Equal(t, http.StatusNotFound, sendWithParam(6, 1337, c).Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodDelete, "/dishes/6/options/1", true)
+	})
+}
+
+func TestImages(t *testing.T) {
+	gallery := func(t *testing.T, dishID uint) ([]dish.Image, dish.Dish) {
+		var d dish.Dish
+		require.NoError(t, db.Preload("Images", dish.PreloadImages).First(&d, dishID).Error)
+		return d.Images, d
+	}
+
+	// addImages uploads pizza.png as new images of dish 6 and returns their dtos.
+	addImages := func(t *testing.T, c *http.Cookie, count int) []dish.ImageDTO {
+		dtos := make([]dish.ImageDTO, count)
+
+		for i := range dtos {
+			img, err := os.Open(testutils.PathToFile("./img/pizza.png"))
+			require.NoError(t, err)
+
+			resp := addImage(6, c, filepath.Base(img.Name()), img)
+			img.Close()
+
+			require.Equal(t, http.StatusCreated, resp.Code)
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&dtos[i]))
+		}
+
+		return dtos
+	}
+
+	t.Run("POST /dishes/:id/images", func(t *testing.T) {
+		t.Run("should add image to the end of the gallery and keep the primary image", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			t.Cleanup(testutils.CleanupStaticFolder)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			dtos := addImages(t, c, 2)
+
+			images, d := gallery(t, 6)
+
+			if it.Len(images, 3) {
+				it.Equal("6.png", images[0].Name)
+				it.Equal(dtos[0].ID, images[1].ID)
+				it.Equal(dtos[1].ID, images[2].ID)
+				it.Equal(imgURL(images[1].Name), dtos[0].URL)
+				it.NotEqual(images[1].Name, images[2].Name, "expected images to have unique names")
+				it.FileExists(filepath.Join(config.DishesImgDirAbs, images[1].Name))
+			}
+
+			if it.NotNil(d.Image) {
+				it.Equal("6.png", *d.Image)
+			}
+
+			resp := testutils.SendReq(http.MethodGet, "/dishes/6")("")
+			var dto dish.DTO
+
+			if it.Equal(http.StatusOK, resp.Code) && it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+				it.Equal(dish.ToImageDTOs(images), dto.Images)
+			}
+		})
+
+		t.Run("should make the first image of an empty gallery primary", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			t.Cleanup(testutils.CleanupStaticFolder)
+			it := assert.New(t)
+			require.NoError(t, db.Exec("DELETE FROM dish_images WHERE dish_id = 6").Error)
+			require.NoError(t, db.Exec("UPDATE dishes SET image = NULL WHERE id = 6").Error)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			dto := addImages(t, c, 1)[0]
+
+			if _, d := gallery(t, 6); it.NotNil(d.Image) {
+				it.Equal(dto.URL, imgURL(*d.Image))
+			}
+		})
+
+		t.Run("should return 415 if file type is not supported", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := addImage(6, c, "img.json", strings.NewReader("{}"))
+			assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+
+			images, _ := gallery(t, 6)
+			assert.Len(t, images, 1)
+		})
+
+		t.Run("should return 404 if dish doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := addImage(1337, c, "img.json", strings.NewReader("{}"))
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPost, "/dishes/6/images", true)
+	})
+
+	t.Run("PUT /dishes/:id/images", func(t *testing.T) {
+		send := testutils.ReqWithCookie(http.MethodPut, "/dishes/6/images")
+
+		t.Run("should reorder the gallery and make the first image primary", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			t.Cleanup(testutils.CleanupStaticFolder)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dtos := addImages(t, c, 2)
+			fixture := testutils.FindTestDishByID(6).Images[0]
+
+			resp := send(c, fmt.Sprintf(`{"ids":[%d,%d,%d]}`, dtos[1].ID, fixture.ID, dtos[0].ID))
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var ordered []dish.ImageDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&ordered)) {
+					it.Equal([]dish.ImageDTO{dtos[1], dish.ToImageDTO(fixture), dtos[0]}, ordered)
+				}
+			}
+
+			images, d := gallery(t, 6)
+
+			if it.Len(images, 3) {
+				it.Equal([]uint{dtos[1].ID, fixture.ID, dtos[0].ID}, []uint{images[0].ID, images[1].ID, images[2].ID})
+			}
+
+			if it.NotNil(d.Image) {
+				it.Equal(dtos[1].URL, imgURL(*d.Image))
+			}
+		})
+
+		t.Run("should return 422 unless ids list every image of the gallery once", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			own := testutils.FindTestDishByID(6).Images[0].ID
+			foreign := testutils.FindTestDishByID(5).Images[0].ID
+
+			bodies := []string{
+				`{}`,
+				`{"ids":[]}`,
+				fmt.Sprintf(`{"ids":[%d,%d]}`, own, own),
+				fmt.Sprintf(`{"ids":[%d]}`, foreign),
+			}
+
+			for _, body := range bodies {
+				resp := send(c, body)
+				it.Equal(http.StatusUnprocessableEntity, resp.Code, body)
+			}
+		})
+
+		t.Run("should return 404 if dish doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := testutils.ReqWithCookie(http.MethodPut, "/dishes/1337/images")(c, `{"ids":[1]}`)
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPut, "/dishes/6/images", true)
+	})
+
+	t.Run("DELETE /dishes/:id/images/:iid", func(t *testing.T) {
+		sendWithParam := func(id, iid uint, c *http.Cookie) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodDelete, fmt.Sprintf("/dishes/%d/images/%d", id, iid))(c, "")
+		}
+
+		t.Run("should delete image, remove its file and make the next image primary", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			t.Cleanup(testutils.CleanupStaticFolder)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dtos := addImages(t, c, 2)
+			fixture := testutils.FindTestDishByID(6).Images[0]
+
+			if it.Equal(http.StatusNoContent, sendWithParam(6, fixture.ID, c).Code) {
+				images, d := gallery(t, 6)
+
+				if it.Len(images, 2) {
+					it.Equal(dtos[0].ID, images[0].ID)
+					it.Equal(0, images[0].Position)
+					it.Equal(1, images[1].Position)
+				}
+
+				if it.NotNil(d.Image) {
+					it.Equal(dtos[0].URL, imgURL(*d.Image))
+				}
+			}
+
+			if it.Equal(http.StatusNoContent, sendWithParam(6, dtos[1].ID, c).Code) {
+				it.NoFileExists(filepath.Join(config.DishesImgDirAbs, path.Base(dtos[1].URL)))
+				it.FileExists(filepath.Join(config.DishesImgDirAbs, path.Base(dtos[0].URL)))
+			}
+		})
+
+		t.Run("should clear the primary image once the gallery is empty", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			fixture := testutils.FindTestDishByID(6).Images[0]
+
+			if it.Equal(http.StatusNoContent, sendWithParam(6, fixture.ID, c).Code) {
+				images, d := gallery(t, 6)
+				it.Empty(images)
+				it.Nil(d.Image)
+			}
+		})
+
+		t.Run("should return 404 if the dish doesn't have image with provided id", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			foreign := testutils.FindTestDishByID(5).Images[0].ID
+
+			assert.Equal(t, http.StatusNotFound, sendWithParam(6, foreign, c).Code)
+			assert.Equal(t, http.StatusNotFound, sendWithParam(1337, foreign, c).Code)
+
+			images, _ := gallery(t, 5)
+			assert.Len(t, images, 1)
+		})
+
+		t.Run("should return 400 if image id isn't valid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := testutils.ReqWithCookie(http.MethodDelete, "/dishes/6/images/first")(c, "")
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodDelete, "/dishes/6/images/1", true)
+	})
+}
+
 func negativePriceTest(t *testing.T, method string) {
 	t.Run("should return 400 if price is < 0", func(t *testing.T) {
 		testutils.SetupUsersDB(t)
 		testutils.SetupDishesAndCategories(t)
 
 		it := assert.New(t)
-		json := `{"id":1,"title":"Meat Supreme","price":-3.22,"category_id":3}`
+		json := `{"id":1,"title":"Meat Supreme","price":{"amount":-322,"currency":"USD"},"category_id":3}`
 		_, c := testutils.LoginAsRandomAdmin(t)
 		var resp *httptest.ResponseRecorder
 
@@ -605,6 +1327,11 @@ func upload(id uint, c *http.Cookie, fileName string, file io.Reader) *httptest.
 	return testutils.UploadReqWithCookie(http.MethodPatch, "/dishes/"+param+"/upload", "image")(c, fileName, file)
 }
 
+func addImage(id uint, c *http.Cookie, fileName string, file io.Reader) *httptest.ResponseRecorder {
+	param := strconv.Itoa(int(id))
+	return testutils.UploadReqWithCookie(http.MethodPost, "/dishes/"+param+"/images", "image")(c, fileName, file)
+}
+
 func imgURL(name string) string {
 	return dish.PathToImg(name)
 }
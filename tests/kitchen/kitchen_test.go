@@ -0,0 +1,182 @@
+package kitchen_test
+
+import (
+	"food_ordering_backend/config"
+	"food_ordering_backend/controllers/kitchen"
+	"food_ordering_backend/controllers/order"
+	"food_ordering_backend/database"
+	"food_ordering_backend/tests/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var orderRepo = order.ProvideRepository(database.MustGetTest())
+
+func TestKitchen(t *testing.T) {
+	t.Run("GET /kitchen/ws", func(t *testing.T) {
+		server := httptest.NewServer(testutils.Router)
+		t.Cleanup(server.Close)
+
+		t.Run("should send queue of created and in progress orders on connect", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			conn := dial(t, server, config.ClientURL.String())
+
+			queue := receiveQueue(t, conn)
+
+			var ids []uint
+			for _, o := range queue.Orders {
+				ids = append(ids, o.ID)
+				testOrder := testutils.FindTestOrderByID(o.ID)
+				it.Equal(testOrder.Status, o.Status)
+
+				if it.Len(o.Items, len(testOrder.Items)) {
+					for i, item := range o.Items {
+						it.Equal(testOrder.Items[i].DishID, item.DishID)
+						it.Equal(testOrder.Items[i].DishTitle, item.DishTitle)
+						it.Equal(testOrder.Items[i].CategoryTitle, item.CategoryTitle)
+						it.Equal(testOrder.Items[i].Quantity, item.Quantity)
+					}
+				}
+			}
+
+			it.ElementsMatch([]uint{2, 4, 5}, ids)
+		})
+
+		t.Run("should change order status and send updated queue", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			conn := dial(t, server, config.ClientURL.String())
+			receiveQueue(t, conn)
+
+			send(t, conn, kitchen.CommandDTO{Type: kitchen.MessageSetStatus, OrderID: 2, Status: order.StatusDone})
+			queue := receiveQueue(t, conn)
+
+			var ids []uint
+			for _, o := range queue.Orders {
+				ids = append(ids, o.ID)
+			}
+			it.ElementsMatch([]uint{4, 5}, ids)
+
+			o, err := orderRepo.FindByID(2)
+			if it.NoError(err) {
+				it.Equal(order.StatusDone, o.Status)
+			}
+		})
+
+		t.Run("should respond with error if command can't be executed", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			conn := dial(t, server, config.ClientURL.String())
+			receiveQueue(t, conn)
+
+			tests := []struct {
+				cmd      interface{}
+				expected string
+			}{
+				{kitchen.CommandDTO{Type: kitchen.MessageSetStatus, OrderID: 4, Status: order.StatusDone}, "Can't change order status"},
+				{kitchen.CommandDTO{Type: kitchen.MessageSetStatus, OrderID: 999, Status: order.StatusDone}, "Order with id 999 doesn't exist"},
+				{kitchen.CommandDTO{Type: kitchen.MessageSetStatus, OrderID: 4, Status: 8}, "Invalid status"},
+				{kitchen.CommandDTO{Type: "delete", OrderID: 4}, "Unknown command"},
+				{"not a command", "Malformed command"},
+			}
+
+			for _, tc := range tests {
+				send(t, conn, tc.cmd)
+
+				var dto kitchen.ErrorDTO
+				require.NoError(t, websocket.JSON.Receive(conn, &dto))
+				it.Equal(kitchen.MessageError, dto.Type)
+				it.Contains(dto.Error, tc.expected)
+			}
+
+			o, err := orderRepo.FindByID(4)
+			if it.NoError(err) {
+				it.Equal(order.StatusCreated, o.Status)
+			}
+		})
+
+		t.Run("should close connection once the session is revoked", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			conn := dialWithCookie(t, server, config.ClientURL.String(), c)
+			receiveQueue(t, conn)
+
+			resp := testutils.ReqWithCookie(http.MethodGet, "/users/logout")(c, "")
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			send(t, conn, kitchen.CommandDTO{Type: kitchen.MessageSetStatus, OrderID: 2, Status: order.StatusDone})
+
+			var dto kitchen.ErrorDTO
+			if it.NoError(websocket.JSON.Receive(conn, &dto)) {
+				it.Equal(kitchen.MessageError, dto.Type)
+				it.Equal(http.StatusText(http.StatusUnauthorized), dto.Error)
+			}
+
+			var data []byte
+			it.Error(websocket.Message.Receive(conn, &data), "expected connection to be closed")
+
+			o, err := orderRepo.FindByID(2)
+			if it.NoError(err) {
+				it.Equal(order.StatusInProgress, o.Status)
+			}
+		})
+
+		t.Run("should reject connections from other origins", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			cfg, err := websocket.NewConfig(wsURL(server), "http://evil.example.com")
+			require.NoError(t, err)
+			cfg.Header.Add("Cookie", c.String())
+
+			_, err = websocket.DialConfig(cfg)
+			assert.Error(t, err)
+		})
+
+		testutils.RunAuthTests(t, http.MethodGet, "/kitchen/ws", true)
+	})
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/kitchen/ws"
+}
+
+// dial opens kitchen websocket as a random admin.
+// Connection will be closed after the test.
+func dial(t *testing.T, server *httptest.Server, origin string) *websocket.Conn {
+	_, c := testutils.LoginAsRandomAdmin(t)
+	return dialWithCookie(t, server, origin, c)
+}
+
+// dialWithCookie opens kitchen websocket with provided session cookie.
+// Connection will be closed after the test.
+func dialWithCookie(t *testing.T, server *httptest.Server, origin string, c *http.Cookie) *websocket.Conn {
+	cfg, err := websocket.NewConfig(wsURL(server), origin)
+	require.NoError(t, err)
+	cfg.Header.Add("Cookie", c.String())
+
+	conn, err := websocket.DialConfig(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.SetDeadline(time.Now().Add(10*time.Second)))
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, v interface{}) {
+	require.NoError(t, websocket.JSON.Send(conn, v))
+}
+
+func receiveQueue(t *testing.T, conn *websocket.Conn) kitchen.QueueDTO {
+	var dto kitchen.QueueDTO
+	require.NoError(t, websocket.JSON.Receive(conn, &dto))
+	require.Equal(t, kitchen.MessageQueue, dto.Type)
+	return dto
+}
@@ -1,17 +1,26 @@
 package order_test
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"food_ordering_backend/common"
+	"food_ordering_backend/config"
 	"food_ordering_backend/controllers/dish"
 	"food_ordering_backend/controllers/order"
+	"food_ordering_backend/controllers/user"
 	"food_ordering_backend/database"
 	"food_ordering_backend/tests/testutils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var db = database.MustGetTest()
@@ -33,6 +42,33 @@ func TestOrders(t *testing.T) {
 			verifyResponse(t, len(testutils.TestOrders), send(c, ""))
 		})
 
+		t.Run("should keep orders of deleted users", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			deleted := testutils.TestUsers[1]
+
+			resp := testutils.ReqWithCookie(http.MethodDelete, fmt.Sprintf("/users/%d", deleted.ID))(c, "")
+			require.Equal(t, http.StatusNoContent, resp.Code)
+
+			resp = send(c, "")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto order.DTOsWithPagination
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) && it.Len(dto.Orders, len(testutils.TestOrders)) {
+					for _, o := range dto.Orders {
+						if testutils.FindTestOrderByID(o.ID).UserID == deleted.ID {
+							it.Zero(o.UserID)
+							it.Zero(o.User.ID)
+						} else {
+							it.NotZero(o.UserID)
+						}
+					}
+				}
+			}
+		})
+
 		t.Run("should work with pagination", func(t *testing.T) {
 			it := assert.New(t)
 			testutils.SetupOrdersDB(t)
@@ -73,12 +109,209 @@ func TestOrders(t *testing.T) {
 			}
 		})
 
+		t.Run("should filter orders", func(t *testing.T) {
+			it := assert.New(t)
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			tests := []struct {
+				query       string
+				expectedIDs []uint
+			}{
+				{"status=0", []uint{4, 5}},
+				{"status=0&status=2", []uint{1, 4, 5}},
+				{"status=0,3", []uint{3, 4, 5}},
+				{"dish_id=5", []uint{2, 5}},
+				{"dish_id=7&status=0", []uint{4}},
+				{"email=kallie_larson", []uint{1, 2, 5}},
+				{"email=kallie%25larson", []uint{}},
+				{"email=_", []uint{1, 2, 3, 5}},
+				{"email=hotmail.com&status=3", []uint{3}},
+				{"max_total=" + totalOf(3) + "&min_total=" + totalOf(3), []uint{3}},
+				{"created_from=2000-01-01", []uint{1, 2, 3, 4, 5}},
+				{"created_to=2000-01-01", []uint{}},
+			}
+
+			for _, tc := range tests {
+				resp := testutils.ReqWithCookie(http.MethodGet, "/orders?"+tc.query)(c, "")
+
+				if it.Equal(http.StatusOK, resp.Code, tc.query) {
+					var dto order.DTOsWithPagination
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+						it.Equal(tc.expectedIDs, orderIDs(dto.Orders), tc.query)
+						it.Equal(len(tc.expectedIDs), dto.Pagination.Total, tc.query)
+					}
+				}
+			}
+		})
+
+		t.Run("should ignore email filter and show only own orders to customers", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			query := "/orders?email=" + testutils.TestUsersDTOs[1].Email
+			verifyResponse(t, 3, testutils.ReqWithCookie(http.MethodGet, query)(c, ""))
+		})
+
+		t.Run("should sort orders", func(t *testing.T) {
+			it := assert.New(t)
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			orders := make([]order.Order, len(testutils.TestOrders))
+			copy(orders, testutils.TestOrders)
+			sort.SliceStable(orders, func(i, j int) bool {
+				if orders[i].Total.Amount == orders[j].Total.Amount {
+					return orders[i].ID < orders[j].ID
+				}
+				return orders[i].Total.Amount > orders[j].Total.Amount
+			})
+
+			expectedIDs := make([]uint, len(orders))
+			for i, o := range orders {
+				expectedIDs[i] = o.ID
+			}
+
+			resp := testutils.ReqWithCookie(http.MethodGet, "/orders?sort=total&order=desc")(c, "")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto order.DTOsWithPagination
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(expectedIDs, orderIDs(dto.Orders))
+				}
+			}
+		})
+
+		t.Run("should return 400 if filter is invalid", func(t *testing.T) {
+			it := assert.New(t)
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			queries := []string{
+				"status=8",
+				"status=abc",
+				"created_from=yesterday",
+				"updated_to=2021-13-01",
+				"dish_id=-1",
+				"min_total=1.5",
+				"sort=password",
+				"order=random",
+			}
+
+			for _, query := range queries {
+				resp := testutils.ReqWithCookie(http.MethodGet, "/orders?"+query)(c, "")
+				it.Equal(http.StatusBadRequest, resp.Code, query)
+			}
+		})
+
 		testutils.RunAuthTests(t, http.MethodGet, "/orders", false)
 	})
 
+	t.Run("GET /orders/stream", func(t *testing.T) {
+		server := httptest.NewServer(testutils.Router)
+		t.Cleanup(server.Close)
+
+		t.Run("should stream events of own orders to customer", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			otherC := testutils.LoginAs(t, testutils.TestUsersDTOs[1])
+			create := testutils.ReqWithCookie(http.MethodPost, "/orders")
+
+			events, cancel := subscribe(t, server, c)
+			defer cancel()
+
+			require.Equal(t, http.StatusCreated, create(otherC, `{"items": [{"id": 1, "quantity": 1}]}`).Code)
+			resp := create(c, `{"items": [{"id": 2, "quantity": 1}]}`)
+			require.Equal(t, http.StatusCreated, resp.Code)
+
+			var created order.ResponseDTO
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+			select {
+			case e := <-events:
+				it.Equal(string(order.EventCreated), e.name)
+				it.Equal(created.ID, e.order.ID)
+				it.Equal(testutils.TestUsers[0].ID, e.order.UserID)
+			case <-time.After(5 * time.Second):
+				it.Fail("event hasn't been received")
+			}
+		})
+
+		t.Run("should stream events of all orders to admin", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			_, adminC := testutils.LoginAsRandomAdmin(t)
+			o := testutils.FindTestOrderByID(4)
+
+			events, cancel := subscribe(t, server, adminC)
+			defer cancel()
+
+			resp := testutils.ReqWithCookie(http.MethodPatch, "/orders/4")(adminC, `{"status": 1}`)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			select {
+			case e := <-events:
+				it.Equal(string(order.EventStatusChanged), e.name)
+				it.Equal(o.ID, e.order.ID)
+				it.Equal(order.StatusInProgress, e.order.Status)
+			case <-time.After(5 * time.Second):
+				it.Fail("event hasn't been received")
+			}
+		})
+
+		t.Run("should remove subscription when client disconnects", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			noSubscribers := func() bool {
+				return order.DefaultHub.Len() == 0
+			}
+
+			// streams of previous tests might still be closing
+			require.Eventually(t, noSubscribers, 5*time.Second, 10*time.Millisecond)
+
+			_, cancel := subscribe(t, server, c)
+			require.Equal(t, 1, order.DefaultHub.Len())
+			cancel()
+
+			assert.Eventually(t, noSubscribers, 5*time.Second, 10*time.Millisecond)
+		})
+
+		testutils.RunAuthTests(t, http.MethodGet, "/orders/stream", false)
+	})
+
 	t.Run("POST /orders", func(t *testing.T) {
 		send := testutils.ReqWithCookie(http.MethodPost, "/orders")
 
+		t.Run("should return 403 until user verifies email if it's required", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			reqJSON := `{"items": [{"id":  1, "quantity": 2}]}`
+			dto := user.AuthDTO{Email: "unverified@user.com", Password: "secretpass"}
+			data, _ := json.Marshal(&dto)
+			resp := testutils.SendReq(http.MethodPost, "/users/signup")(string(data))
+			require.Equal(t, http.StatusCreated, resp.Code)
+			c := testutils.FindCookieByName(resp.Result(), user.SessionCookieName)
+			addAddress := testutils.ReqWithCookie(http.MethodPost, "/users/me/addresses")
+			require.Equal(t, http.StatusCreated, addAddress(c, `{"line1": "1 Main St", "city": "Springfield"}`).Code)
+
+			it.Equal(http.StatusForbidden, send(c, reqJSON).Code)
+
+			config.RequireVerifiedEmail = false
+			t.Cleanup(func() {
+				config.RequireVerifiedEmail = true
+			})
+			it.Equal(http.StatusCreated, send(c, reqJSON).Code)
+			config.RequireVerifiedEmail = true
+
+			verify := testutils.SendReq(http.MethodGet, "/users/verify?token="+testutils.TokenFromMail(t, dto.Email))
+			require.Equal(t, http.StatusOK, verify("").Code)
+			it.Equal(http.StatusCreated, send(c, reqJSON).Code)
+		})
+
 		t.Run("should create an order and return it", func(t *testing.T) {
 			testutils.SetupUsersDB(t)
 			testutils.SetupDishesAndCategories(t)
@@ -96,7 +329,7 @@ func TestOrders(t *testing.T) {
 					it.Equal(dto.CreatedAt, dto.UpdatedAt)
 					it.Equal(order.StatusCreated, dto.Status)
 					it.Equal(userDTO.Email, dto.User.Email)
-					it.Equal(7.29, dto.Total)
+					it.Equal(common.NewMoney(729), dto.Total)
 
 					if it.Len(dto.Items, 2) {
 						item1, item2 := dto.Items[0], dto.Items[1]
@@ -116,6 +349,239 @@ func TestOrders(t *testing.T) {
 			}
 		})
 
+		t.Run("should deliver the order to the default address of the user", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			a := testutils.FindTestAddressByUserID(testutils.TestUsers[0].ID)
+
+			resp := send(c, `{"items": [{"id": 1, "quantity": 1}]}`)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				var dto order.ResponseDTO
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(a.Line1, dto.DeliveryAddress.Line1)
+					it.Equal(a.City, dto.DeliveryAddress.City)
+				}
+			}
+		})
+
+		t.Run("should deliver the order to provided address and keep its copy", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			u := testutils.TestUsers[0]
+			require.NoError(t, db.Model(&user.User{ID: u.ID}).Updates(map[string]interface{}{"name": "John Doe", "phone": "+14155550000"}).Error)
+			a := user.Address{UserID: u.ID, Line1: "2 Work Rd", Line2: "Floor 3", City: "Shelbyville", PostalCode: "54321", Comment: "Ask at the desk"}
+			require.NoError(t, db.Omit("User").Create(&a).Error)
+
+			resp := send(c, fmt.Sprintf(`{"items": [{"id": 1, "quantity": 1}], "address_id": %d}`, a.ID))
+			require.Equal(t, http.StatusCreated, resp.Code)
+
+			var created order.ResponseDTO
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+			expected := order.DeliveryAddressDTO{
+				Recipient:  "John Doe",
+				Phone:      "+14155550000",
+				Line1:      a.Line1,
+				Line2:      a.Line2,
+				City:       a.City,
+				PostalCode: a.PostalCode,
+				Comment:    a.Comment,
+			}
+			it.Equal(expected, created.DeliveryAddress)
+
+			require.NoError(t, db.Delete(&user.Address{}, a.ID).Error)
+			o, err := orderRepo.FindByID(created.ID)
+
+			if it.NoError(err) {
+				it.Equal(expected, order.ToResponseDTO(o).DeliveryAddress)
+			}
+		})
+
+		t.Run("should return 400 if address with provided id doesn't belong to the user", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			other := testutils.FindTestAddressByUserID(testutils.TestUsers[1].ID)
+
+			resp := send(c, fmt.Sprintf(`{"items": [{"id": 1, "quantity": 1}], "address_id": %d}`, other.ID))
+
+			it.Equal(http.StatusBadRequest, resp.Code)
+			it.Contains(resp.Body.String(), fmt.Sprintf("Address with id %d doesn't exist", other.ID))
+		})
+
+		t.Run("should return 422 if the user has no addresses", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			require.NoError(t, db.Where("user_id = ?", testutils.TestUsers[0].ID).Delete(&user.Address{}).Error)
+
+			resp := send(c, `{"items": [{"id": 1, "quantity": 1}]}`)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		})
+
+		t.Run("should keep dish price and title from the moment of creation", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomUser(t)
+
+			resp := send(c, `{"items": [{"id":  1, "quantity": 2}]}`)
+			require.Equal(t, http.StatusCreated, resp.Code)
+
+			var created order.ResponseDTO
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+			original := testutils.FindTestDishByID(1)
+
+			if it.Len(created.Items, 1) {
+				it.Equal(original.Category.Title, created.Items[0].Dish.Category.Title)
+			}
+
+			var items []order.Item
+			if it.NoError(db.Where("order_id = ?", created.ID).Find(&items).Error) && it.Len(items, 1) {
+				it.Equal(original.Category.Title, items[0].CategoryTitle, "expected category title to be saved")
+			}
+
+			d := testutils.FindTestDishByID(1)
+			d.Title = "Renamed Salad"
+			d.Price = common.NewMoney(9999)
+			require.NoError(t, db.Omit("Category").Save(&d).Error)
+
+			o, err := orderRepo.FindByID(created.ID)
+
+			if it.NoError(err) && it.Len(o.Items, 1) {
+				dto := order.ToResponseDTO(o)
+
+				it.Equal(original.Title, dto.Items[0].Dish.Title)
+				it.Equal(original.Category.Title, dto.Items[0].Dish.Category.Title)
+				it.Equal(original.Price, dto.Items[0].Dish.Price)
+				it.Equal(original.Price, dto.Items[0].UnitPrice)
+				it.Equal(original.Price.Mul(2), dto.Items[0].Cost)
+				it.Equal(original.Price.Mul(2), dto.Total)
+			}
+		})
+
+		t.Run("should return the original response to a request with the same idempotency key", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			otherC := testutils.LoginAs(t, testutils.TestUsersDTOs[1])
+
+			first := sendWithKey(c, "checkout-1", `{"items": [{"id": 1, "quantity": 2}]}`)
+			require.Equal(t, http.StatusCreated, first.Code)
+			it.Empty(first.Header().Get(order.IdempotentReplayedHeader))
+
+			repeated := sendWithKey(c, "checkout-1", `{ "items": [{"quantity": 2, "id": 1}] }`)
+
+			if it.Equal(http.StatusCreated, repeated.Code) {
+				it.Equal("true", repeated.Header().Get(order.IdempotentReplayedHeader))
+				it.JSONEq(first.Body.String(), repeated.Body.String())
+			}
+
+			// keys are unique per user
+			it.Equal(http.StatusCreated, sendWithKey(otherC, "checkout-1", `{"items": [{"id": 1, "quantity": 2}]}`).Code)
+
+			var count int64
+			if it.NoError(db.Model(&order.Order{}).Count(&count).Error) {
+				it.EqualValues(2, count)
+			}
+		})
+
+		t.Run("should create a new order if idempotency key has expired", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			body := `{"items": [{"id": 1, "quantity": 2}]}`
+
+			first := sendWithKey(c, "checkout-1", body)
+			require.Equal(t, http.StatusCreated, first.Code)
+
+			expiredAt := time.Now().Add(-config.IdempotencyKeyRetention - time.Minute)
+			require.NoError(t, db.Model(&order.IdempotencyKey{}).Where("key = ?", "checkout-1").Update("created_at", expiredAt).Error)
+
+			repeated := sendWithKey(c, "checkout-1", body)
+
+			if it.Equal(http.StatusCreated, repeated.Code) {
+				it.Empty(repeated.Header().Get(order.IdempotentReplayedHeader))
+				it.NotEqual(first.Body.String(), repeated.Body.String())
+			}
+		})
+
+		t.Run("should let a repeated request claim idempotency key once the lease of the original one expires", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			body := `{"items": [{"id": 1, "quantity": 2}]}`
+
+			require.Equal(t, http.StatusCreated, sendWithKey(c, "checkout-1", body).Code)
+
+			// pretend the original request is still being processed
+			var original order.IdempotencyKey
+			require.NoError(t, db.Where("key = ?", "checkout-1").First(&original).Error)
+			require.NoError(t, db.Model(&original).Updates(map[string]interface{}{"status_code": 0, "response": nil}).Error)
+
+			it.Equal(http.StatusConflict, sendWithKey(c, "checkout-1", body).Code)
+
+			abandonedAt := time.Now().Add(-config.IdempotencyKeyLease - time.Second)
+			require.NoError(t, db.Model(&original).Update("created_at", abandonedAt).Error)
+
+			repeated := sendWithKey(c, "checkout-1", body)
+
+			if it.Equal(http.StatusCreated, repeated.Code) {
+				it.Empty(repeated.Header().Get(order.IdempotentReplayedHeader))
+
+				var k order.IdempotencyKey
+				if it.NoError(db.First(&k, original.ID).Error) {
+					it.Equal(http.StatusCreated, k.StatusCode)
+					it.JSONEq(repeated.Body.String(), string(k.Response), "expected response to be stored with the order")
+				}
+			}
+
+			it.ErrorIs(orderRepo.CompleteIdempotencyKey(original, http.StatusCreated, []byte("{}")), order.ErrIdempotencyKeyLost,
+				"expected abandoned request not to complete the claimed key")
+		})
+
+		t.Run("should return 422 if idempotency key has been used for a different request", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			require.Equal(t, http.StatusCreated, sendWithKey(c, "checkout-1", `{"items": [{"id": 1, "quantity": 2}]}`).Code)
+			resp := sendWithKey(c, "checkout-1", `{"items": [{"id": 1, "quantity": 3}]}`)
+
+			it.Equal(http.StatusUnprocessableEntity, resp.Code)
+		})
+
+		t.Run("should allow to retry failed request with the same idempotency key", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			it.Equal(http.StatusBadRequest, sendWithKey(c, "checkout-1", `{"items": [{"id": 9999, "quantity": 1}]}`).Code)
+			it.Equal(http.StatusCreated, sendWithKey(c, "checkout-1", `{"items": [{"id": 1, "quantity": 1}]}`).Code)
+		})
+
+		t.Run("should return 400 if idempotency key is too long", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			resp := sendWithKey(c, strings.Repeat("k", 256), `{"items": [{"id": 1, "quantity": 1}]}`)
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+
 		t.Run("should return 422 if json is incorrect or contains validation errors", func(t *testing.T) {
 			testutils.SetupUsersDB(t)
 			it := assert.New(t)
@@ -132,6 +598,114 @@ func TestOrders(t *testing.T) {
 				it.Equal(http.StatusUnprocessableEntity, resp.Code)
 			}
 		})
+		t.Run("should keep chosen options and add their price deltas to the total", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			size, extras := testutils.TestOptionGroups[0], testutils.TestOptionGroups[1]
+			large, cheese := size.Options[1], extras.Options[0]
+
+			resp := send(c, fmt.Sprintf(`{"items": [{"id": 6, "quantity": 2, "options": [%d, %d]}, {"id": 1, "quantity": 1}]}`, cheese.ID, large.ID))
+			require.Equal(t, http.StatusCreated, resp.Code)
+
+			var created order.ResponseDTO
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+			it.Equal(common.NewMoney((469+150+50)*2+265), created.Total)
+
+			if it.Len(created.Items, 2) {
+				item := created.Items[0]
+				it.Equal(common.NewMoney(469), item.UnitPrice)
+				it.Equal(common.NewMoney((469+150+50)*2), item.Cost)
+				it.Equal([]order.ItemOptionResponseDTO{
+					{OptionID: large.ID, GroupTitle: "Size", Title: "Large", PriceDelta: common.NewMoney(150)},
+					{OptionID: cheese.ID, GroupTitle: "Extras", Title: "Extra cheese", PriceDelta: common.NewMoney(50)},
+				}, item.Options)
+				it.Empty(created.Items[1].Options)
+			}
+
+			require.NoError(t, db.Delete(&dish.OptionGroup{}, size.ID).Error)
+			o, err := orderRepo.FindByID(created.ID)
+
+			if it.NoError(err) {
+				dto := order.ToResponseDTO(o)
+				it.Equal(created.Total, dto.Total)
+
+				if it.Len(dto.Items[0].Options, 2) {
+					it.Zero(dto.Items[0].Options[0].OptionID, "expected deleted option to be unlinked")
+					it.Equal("Large", dto.Items[0].Options[0].Title)
+				}
+			}
+		})
+
+		t.Run("should return 422 if chosen options are illegal", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			testutils.SetupDishOptions(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			small, large := testutils.TestOptionGroups[0].Options[0], testutils.TestOptionGroups[0].Options[1]
+			tests := []struct {
+				reason  string
+				reqJSON string
+			}{
+				{"required group is skipped", `{"items": [{"id": 6, "quantity": 1}]}`},
+				{"too many options are chosen from a group", fmt.Sprintf(`{"items": [{"id": 6, "quantity": 1, "options": [%d, %d]}]}`, small.ID, large.ID)},
+				{"option belongs to another dish", fmt.Sprintf(`{"items": [{"id": 1, "quantity": 1, "options": [%d]}]}`, small.ID)},
+			}
+
+			for _, tc := range tests {
+				resp := send(c, tc.reqJSON)
+				it.Equalf(http.StatusUnprocessableEntity, resp.Code, "expected 422 if %s", tc.reason)
+			}
+
+			var count int64
+			require.NoError(t, db.Model(&order.Order{}).Count(&count).Error)
+			it.Zero(count)
+		})
+
+		t.Run("should take ordered portions from stock", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			setStock(t, 1, 5)
+			_, c := testutils.LoginAsRandomUser(t)
+
+			resp := send(c, `{"items": [{"id": 1, "quantity": 2}, {"id": 3, "quantity": 1}, {"id": 1, "quantity": 1}]}`)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				it.Equal(2, *stockOf(t, 1))
+				it.Nil(stockOf(t, 3), "expected unlimited stock to stay unlimited")
+			}
+		})
+
+		t.Run("should return 409 if dish is unavailable or doesn't have enough portions", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.SetupDishesAndCategories(t)
+			it := assert.New(t)
+			setStock(t, 1, 2)
+			require.NoError(t, db.Model(&dish.Dish{ID: 3}).Update("available", false).Error)
+			_, c := testutils.LoginAsRandomUser(t)
+
+			resp := send(c, `{"items": [{"id": 1, "quantity": 2}, {"id": 3, "quantity": 1}]}`)
+			if it.Equal(http.StatusConflict, resp.Code) {
+				it.Contains(resp.Body.String(), "Dish with id 3")
+			}
+
+			resp = send(c, `{"items": [{"id": 1, "quantity": 3}]}`)
+			if it.Equal(http.StatusConflict, resp.Code) {
+				it.Contains(resp.Body.String(), "Dish with id 1")
+			}
+
+			it.Equal(2, *stockOf(t, 1), "expected stock to be kept")
+
+			var count int64
+			require.NoError(t, db.Model(&order.Order{}).Count(&count).Error)
+			it.Zero(count)
+		})
+
 		t.Run("should return 400 if dish with provided id doesn't exist", func(t *testing.T) {
 			testutils.SetupDishesAndCategories(t)
 			testutils.SetupUsersDB(t)
@@ -153,21 +727,108 @@ func TestOrders(t *testing.T) {
 			return testutils.ReqWithCookie(http.MethodPatch, uri)(c, "")
 		}
 
-		t.Run("should change orders status", func(t *testing.T) {
+		t.Run("should change orders status", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(4)
+
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			for _, status := range []order.Status{order.StatusInProgress, order.StatusDone} {
+				resp := sendWithParam(c, o.ID, status)
+
+				if it.Equal(http.StatusNoContent, resp.Code) {
+					verifyStatusChange(t, o.ID, status)
+				}
+			}
+		})
+
+		t.Run("should return portions to stock when order is canceled", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			setStock(t, 5, 0)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			if it.Equal(http.StatusNoContent, sendWithParam(c, 5, order.StatusCanceled).Code) {
+				it.Equal(3, *stockOf(t, 5))
+			}
+		})
+
+		t.Run("should record status changes in order's history", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(4)
+			adminDTO, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := sendWithParam(c, o.ID, order.StatusInProgress)
+			require.Equal(t, http.StatusNoContent, resp.Code)
+			resp = sendWithParam(c, o.ID, order.StatusCanceled)
+			require.Equal(t, http.StatusNoContent, resp.Code)
+
+			history, err := orderRepo.FindStatusHistory(o.ID)
+
+			if it.NoError(err) && it.Len(history, 2) {
+				it.Equal(order.StatusCreated, history[0].FromStatus)
+				it.Equal(order.StatusInProgress, history[0].ToStatus)
+				it.Equal(order.StatusInProgress, history[1].FromStatus)
+				it.Equal(order.StatusCanceled, history[1].ToStatus)
+
+				for _, change := range history {
+					it.Equal(adminDTO.Email, change.ChangedBy.Email)
+					it.NotZero(change.CreatedAt)
+				}
+			}
+		})
+
+		t.Run("should allow kitchen staff to change status of any order", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(4)
+			dto := testutils.TestUsersDTOs[1]
+			testutils.GrantRoles(t, testutils.TestUsers[1], user.RoleKitchen)
+			c := testutils.LoginAs(t, dto)
+
+			resp := sendWithParam(c, o.ID, order.StatusInProgress)
+
+			if it.Equal(http.StatusNoContent, resp.Code) {
+				verifyStatusChange(t, o.ID, order.StatusInProgress)
+			}
+		})
+
+		t.Run("should return 401 if user can't update statuses", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			o := testutils.FindTestOrderByID(4)
+			dto := testutils.TestUsersDTOs[1]
+			testutils.GrantRoles(t, testutils.TestUsers[1], user.RoleMenuManager)
+			c := testutils.LoginAs(t, dto)
+
+			resp := sendWithParam(c, o.ID, order.StatusInProgress)
+
+			if assert.Equal(t, http.StatusUnauthorized, resp.Code) {
+				verifyStatusNotChange(t, o.ID, o.Status)
+			}
+		})
+
+		t.Run("should return 409 if status transition is illegal", func(t *testing.T) {
 			testutils.SetupOrdersDB(t)
 			it := assert.New(t)
-			o := testutils.TestOrders[0]
-			o.Status = order.StatusDone
-
 			_, c := testutils.LoginAsRandomAdmin(t)
+			tests := []struct {
+				orderID uint
+				status  order.Status
+			}{
+				{1, order.StatusCreated},
+				{1, order.StatusInProgress},
+				{2, order.StatusCreated},
+				{3, order.StatusDone},
+				{4, order.StatusDone},
+			}
 
-			if it.NoError(db.Omit("User").Save(&o).Error) {
-				for _, status := range order.Statuses {
-					resp := sendWithParam(c, o.ID, status)
+			for _, tc := range tests {
+				resp := sendWithParam(c, tc.orderID, tc.status)
 
-					if it.Equal(http.StatusNoContent, resp.Code) {
-						verifyStatusChange(t, o.ID, status)
-					}
+				if it.Equal(http.StatusConflict, resp.Code) {
+					verifyStatusNotChange(t, tc.orderID, testutils.FindTestOrderByID(tc.orderID).Status)
 				}
 			}
 		})
@@ -234,7 +895,6 @@ func TestOrders(t *testing.T) {
 			dto := order.UpdateDTO{
 				Status: order.StatusInProgress,
 				UserID: 1,
-				Total:  1337,
 				Items:  []order.ItemCreateDTO{{ID: 5, Quantity: 4}, {ID: 1, Quantity: 20}},
 			}
 			body, _ := json.Marshal(&dto)
@@ -249,7 +909,7 @@ func TestOrders(t *testing.T) {
 					it.NotEqual(initialOrder.UpdatedAt, respDTO.UpdatedAt)
 					it.Equal(dto.Status, respDTO.Status)
 					it.Equal(dto.UserID, respDTO.UserID)
-					it.Equal(dto.Total, respDTO.Total)
+					it.Equal(common.NewMoney(6980), respDTO.Total, "expected total to be calculated from items")
 
 					if it.Len(respDTO.Items, 2) {
 						it.NotEqual(dto.Items[0].ID, respDTO.Items[0].ID)
@@ -272,21 +932,196 @@ func TestOrders(t *testing.T) {
 			}
 		})
 
+		t.Run("should save category titles of new items", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(4)
+			expected := testutils.FindTestDishByID(5).Category.Title
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := order.UpdateDTO{
+				Status: o.Status,
+				UserID: o.UserID,
+				Items:  []order.ItemCreateDTO{{ID: 5, Quantity: 1}},
+			}
+			body, _ := json.Marshal(&dto)
+
+			resp := sendWithParam(o.ID, string(body), c)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var respDTO order.ResponseDTO
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) && it.Len(respDTO.Items, 1) {
+					it.Equal(expected, respDTO.Items[0].Dish.Category.Title)
+				}
+
+				var items []order.Item
+				if it.NoError(db.Where("order_id = ?", o.ID).Find(&items).Error) && it.Len(items, 1) {
+					it.Equal(expected, items[0].CategoryTitle, "expected category title to be saved")
+				}
+			}
+		})
+
+		t.Run("should keep prices and titles of existing items", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			initialOrder := testutils.FindTestOrderByID(4)
+			kept, deleted := initialOrder.Items[0], initialOrder.Items[1]
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			require.NoError(t, db.Model(&dish.Dish{ID: kept.DishID}).Updates(map[string]interface{}{
+				"title":        "Renamed dish",
+				"price_amount": kept.UnitPrice.Amount + 1000,
+			}).Error)
+			require.NoError(t, db.Delete(&dish.Dish{ID: deleted.DishID}).Error)
+
+			dto := order.UpdateDTO{
+				Status: initialOrder.Status,
+				UserID: initialOrder.UserID,
+				Items: []order.ItemCreateDTO{
+					{ItemID: kept.ID, Quantity: kept.Quantity + 1},
+					{ItemID: deleted.ID, Quantity: deleted.Quantity},
+					{ID: 5, Quantity: 1},
+				},
+			}
+			body, _ := json.Marshal(&dto)
+
+			resp := sendWithParam(initialOrder.ID, string(body), c)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var respDTO order.ResponseDTO
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) && it.Len(respDTO.Items, 3) {
+					items := make(map[uint]order.ItemResponseDTO)
+					for _, item := range respDTO.Items {
+						items[item.ID] = item
+					}
+
+					if item, ok := items[kept.ID]; it.True(ok, "expected item to be kept") {
+						it.Equal(kept.Quantity+1, item.Quantity)
+						it.Equal(kept.UnitPrice, item.UnitPrice)
+						it.Equal(kept.DishTitle, item.Dish.Title)
+					}
+
+					if item, ok := items[deleted.ID]; it.True(ok, "expected item of deleted dish to be kept") {
+						it.Zero(item.DishID)
+						it.Equal(deleted.DishTitle, item.Dish.Title)
+					}
+
+					delete(items, kept.ID)
+					delete(items, deleted.ID)
+					for _, item := range items {
+						it.Equal(uint(5), item.DishID)
+					}
+				}
+			}
+		})
+
+		t.Run("should return 400 if item with provided id doesn't belong to the order", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			foreign := testutils.FindTestOrderByID(4).Items[0].ID
+			body := fmt.Sprintf(`{"status": 1, "user_id": 1, "items": [{"item_id": %d, "quantity": 4}]}`, foreign)
+
+			resp := sendWithParam(2, body, c)
+
+			if assert.Equal(t, http.StatusBadRequest, resp.Code) {
+				assert.Contains(t, resp.Body.String(), fmt.Sprintf("Item with id %d doesn't exist", foreign))
+			}
+		})
+
+		t.Run("should apply adjustments to the total and revoke unlisted ones", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			adminDTO, c := testutils.LoginAsRandomAdmin(t)
+			o := testutils.FindTestOrderByID(2)
+			dto := order.UpdateDTO{
+				Status: o.Status,
+				UserID: o.UserID,
+				Items:  []order.ItemCreateDTO{{ID: 5, Quantity: 1}},
+				Adjustments: []order.AdjustmentCreateDTO{
+					{Kind: order.AdjustmentDiscount, Value: common.NewMoney(100), Reason: "Loyal customer"},
+					{Kind: order.AdjustmentSurcharge, Value: common.NewMoney(250), Reason: "Night delivery"},
+				},
+			}
+			body, _ := json.Marshal(&dto)
+
+			resp := sendWithParam(o.ID, string(body), c)
+			require.Equal(t, http.StatusOK, resp.Code)
+
+			var respDTO order.ResponseDTO
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&respDTO))
+
+			it.Equal(common.NewMoney(420-100+250), respDTO.Total)
+
+			if it.Len(respDTO.Adjustments, 2) {
+				for i, a := range respDTO.Adjustments {
+					it.NotZero(a.ID)
+					it.NotZero(a.CreatedAt)
+					it.Equal(dto.Adjustments[i].Kind, a.Kind)
+					it.Equal(dto.Adjustments[i].Value, a.Value)
+					it.Equal(dto.Adjustments[i].Reason, a.Reason)
+					it.Equal(adminDTO.Email, a.CreatedBy.Email)
+				}
+
+				dto.Adjustments = []order.AdjustmentCreateDTO{{ID: respDTO.Adjustments[1].ID}}
+				body, _ = json.Marshal(&dto)
+
+				resp = sendWithParam(o.ID, string(body), c)
+
+				if it.Equal(http.StatusOK, resp.Code) && it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) {
+					it.Equal(common.NewMoney(420+250), respDTO.Total)
+
+					if it.Len(respDTO.Adjustments, 2, "expected revoked adjustment to be kept") {
+						revoked := respDTO.Adjustments[0]
+						it.Equal(order.AdjustmentDiscount, revoked.Kind)
+						it.NotNil(revoked.RevokedAt)
+						if it.NotNil(revoked.RevokedByID) && it.NotNil(revoked.RevokedBy) {
+							it.Equal(revoked.CreatedByID, *revoked.RevokedByID)
+							it.Equal(adminDTO.Email, revoked.RevokedBy.Email)
+						}
+
+						it.Equal(order.AdjustmentSurcharge, respDTO.Adjustments[1].Kind)
+						it.Nil(respDTO.Adjustments[1].RevokedAt)
+						it.Nil(respDTO.Adjustments[1].RevokedBy)
+					}
+				}
+
+				dto.Adjustments = []order.AdjustmentCreateDTO{{ID: respDTO.Adjustments[0].ID}}
+				body, _ = json.Marshal(&dto)
+
+				resp = sendWithParam(o.ID, string(body), c)
+				it.Equal(http.StatusBadRequest, resp.Code, "expected revoked adjustment not to be restorable")
+			}
+		})
+
+		t.Run("should return 400 if adjustment with provided id doesn't belong to the order", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			body := `{"status": 1, "user_id": 1, "items": [{"id": 2, "quantity": 4}], "adjustments": [{"id": 1337}]}`
+
+			resp := sendWithParam(2, body, c)
+
+			if assert.Equal(t, http.StatusBadRequest, resp.Code) {
+				assert.Contains(t, resp.Body.String(), "Adjustment with id 1337 doesn't exist")
+			}
+		})
+
 		t.Run("should return 422 if json in request is malformed or invalid", func(t *testing.T) {
 			testutils.SetupOrdersDB(t)
 			orderID := uint(2)
 			tests := []struct{ reqJSON, reason string }{
-				{`{"status": 2, "user_id": 1, "total": 23, "items": [{"id"}]}`, "malformed"},
-				{`{"status": -1, "user_id": 1, "total": 12.88, "items": [{"id": 2, "quantity": 4}]}`, "status < 0"},
-				{`{"status": 4, "user_id": 1, "total": 23, "items": [{"id": 2, "quantity": 4}]}`, "status > 4"},
-				{`{"user_id": 1, "total": 23, "items": [{"id": 2, "quantity": 4}]}`, "no status field"},
-				{`{"status": 4, "total": 23, "items": [{"id": 2, "quantity": 4}]}`, "no user id"},
-				{`{"status": 4, "user_id": 1, "total": 12.88, "items": [{"id": 2, "quantity": 4}]}`, "total < 0"},
-				{`{"status": 4, "user_id": 1, "total": 12.88, "items": []}`, "empty items array"},
-				{`{"status": 4, "user_id": 1, "total": 12.88}`, "no items field"},
-				{`{"status": 4, "user_id": 1, "total": 12.88, "items": [{"quantity": 4}]}`, "no id field in items"},
-				{`{"status": 4, "user_id": 1, "total": 12.88, "items": [{"id": 2, "quantity": 0}]}`, "quantity is 0"},
-				{`{"status": 4, "user_id": 1, "total": 12.88, "items": [{"id": 2, "quantity": -1}]}`, "quantity < 0"},
+				{`{"status": 2, "user_id": 1, "items": [{"id"}]}`, "malformed"},
+				{`{"status": -1, "user_id": 1, "items": [{"id": 2, "quantity": 4}]}`, "status < 0"},
+				{`{"status": 4, "user_id": 1, "items": [{"id": 2, "quantity": 4}]}`, "status > 4"},
+				{`{"user_id": 1, "items": [{"id": 2, "quantity": 4}]}`, "no status field"},
+				{`{"status": 4, "items": [{"id": 2, "quantity": 4}]}`, "no user id"},
+				{`{"status": 1, "user_id": 1, "items": [{"id": 2, "quantity": 4}], "adjustments": [{"kind": "discount", "value": {"amount": 1289}, "reason": "Too much"}]}`, "total < 0"},
+				{`{"status": 1, "user_id": 1, "items": [{"id": 2, "quantity": 4}], "adjustments": [{"kind": "discount", "value": {"amount": 100}}]}`, "adjustment without reason"},
+				{`{"status": 1, "user_id": 1, "items": [{"id": 2, "quantity": 4}], "adjustments": [{"kind": "discount", "value": {"amount": -100}, "reason": "Negative"}]}`, "negative discount"},
+				{`{"status": 1, "user_id": 1, "items": [{"id": 2, "quantity": 4}], "adjustments": [{"kind": "gift", "value": {"amount": 100}, "reason": "Unknown"}]}`, "unknown adjustment kind"},
+				{`{"status": 4, "user_id": 1, "items": []}`, "empty items array"},
+				{`{"status": 4, "user_id": 1}`, "no items field"},
+				{`{"status": 4, "user_id": 1, "items": [{"quantity": 4}]}`, "no id field in items"},
+				{`{"status": 4, "user_id": 1, "items": [{"id": 2, "quantity": 0}]}`, "quantity is 0"},
+				{`{"status": 4, "user_id": 1, "items": [{"id": 2, "quantity": -1}]}`, "quantity < 0"},
 			}
 			_, c := testutils.LoginAsRandomAdmin(t)
 
@@ -296,6 +1131,101 @@ func TestOrders(t *testing.T) {
 			}
 		})
 
+		t.Run("should return previous items to stock and take new ones", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			setStock(t, 6, 2)
+			setStock(t, 7, 0)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := order.UpdateDTO{
+				Status: order.StatusCreated,
+				UserID: testutils.FindTestOrderByID(4).UserID,
+				Items:  []order.ItemCreateDTO{{ID: 6, Quantity: 3}},
+			}
+			body, _ := json.Marshal(&dto)
+
+			if it.Equal(http.StatusOK, sendWithParam(4, string(body), c).Code) {
+				it.Equal(0, *stockOf(t, 6))
+				it.Equal(2, *stockOf(t, 7))
+			}
+
+			dto.Items = []order.ItemCreateDTO{{ID: 6, Quantity: 5}}
+			body, _ = json.Marshal(&dto)
+
+			if it.Equal(http.StatusConflict, sendWithParam(4, string(body), c).Code) {
+				it.Equal(0, *stockOf(t, 6), "expected stock to be kept")
+			}
+
+			dto.Status = order.StatusCanceled
+			body, _ = json.Marshal(&dto)
+
+			if it.Equal(http.StatusOK, sendWithParam(4, string(body), c).Code) {
+				it.Equal(3, *stockOf(t, 6))
+			}
+		})
+
+		t.Run("should change stock only by the difference in quantities", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(4)
+			setStock(t, 6, 1)
+			setStock(t, 7, 0)
+			require.NoError(t, db.Model(&dish.Dish{ID: 7}).Update("available", false).Error)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := order.UpdateDTO{
+				Status: order.StatusInProgress,
+				UserID: o.UserID,
+				Items: []order.ItemCreateDTO{
+					{ItemID: o.Items[0].ID, Quantity: o.Items[0].Quantity + 1},
+					{ItemID: o.Items[1].ID, Quantity: o.Items[1].Quantity},
+				},
+			}
+			body, _ := json.Marshal(&dto)
+
+			if it.Equal(http.StatusOK, sendWithParam(o.ID, string(body), c).Code, "expected unchanged items not to be checked") {
+				it.Equal(0, *stockOf(t, 6))
+				it.Equal(0, *stockOf(t, 7))
+			}
+
+			dto.Items = []order.ItemCreateDTO{{ID: 6, Quantity: 1}, {ID: 7, Quantity: 1}}
+			body, _ = json.Marshal(&dto)
+
+			if it.Equal(http.StatusOK, sendWithParam(o.ID, string(body), c).Code, "expected decreased items not to be checked") {
+				it.Equal(1, *stockOf(t, 6))
+				it.Equal(1, *stockOf(t, 7))
+			}
+		})
+
+		t.Run("should keep order unchanged if update fails", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			initialOrder := testutils.FindTestOrderByID(4)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := order.UpdateDTO{
+				Status: order.StatusInProgress,
+				UserID: 9999,
+				Items:  []order.ItemCreateDTO{{ID: 5, Quantity: 4}},
+			}
+			body, _ := json.Marshal(&dto)
+
+			resp := sendWithParam(initialOrder.ID, string(body), c)
+
+			if it.Equal(http.StatusInternalServerError, resp.Code) {
+				orderInDB, err := orderRepo.FindByID(initialOrder.ID)
+
+				if it.NoError(err) {
+					it.Equal(initialOrder.Status, orderInDB.Status)
+					it.Equal(initialOrder.UserID, orderInDB.UserID)
+					it.Equal(order.Items(initialOrder.Items).IDs(), order.Items(orderInDB.Items).IDs(), "expected items to be kept")
+				}
+
+				history, err := orderRepo.FindStatusHistory(initialOrder.ID)
+				if it.NoError(err) {
+					it.Empty(history)
+				}
+			}
+		})
+
 		t.Run("should return 404 if order with provided id doesn't exist", func(t *testing.T) {
 			it := assert.New(t)
 			testutils.SetupOrdersDB(t)
@@ -310,8 +1240,176 @@ func TestOrders(t *testing.T) {
 			}
 		})
 
+		t.Run("should return 409 if status transition is illegal", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := order.UpdateDTO{
+				Status: order.StatusCreated,
+				UserID: 1,
+				Items:  []order.ItemCreateDTO{{ID: 5, Quantity: 4}},
+			}
+			body, _ := json.Marshal(&dto)
+
+			resp := sendWithParam(1, string(body), c)
+			assert.Equal(t, http.StatusConflict, resp.Code)
+		})
+
 		testutils.RunAuthTests(t, http.MethodPut, "/orders/69", true)
 	})
+
+	t.Run("POST /orders/:id/cancel", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodPost, fmt.Sprintf("/orders/%d/cancel", id))(c, "")
+		}
+
+		t.Run("should cancel own order and return it", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(5)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			resp := send(c, o.ID)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto order.ResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(o.ID, dto.ID)
+					it.Equal(order.StatusCanceled, dto.Status)
+
+					if it.NotNil(dto.LastTransition) {
+						it.Equal(order.StatusCreated, dto.LastTransition.From)
+						it.Equal(order.StatusCanceled, dto.LastTransition.To)
+						it.Equal(o.UserID, dto.LastTransition.ChangedByID)
+					}
+				}
+
+				verifyStatusChange(t, o.ID, order.StatusCanceled)
+			}
+		})
+
+		t.Run("should return portions to stock", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			setStock(t, 5, 1)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			if assert.Equal(t, http.StatusOK, send(c, 5).Code) {
+				assert.Equal(t, 4, *stockOf(t, 5))
+			}
+		})
+
+		t.Run("should return 403 if order belongs to another user", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[1])
+
+			for _, id := range []uint{4, 5} {
+				if it.Equal(http.StatusForbidden, send(c, id).Code) {
+					verifyStatusNotChange(t, id, order.StatusCreated)
+				}
+			}
+		})
+
+		t.Run("should return 403 if order has already been taken into work", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			for _, id := range []uint{1, 2} {
+				o := testutils.FindTestOrderByID(id)
+				if it.Equal(http.StatusForbidden, send(c, id).Code) {
+					verifyStatusNotChange(t, id, o.Status)
+				}
+			}
+		})
+
+		t.Run("should return 403 if cancel window has expired", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			oldWindow := config.OrderCancelWindow
+			config.OrderCancelWindow = 0
+			t.Cleanup(func() {
+				config.OrderCancelWindow = oldWindow
+			})
+
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			resp := send(c, 5)
+
+			if assert.Equal(t, http.StatusForbidden, resp.Code) {
+				verifyStatusNotChange(t, 5, order.StatusCreated)
+			}
+		})
+
+		t.Run("should return 404 if order with provided id doesn't exist", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			assert.Equal(t, http.StatusNotFound, send(c, 1337).Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPost, "/orders/1/cancel", false)
+	})
+
+	t.Run("GET /orders/:id/history", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodGet, fmt.Sprintf("/orders/%d/history", id))(c, "")
+		}
+
+		t.Run("should return status history of the order", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			it := assert.New(t)
+			o := testutils.FindTestOrderByID(4)
+			_, adminCookie := testutils.LoginAsRandomAdmin(t)
+			patch := testutils.ReqWithCookie(http.MethodPatch, fmt.Sprintf("/orders/%d?status=%d", o.ID, order.StatusInProgress))
+			require.Equal(t, http.StatusNoContent, patch(adminCookie, "").Code)
+
+			userCookie := testutils.LoginAs(t, testutils.TestUsersDTOs[2])
+
+			for _, c := range []*http.Cookie{adminCookie, userCookie} {
+				resp := send(c, o.ID)
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					var dtos []order.StatusChangeDTO
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&dtos)) && it.Len(dtos, 1) {
+						it.Equal(o.ID, dtos[0].OrderID)
+						it.Equal(order.StatusCreated, dtos[0].From)
+						it.Equal(order.StatusInProgress, dtos[0].To)
+					}
+				}
+			}
+
+			resp := testutils.ReqWithCookie(http.MethodGet, "/orders")(adminCookie, "")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto order.DTOsWithPagination
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					for _, respOrder := range dto.Orders {
+						if respOrder.ID == o.ID && it.NotNil(respOrder.LastTransition) {
+							it.Equal(order.StatusInProgress, respOrder.LastTransition.To)
+						} else if respOrder.ID != o.ID {
+							it.Nil(respOrder.LastTransition)
+						}
+					}
+				}
+			}
+		})
+
+		t.Run("should return 403 if order belongs to another user", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[1])
+			resp := send(c, 4)
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+		})
+
+		t.Run("should return 404 if order with provided id doesn't exist", func(t *testing.T) {
+			testutils.SetupOrdersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			resp := send(c, 1337)
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodGet, "/orders/1/history", false)
+	})
 }
 
 func verifyResponse(t *testing.T, expectedLen int, resp *httptest.ResponseRecorder) {
@@ -343,6 +1441,78 @@ func verifyResponse(t *testing.T, expectedLen int, resp *httptest.ResponseRecord
 	}
 }
 
+type streamEvent struct {
+	name  string
+	order order.ResponseDTO
+}
+
+// subscribe opens order events stream and returns a channel of received events.
+// Stream is open once subscribe returns and is closed by calling cancel.
+func subscribe(t *testing.T, server *httptest.Server, c *http.Cookie) (<-chan streamEvent, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/orders/stream", nil)
+	require.NoError(t, err)
+	req.AddCookie(c)
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan streamEvent)
+
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var e streamEvent
+		scanner := bufio.NewScanner(resp.Body)
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				e.name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				if json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &e.order) != nil {
+					return
+				}
+			case line == "" && e.name != "":
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+				e = streamEvent{}
+			}
+		}
+	}()
+
+	return events, cancel
+}
+
+func sendWithKey(c *http.Cookie, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.AddCookie(c)
+	req.Header.Set(order.IdempotencyKeyHeader, key)
+	testutils.Router.ServeHTTP(w, req)
+	return w
+}
+
+func orderIDs(orders []order.ResponseDTO) []uint {
+	ids := make([]uint, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	return ids
+}
+
+func totalOf(orderID uint) string {
+	return strconv.FormatInt(testutils.FindTestOrderByID(orderID).Total.Amount, 10)
+}
+
 func isEqualOrder(t *testing.T, o order.Order, dto order.ResponseDTO) {
 	it := assert.New(t)
 	it.Equal(o.UserID, dto.UserID)
@@ -351,7 +1521,7 @@ func isEqualOrder(t *testing.T, o order.Order, dto order.ResponseDTO) {
 	it.Equal(o.Total, dto.Total)
 	it.Equal(o.User.ID, dto.User.ID)
 	it.Equal(o.User.Email, dto.User.Email)
-	it.Equal(o.User.IsAdmin, dto.User.IsAdmin)
+	it.Equal(o.User.RoleNames(), dto.User.Roles)
 	it.True(testutils.EqualTimestamps(o.User.CreatedAt, dto.User.CreatedAt))
 }
 
@@ -387,3 +1557,13 @@ func verifyStatusNotChange(t *testing.T, orderID uint, status order.Status) {
 		it.Equal(unmodified.Items, o.Items)
 	}
 }
+
+func setStock(t *testing.T, dishID uint, stock int) {
+	require.NoError(t, db.Model(&dish.Dish{ID: dishID}).Update("stock", stock).Error)
+}
+
+func stockOf(t *testing.T, dishID uint) *int {
+	var d dish.Dish
+	require.NoError(t, db.First(&d, dishID).Error)
+	return d.Stock
+}
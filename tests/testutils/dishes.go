@@ -1,6 +1,7 @@
 package testutils
 
 import (
+	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/category"
 	"food_ordering_backend/controllers/dish"
 	"github.com/stretchr/testify/require"
@@ -15,14 +16,28 @@ var TestCategories = []category.Category{
 	{ID: 2, Title: "Burgers", Removable: true, Image: strPointer("2.png")},
 }
 var TestDishes = []dish.Dish{
-	{ID: 4, Title: "Cheeseburger", Price: 2.28, Image: strPointer("4.png"), CategoryID: 2, Category: FindTestCategoryByID(2)},
-	{ID: 5, Title: "Margherita", Price: 4.20, Image: strPointer("5.png"), CategoryID: 3, Category: FindTestCategoryByID(3)},
-	{ID: 1, Title: "Fresh and Healthy Salad", Price: 2.65, Image: strPointer("1.png"), CategoryID: 1, Category: FindTestCategoryByID(1)},
-	{ID: 2, Title: "Crunchy Cashew Salad", Price: 3.22, Image: strPointer("2.png"), CategoryID: 1, Category: FindTestCategoryByID(1)},
-	{ID: 6, Title: "4 Cheese", Price: 4.69, Image: strPointer("6.png"), CategoryID: 3, Category: FindTestCategoryByID(3)},
-	{ID: 8, Title: "Orange Juice 2L", Price: 2, Image: strPointer("8.png"), CategoryID: 4, Category: FindTestCategoryByID(4)},
-	{ID: 3, Title: "Hamburger", Price: 1.99, Image: strPointer("3.png"), CategoryID: 2, Category: FindTestCategoryByID(2)},
-	{ID: 7, Title: "Pepsi 2L", Price: 1.50, Image: strPointer("7.png"), CategoryID: 4, Category: FindTestCategoryByID(4)},
+	{ID: 4, Title: "Cheeseburger", Price: common.NewMoney(228), Image: strPointer("4.png"), Images: gallery("4.png"), CategoryID: 2, Category: FindTestCategoryByID(2), Available: true},
+	{
+		ID: 5, Title: "Margherita", Price: common.NewMoney(420), Image: strPointer("5.png"), Images: gallery("5.png"), CategoryID: 3, Category: FindTestCategoryByID(3), Available: true,
+		Description: "Tomato sauce, mozzarella and basil", Ingredients: dish.Ingredients{"tomato sauce", "mozzarella", "basil"},
+		Allergens: dish.AllergenGluten | dish.AllergenMilk, DietaryTags: dish.TagVegetarian, Calories: intPointer(800),
+	},
+	{
+		ID: 1, Title: "Fresh and Healthy Salad", Price: common.NewMoney(265), Image: strPointer("1.png"), Images: gallery("1.png"), CategoryID: 1, Category: FindTestCategoryByID(1), Available: true,
+		Description: "Lettuce, cucumber and cherry tomatoes with olive oil", Ingredients: dish.Ingredients{"lettuce", "cucumber", "cherry tomatoes", "olive oil"},
+		DietaryTags: dish.TagVegetarian | dish.TagVegan | dish.TagGlutenFree, Calories: intPointer(180),
+	},
+	{
+		ID: 2, Title: "Crunchy Cashew Salad", Price: common.NewMoney(322), Image: strPointer("2.png"), Images: gallery("2.png"), CategoryID: 1, Category: FindTestCategoryByID(1), Available: true,
+		Allergens: dish.AllergenNuts, DietaryTags: dish.TagVegetarian, SpicyLevel: 1, Calories: intPointer(420),
+	},
+	{ID: 6, Title: "4 Cheese", Price: common.NewMoney(469), Image: strPointer("6.png"), Images: gallery("6.png"), CategoryID: 3, Category: FindTestCategoryByID(3), Available: true},
+	{ID: 8, Title: "Orange Juice 2L", Price: common.NewMoney(200), Image: strPointer("8.png"), Images: gallery("8.png"), CategoryID: 4, Category: FindTestCategoryByID(4), Available: true},
+	{
+		ID: 3, Title: "Hamburger", Price: common.NewMoney(199), Image: strPointer("3.png"), Images: gallery("3.png"), CategoryID: 2, Category: FindTestCategoryByID(2), Available: true,
+		Allergens: dish.AllergenGluten | dish.AllergenSesame, SpicyLevel: 2, Calories: intPointer(550),
+	},
+	{ID: 7, Title: "Pepsi 2L", Price: common.NewMoney(150), Image: strPointer("7.png"), Images: gallery("7.png"), CategoryID: 4, Category: FindTestCategoryByID(4), Available: true},
 }
 
 func SetupDishesAndCategories(t *testing.T) {
@@ -38,6 +53,25 @@ func SetupDishesAndCategories(t *testing.T) {
 	req.NoError(db.Omit("Category").Create(TestDishes).Error)
 }
 
+// TestOptionGroups are option groups of "4 Cheese" pizza.
+var TestOptionGroups = []dish.OptionGroup{
+	{DishID: 6, Title: "Size", MinSelect: 1, MaxSelect: 1, Options: []dish.Option{
+		{Title: "Small", PriceDelta: common.NewMoney(0)},
+		{Title: "Large", PriceDelta: common.NewMoney(150)},
+	}},
+	{DishID: 6, Title: "Extras", MinSelect: 0, MaxSelect: 2, Options: []dish.Option{
+		{Title: "Extra cheese", PriceDelta: common.NewMoney(50)},
+		{Title: "No onions", PriceDelta: common.NewMoney(0)},
+		{Title: "Jalapenos", PriceDelta: common.NewMoney(30)},
+	}},
+}
+
+// SetupDishOptions inserts TestOptionGroups into db. It must be called after
+// SetupDishesAndCategories, which also removes them after test run.
+func SetupDishOptions(t *testing.T) {
+	require.NoError(t, db.Omit("Dish").Create(&TestOptionGroups).Error)
+}
+
 func FindTestDishByID(id uint) dish.Dish {
 	for _, testDish := range TestDishes {
 		if testDish.ID == id {
@@ -68,6 +102,22 @@ func FindTestDishesByCategoryID(id uint) []dish.Dish {
 	return dishes
 }
 
+// gallery returns images with provided names in order. Their ids are
+// filled in once the dishes are inserted.
+func gallery(names ...string) []dish.Image {
+	images := make([]dish.Image, len(names))
+
+	for i, name := range names {
+		images[i] = dish.Image{Name: name, Position: i}
+	}
+
+	return images
+}
+
+func intPointer(i int) *int {
+	return &i
+}
+
 func strPointer(str string) *string {
 	return &str
 }
@@ -47,7 +47,7 @@ var TestOrderItems = []order.Item{
 func SetupOrdersDB(t *testing.T) {
 	req := require.New(t)
 	cleanup := func() {
-		req.NoError(db.Exec("TRUNCATE orders, order_items;").Error)
+		req.NoError(db.Exec("TRUNCATE orders CASCADE;").Error)
 	}
 	t.Cleanup(cleanup)
 	cleanup()
@@ -59,7 +59,8 @@ func SetupOrdersDB(t *testing.T) {
 		TestOrders[i], TestOrders[j] = TestOrders[j], TestOrders[i]
 	})
 
-	req.NoError(db.Create(&TestOrders).Error)
+	// dishes already exist, saving copies of them would duplicate their galleries
+	req.NoError(db.Omit("Items.Dish").Create(&TestOrders).Error)
 	//req.NoError(db.Create(&TestOrderItems).Error)
 }
 
@@ -73,19 +74,14 @@ func (g *orderGenerator) createTestOrder(status order.Status, u user.User) order
 	}
 	g.orderID++
 
-	o.Total = order.CalcTotal(o.Items)
+	o.Total, _ = order.CalcTotal(o.Items)
 	return o
 }
 
 func (g *orderGenerator) createOrderItem(orderID, testDishID uint, quantity int) order.Item {
-	d := FindTestDishByID(testDishID)
-	i := order.Item{
-		ID:       g.orderItemID,
-		OrderID:  orderID,
-		Dish:     d,
-		DishID:   d.ID,
-		Quantity: quantity,
-	}
+	i := order.NewItem(FindTestDishByID(testDishID), quantity)
+	i.ID = g.orderItemID
+	i.OrderID = orderID
 
 	g.orderItemID++
 	return i
@@ -5,6 +5,7 @@ import (
 	"food_ordering_backend/config"
 	"food_ordering_backend/database"
 	"food_ordering_backend/router"
+	"food_ordering_backend/services"
 	"github.com/gin-gonic/gin"
 	"image"
 	"image/color"
@@ -18,12 +19,16 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"testing"
 	"time"
 )
 
 var db = database.MustGetTest()
 var Router *gin.Engine
 
+// Mailer keeps emails sent by the app during tests.
+var Mailer = &services.MemoryMailer{}
+
 func init() {
 	config.StaticDir = "test-static"
 	config.CategoriesImgDir = config.StaticDir + "/categories"
@@ -32,9 +37,40 @@ func init() {
 	config.CategoriesImgDirAbs = PathToFile(config.CategoriesImgDir)
 	config.DishesImgDirAbs = PathToFile(config.DishesImgDir)
 
+	// Tests sign in a lot from the same address, so rate limits are disabled.
+	// Use LimitedRouter to test them.
+	config.RateLimit = config.Rate{}
+	config.AuthRateLimit = config.Rate{}
+	config.AuthEmailRateLimit = config.Rate{}
+	config.LoginLockout = config.Rate{}
+
+	services.DefaultMailer = Mailer
 	Router = router.Setup(db)
 }
 
+// LimitedRouter creates a router with provided rate limits and
+// an empty limiter store. Limits are restored once the test finishes.
+func LimitedRouter(t *testing.T, limits map[*config.Rate]config.Rate) *gin.Engine {
+	store := services.DefaultLimiterStore
+	services.DefaultLimiterStore = services.NewMemoryLimiterStore()
+
+	for rate, limit := range limits {
+		rate := rate
+		prev := *rate
+		*rate = limit
+
+		t.Cleanup(func() {
+			*rate = prev
+		})
+	}
+
+	t.Cleanup(func() {
+		services.DefaultLimiterStore = store
+	})
+
+	return router.Setup(db)
+}
+
 func SendReq(method, target string) func(body string) *httptest.ResponseRecorder {
 	return func(body string) *httptest.ResponseRecorder {
 		w := httptest.NewRecorder()
@@ -58,6 +94,16 @@ func ReqWithCookie(method, target string) func(c *http.Cookie, body string) *htt
 	}
 }
 
+func ReqWithToken(method, target string) func(token, body string) *httptest.ResponseRecorder {
+	return func(token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		Router.ServeHTTP(w, req)
+		return w
+	}
+}
+
 func UploadReqWithCookie(method, target, fieldName string) func(c *http.Cookie, fileName string, file io.Reader) *httptest.ResponseRecorder {
 	return func(c *http.Cookie, fileName string, file io.Reader) *httptest.ResponseRecorder {
 		writer, body := MultipartWithFile(fieldName, fileName, file)
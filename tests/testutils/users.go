@@ -2,12 +2,15 @@ package testutils
 
 import (
 	"encoding/json"
+	"fmt"
 	"food_ordering_backend/common"
 	"food_ordering_backend/controllers/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"sort"
 	"testing"
 )
@@ -27,11 +30,15 @@ var startID uint = 1
 var TestUsers = populateTestUsers(startID)
 var TestAdmins = populateTestAdmins(startID + uint(len(TestUsers)))
 
+// TestAddresses are default addresses of TestUsers and TestAdmins, in the same order.
+var TestAddresses = populateTestAddresses(append(append([]user.User{}, TestUsers...), TestAdmins...))
+
 func populateTestUsers(startID uint) []user.User {
 	users := make([]user.User, 3)
 	for i, dto := range TestUsersDTOs {
 		u := user.CreateFromDTO(dto)
 		u.ID = startID
+		u.EmailVerified = true
 
 		startID++
 
@@ -45,16 +52,42 @@ func populateTestAdmins(startID uint) []user.User {
 	for i, dto := range TestAdminsDTOs {
 		u := user.CreateFromDTO(dto)
 		u.ID = startID
+		u.EmailVerified = true
 
 		startID++
 
-		u.IsAdmin = true
+		u.Roles = []user.UserRole{{Role: user.RoleAdmin}}
 		admins[i] = u
 	}
 	return admins
 }
 
-// SetupUsersDB inserts TestUsers and TestAdmins into db.
+func populateTestAddresses(users []user.User) []user.Address {
+	addresses := make([]user.Address, len(users))
+	for i, u := range users {
+		addresses[i] = user.Address{
+			UserID:    u.ID,
+			Label:     "Home",
+			Line1:     fmt.Sprintf("%d Main St", u.ID),
+			City:      "Springfield",
+			IsDefault: true,
+		}
+	}
+	return addresses
+}
+
+// FindTestAddressByUserID returns the default address of the test user with provided id.
+func FindTestAddressByUserID(id uint) user.Address {
+	for _, a := range TestAddresses {
+		if a.UserID == id {
+			return a
+		}
+	}
+	log.Panicf("cannot find TestAddress of user with id %d\n", id)
+	return user.Address{}
+}
+
+// SetupUsersDB inserts TestUsers, TestAdmins and TestAddresses into db.
 // Users and sessions tables will be truncated before and after test run.
 func SetupUsersDB(t *testing.T) {
 	req := require.New(t)
@@ -67,6 +100,24 @@ func SetupUsersDB(t *testing.T) {
 
 	req.NoError(db.Create(&TestUsers).Error)
 	req.NoError(db.Create(&TestAdmins).Error)
+	req.NoError(db.Omit("User").Create(&TestAddresses).Error)
+}
+
+// GrantRoles assigns provided roles to the user in db.
+func GrantRoles(t *testing.T, u user.User, roles ...user.Role) {
+	for _, role := range roles {
+		require.NoError(t, db.Omit("User").Create(&user.UserRole{UserID: u.ID, Role: role}).Error)
+	}
+}
+
+// TokenFromMail returns the token from the link in the latest email sent to provided address.
+func TokenFromMail(t *testing.T, email string) string {
+	mails := Mailer.Sent(email)
+	require.NotEmpty(t, mails)
+
+	match := regexp.MustCompile(`token=([\w-]+)`).FindStringSubmatch(mails[len(mails)-1].Body)
+	require.Len(t, match, 2)
+	return match[1]
 }
 
 func SortUsersByID(users []user.User) {
@@ -84,7 +135,7 @@ func RunAuthTests(t *testing.T, method, target string, adminOnly bool) {
 	})
 
 	if adminOnly {
-		t.Run("should return 401 if user is not admin", func(t *testing.T) {
+		t.Run("should return 401 if user lacks permission", func(t *testing.T) {
 			SetupUsersDB(t)
 			_, c := LoginAsRandomUser(t)
 			resp := ReqWithCookie(method, target)(c, "")
@@ -119,7 +170,9 @@ func UsersEqual(t *testing.T, u1, u2 user.User) {
 	it.Equal(u1.ID, u2.ID)
 	it.Equal(u1.Email, u2.Email)
 	it.True(EqualTimestamps(u1.CreatedAt, u2.CreatedAt))
-	it.Equal(u1.IsAdmin, u2.IsAdmin)
+	it.Equal(u1.RoleNames(), u2.RoleNames())
+	it.Equal(u1.Disabled, u2.Disabled)
+	it.Equal(u1.EmailVerified, u2.EmailVerified)
 	it.Equal(u1.PasswordHash, u2.PasswordHash)
 }
 
@@ -1,15 +1,21 @@
 package user_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"food_ordering_backend/common"
 	"food_ordering_backend/config"
 	"food_ordering_backend/controllers/user"
 	"food_ordering_backend/database"
 	"food_ordering_backend/tests/testutils"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 
 	"testing"
 )
@@ -18,6 +24,8 @@ var db = database.MustGet()
 var testUsers = testutils.TestUsers
 var testAdmins = testutils.TestAdmins
 
+const testUserAgent = "test-agent"
+
 func TestAPI(t *testing.T) {
 	t.Run("GET /users", func(t *testing.T) {
 		send := testutils.ReqWithCookie(http.MethodGet, "/users")
@@ -46,13 +54,14 @@ func TestAPI(t *testing.T) {
 			testutils.SortUsersByID(users)
 
 			tests := []struct {
-				query          string
-				expectedOrders []user.User
-				expectedPage   int
-				expectedLimit  int
+				query         string
+				expectedUsers []user.User
+				expectedPage  int
+				expectedLimit int
 			}{
 				{"limit=2", users[:2], 0, 2},
-				{"limit=3&page=2", users[3:], 2, 3},
+				{"limit=2&page=1", users[2:4], 1, 2},
+				{"limit=3&page=1", users[3:], 1, 3},
 				{"page=2", nil, 2, 10},
 				{"", users, 0, 10},
 			}
@@ -69,16 +78,65 @@ func TestAPI(t *testing.T) {
 						it.Equal(tc.expectedLimit, dto.Pagination.Limit)
 						it.Equal(len(users), dto.Pagination.Total)
 
+						if it.Len(dto.Users, len(tc.expectedUsers), tc.query) {
+							for i, u := range dto.Users {
+								it.Equal(tc.expectedUsers[i].ID, u.ID)
+								it.Equal(tc.expectedUsers[i].Email, u.Email)
+								it.Equal(tc.expectedUsers[i].RoleNames(), u.Roles)
+							}
+						}
+					}
+				}
+			}
+		})
+
+		t.Run("should search by email and sort", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			tests := []struct {
+				query         string
+				expectedIDs   []uint
+				expectedTotal int
+			}{
+				{"email=HOTMAIL", []uint{1, 2, 5, 6}, 4},
+				{"email=hotmail&sort=email", []uint{5, 2, 6, 1}, 4},
+				{"email=hotmail&sort=email&order=desc&limit=2", []uint{1, 6}, 4},
+				{"order=desc", []uint{6, 5, 4, 3, 2, 1}, 6},
+				{"email=gmail", []uint{}, 0},
+				{"email=_", []uint{1, 2, 4}, 3},
+			}
+
+			for _, tc := range tests {
+				resp := testutils.ReqWithCookie(http.MethodGet, "/users?"+tc.query)(c, "")
+
+				if it.Equal(http.StatusOK, resp.Code, tc.query) {
+					var dto user.DTOsWithPagination
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+						ids := make([]uint, len(dto.Users))
+
 						for i, u := range dto.Users {
-							it.Equal(users[i].ID, u.ID)
-							it.Equal(users[i].Email, u.Email)
-							it.Equal(users[i].IsAdmin, u.IsAdmin)
+							ids[i] = u.ID
 						}
+
+						it.Equal(tc.expectedIDs, ids, tc.query)
+						it.Equal(tc.expectedTotal, dto.Pagination.Total, tc.query)
 					}
 				}
 			}
 		})
 
+		t.Run("should return 400 if query is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			for _, query := range []string{"sort=password_hash", "order=up"} {
+				resp := testutils.ReqWithCookie(http.MethodGet, "/users?"+query)(c, "")
+				assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+			}
+		})
+
 		testutils.RunAuthTests(t, http.MethodGet, "/users", true)
 
 		t.Run("GET /users/me", func(t *testing.T) {
@@ -102,6 +160,52 @@ func TestAPI(t *testing.T) {
 				}
 			})
 
+			t.Run("should accept token from Authorization header", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto, c := testutils.LoginAsRandomUser(t)
+				resp := testutils.ReqWithToken(http.MethodGet, "/users/me")(c.Value, "")
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					var respDTO user.ResponseDTO
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) {
+						it.Equal(dto.Email, respDTO.Email)
+					}
+				}
+			})
+
+			t.Run("should return 401 if Authorization header has invalid token or another scheme", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				_, c := testutils.LoginAsRandomUser(t)
+
+				it.Equal(http.StatusUnauthorized, testutils.ReqWithToken(http.MethodGet, "/users/me")("invalid", "").Code)
+
+				req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+				req.Header.Set("Authorization", "Basic "+c.Value)
+				req.AddCookie(c)
+				w := httptest.NewRecorder()
+				testutils.Router.ServeHTTP(w, req)
+				it.Equal(http.StatusUnauthorized, w.Code, "expected header to take precedence over cookie")
+			})
+
+			t.Run("should return 401 if session has expired", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				_, c := testutils.LoginAsRandomUser(t)
+				require.NoError(t, db.Model(&user.Session{}).Where("token = ?", c.Value).Update("expires_at", time.Now()).Error)
+
+				assert.Equal(t, http.StatusUnauthorized, send(c, "").Code)
+			})
+
+			t.Run("should return 401 if session doesn't exist", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				_, c := testutils.LoginAsRandomUser(t)
+				require.NoError(t, db.Where("token = ?", c.Value).Delete(&user.Session{}).Error)
+
+				assert.Equal(t, http.StatusUnauthorized, send(c, "").Code)
+			})
+
 			testutils.RunAuthTests(t, http.MethodGet, "/users/me", false)
 		})
 
@@ -143,6 +247,19 @@ func TestAPI(t *testing.T) {
 				}
 			})
 
+			t.Run("should remove session if token is sent in Authorization header", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				_, c := testutils.LoginAsRandomUser(t)
+				resp := testutils.ReqWithToken(http.MethodGet, "/users/logout")(c.Value, "")
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					var count int64
+					db.Model(&user.Session{}).Where("token = ?", c.Value).Count(&count)
+					it.Zero(count)
+				}
+			})
+
 			testutils.RunAuthTests(t, http.MethodGet, "/users/me", false)
 		})
 	})
@@ -169,7 +286,7 @@ func TestAPI(t *testing.T) {
 					it.NotEmpty(u.PasswordHash)
 					it.Equal(newUser.Email, u.Email)
 					it.False(u.CreatedAt.IsZero())
-					it.False(u.IsAdmin)
+					it.False(u.HasRole(user.RoleAdmin))
 				}
 			})
 
@@ -249,12 +366,21 @@ func TestAPI(t *testing.T) {
 						c := testutils.FindCookieByName(resp.Result(), user.SessionCookieName)
 						validateSessionCookie(t, c)
 
+						rc := testutils.FindCookieByName(resp.Result(), user.RefreshCookieName)
+						if it.NotNil(rc) {
+							it.NotZero(rc.Value)
+							it.True(rc.HttpOnly)
+							it.Equal(user.RefreshCookiePath, rc.Path)
+							it.Greater(rc.MaxAge, 0)
+						}
+
 						var sessions []user.Session
-						db.Find(&sessions)
+						db.Order("id").Find(&sessions)
 
 						if it.Len(sessions, i+1) {
 							it.Equal(sessions[i].Token, c.Value)
 							it.False(sessions[i].CreatedAt.IsZero())
+							it.WithinDuration(sessions[i].CreatedAt.Add(config.RefreshTokenExpiry), sessions[i].ExpiresAt, time.Minute)
 						}
 					}
 
@@ -262,6 +388,26 @@ func TestAPI(t *testing.T) {
 
 			})
 
+			t.Run("should rehash password if it was hashed by another hasher", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+				hasher := user.Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16}
+				hash, err := hasher.Hash(dto.Password)
+				require.NoError(t, err)
+				require.NoError(t, db.Model(&user.User{}).Where("email = ?", dto.Email).Update("password_hash", hash).Error)
+
+				if it.Equal(http.StatusOK, testutils.Login(dto).Code) {
+					var u user.User
+
+					if it.NoError(db.Where("email = ?", dto.Email).First(&u).Error) {
+						it.NotEqual(hash, u.PasswordHash)
+						it.False(u.PasswordNeedsRehash())
+						it.NoError(u.ValidatePassword(dto.Password))
+					}
+				}
+			})
+
 			t.Run("should return 404 if provided email or password is incorrect", func(t *testing.T) {
 				testutils.SetupUsersDB(t)
 				it := assert.New(t)
@@ -278,8 +424,1178 @@ func TestAPI(t *testing.T) {
 				it.Equal(http.StatusNotFound, resp.Code)
 			})
 		})
+
+		t.Run("POST /users/token", func(t *testing.T) {
+			send := testutils.SendReq(http.MethodPost, "/users/token")
+
+			t.Run("should return a token and add session to db without setting cookie", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+				data, _ := json.Marshal(&dto)
+				resp := send(string(data))
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					it.Nil(testutils.FindCookieByName(resp.Result(), user.SessionCookieName))
+
+					var tokenDTO user.TokenDTO
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&tokenDTO)) {
+						it.NotZero(tokenDTO.AccessToken)
+						it.Equal("Bearer", tokenDTO.TokenType)
+						it.WithinDuration(time.Now().Add(config.JWTExpiry), tokenDTO.ExpiresAt, time.Minute)
+						it.NotZero(tokenDTO.RefreshToken)
+						it.WithinDuration(time.Now().Add(config.RefreshTokenExpiry), tokenDTO.RefreshTokenExpiresAt, time.Minute)
+
+						var session user.Session
+
+						if it.NoError(db.Joins("User").Where("token = ?", tokenDTO.AccessToken).First(&session).Error) {
+							it.Equal(dto.Email, session.User.Email)
+						}
+					}
+				}
+			})
+
+			t.Run("should return 404 if provided email or password is incorrect", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+				dto.Password = "some-random-pass"
+				data, _ := json.Marshal(&dto)
+
+				assert.Equal(t, http.StatusNotFound, send(string(data)).Code)
+			})
+
+			t.Run("should return 422 if json is invalid", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				assert.Equal(t, http.StatusUnprocessableEntity, send(`{"email": "test@test.test"}`).Code)
+			})
+		})
+
+		t.Run("POST /users/refresh", func(t *testing.T) {
+			send := testutils.SendReq(http.MethodPost, "/users/refresh")
+			me := testutils.ReqWithToken(http.MethodGet, "/users/me")
+
+			t.Run("should issue new tokens and revoke the old ones", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				_, tokens := getTokens(t)
+				resp := send(refreshBody(tokens.RefreshToken))
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					var newTokens user.TokenDTO
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&newTokens)) {
+						it.NotEqual(tokens.AccessToken, newTokens.AccessToken)
+						it.NotEqual(tokens.RefreshToken, newTokens.RefreshToken)
+						it.Equal("Bearer", newTokens.TokenType)
+
+						it.Equal(http.StatusOK, me(newTokens.AccessToken, "").Code)
+						it.Equal(http.StatusUnauthorized, me(tokens.AccessToken, "").Code, "expected old access token to be revoked")
+
+						var count int64
+						db.Model(&user.Session{}).Count(&count)
+						it.EqualValues(1, count, "expected to keep the same session")
+					}
+				}
+			})
+
+			t.Run("should delete the session if refresh token is reused", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				_, tokens := getTokens(t)
+
+				if it.Equal(http.StatusOK, send(refreshBody(tokens.RefreshToken)).Code) {
+					it.Equal(http.StatusUnauthorized, send(refreshBody(tokens.RefreshToken)).Code)
+
+					var count int64
+					db.Model(&user.Session{}).Count(&count)
+					it.Zero(count)
+				}
+			})
+
+			t.Run("should use refresh cookie if body is empty and set new cookies", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+				login := testutils.Login(dto)
+				rc := testutils.FindCookieByName(login.Result(), user.RefreshCookieName)
+				require.NotNil(t, rc)
+
+				resp := testutils.ReqWithCookie(http.MethodPost, "/users/refresh")(rc, "")
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					var respDTO user.ResponseDTO
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) {
+						it.Equal(dto.Email, respDTO.Email)
+					}
+
+					validateSessionCookie(t, testutils.FindCookieByName(resp.Result(), user.SessionCookieName))
+
+					newRC := testutils.FindCookieByName(resp.Result(), user.RefreshCookieName)
+					if it.NotNil(newRC) {
+						it.NotEqual(rc.Value, newRC.Value)
+					}
+				}
+			})
+
+			t.Run("should return 401 if refresh token is invalid or missing", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+
+				it.Equal(http.StatusUnauthorized, send(refreshBody("invalid")).Code)
+				it.Equal(http.StatusUnauthorized, send("").Code)
+			})
+
+			t.Run("should return 422 if json is invalid", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				assert.Equal(t, http.StatusUnprocessableEntity, send(`{"token": "abc"}`).Code)
+			})
+		})
+	})
+
+	t.Run("/users/me/sessions", func(t *testing.T) {
+		t.Run("GET /users/me/sessions", func(t *testing.T) {
+			t.Run("should return active sessions of the user and mark the current one", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto, tokens := getTokens(t)
+				tokensFor(t, dto)
+				tokensFor(t, testutils.TestAdminsDTOs[0])
+
+				resp := testutils.ReqWithToken(http.MethodGet, "/users/me/sessions")(tokens.AccessToken, "")
+
+				if it.Equal(http.StatusOK, resp.Code) {
+					var sessions []user.SessionDTO
+
+					if it.NoError(json.NewDecoder(resp.Body).Decode(&sessions)) && it.Len(sessions, 2) {
+						current := 0
+
+						for _, s := range sessions {
+							it.NotZero(s.ID)
+							it.Equal(testUserAgent, s.UserAgent)
+							it.NotZero(s.IP)
+							it.False(s.CreatedAt.IsZero())
+							it.False(s.LastUsedAt.IsZero())
+							it.True(s.ExpiresAt.After(time.Now()))
+
+							if s.Current {
+								current++
+							}
+						}
+
+						it.Equal(1, current)
+					}
+				}
+			})
+
+			testutils.RunAuthTests(t, http.MethodGet, "/users/me/sessions", false)
+		})
+
+		t.Run("DELETE /users/me/sessions/:id", func(t *testing.T) {
+			t.Run("should delete provided session of the user", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto, tokens := getTokens(t)
+				otherTokens := tokensFor(t, dto)
+
+				var other user.Session
+				require.NoError(t, db.Where("token = ?", otherTokens.AccessToken).First(&other).Error)
+
+				resp := testutils.ReqWithToken(http.MethodDelete, fmt.Sprintf("/users/me/sessions/%d", other.ID))(tokens.AccessToken, "")
+
+				if it.Equal(http.StatusNoContent, resp.Code) {
+					me := testutils.ReqWithToken(http.MethodGet, "/users/me")
+					it.Equal(http.StatusUnauthorized, me(otherTokens.AccessToken, "").Code)
+					it.Equal(http.StatusOK, me(tokens.AccessToken, "").Code)
+				}
+			})
+
+			t.Run("should return 404 if session belongs to another user", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				_, tokens := getTokens(t)
+				other := tokensFor(t, testutils.TestAdminsDTOs[0])
+
+				var session user.Session
+				require.NoError(t, db.Where("token = ?", other.AccessToken).First(&session).Error)
+
+				resp := testutils.ReqWithToken(http.MethodDelete, fmt.Sprintf("/users/me/sessions/%d", session.ID))(tokens.AccessToken, "")
+				it.Equal(http.StatusNotFound, resp.Code)
+				it.Equal(http.StatusOK, testutils.ReqWithToken(http.MethodGet, "/users/me")(other.AccessToken, "").Code)
+			})
+
+			t.Run("should return 400 if id is invalid", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				_, tokens := getTokens(t)
+				resp := testutils.ReqWithToken(http.MethodDelete, "/users/me/sessions/abc")(tokens.AccessToken, "")
+				assert.Equal(t, http.StatusBadRequest, resp.Code)
+			})
+
+			testutils.RunAuthTests(t, http.MethodDelete, "/users/me/sessions/1", false)
+		})
+
+		t.Run("DELETE /users/me/sessions", func(t *testing.T) {
+			t.Run("should delete all sessions of the user", func(t *testing.T) {
+				testutils.SetupUsersDB(t)
+				it := assert.New(t)
+				dto, tokens := getTokens(t)
+				tokensFor(t, dto)
+				admin := tokensFor(t, testutils.TestAdminsDTOs[0])
+
+				resp := testutils.ReqWithToken(http.MethodDelete, "/users/me/sessions")(tokens.AccessToken, "")
+
+				if it.Equal(http.StatusNoContent, resp.Code) {
+					var count int64
+					db.Model(&user.Session{}).Joins("User").Where("email = ?", dto.Email).Count(&count)
+					it.Zero(count)
+
+					it.Equal(http.StatusOK, testutils.ReqWithToken(http.MethodGet, "/users/me")(admin.AccessToken, "").Code)
+				}
+			})
+
+			testutils.RunAuthTests(t, http.MethodDelete, "/users/me/sessions", false)
+		})
+	})
+}
+
+func TestPassword(t *testing.T) {
+	t.Run("PUT /users/me/password", func(t *testing.T) {
+		send := testutils.ReqWithToken(http.MethodPut, "/users/me/password")
+
+		t.Run("should change password and log out other sessions", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			dto, tokens := getTokens(t)
+			other := tokensFor(t, dto)
+
+			resp := send(tokens.AccessToken, changePasswordBody(dto.Password, "new-password"))
+
+			if it.Equal(http.StatusNoContent, resp.Code) {
+				me := testutils.ReqWithToken(http.MethodGet, "/users/me")
+				it.Equal(http.StatusOK, me(tokens.AccessToken, "").Code)
+				it.Equal(http.StatusUnauthorized, me(other.AccessToken, "").Code)
+
+				it.Equal(http.StatusNotFound, testutils.Login(dto).Code)
+				dto.Password = "new-password"
+				it.Equal(http.StatusOK, testutils.Login(dto).Code)
+			}
+		})
+
+		t.Run("should return 403 if current password is incorrect", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			dto, tokens := getTokens(t)
+
+			resp := send(tokens.AccessToken, changePasswordBody("incorrect-password", "new-password"))
+
+			if it.Equal(http.StatusForbidden, resp.Code) {
+				it.Equal(http.StatusOK, testutils.Login(dto).Code)
+			}
+		})
+
+		t.Run("should return 422 if new password is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			dto, tokens := getTokens(t)
+
+			resp := send(tokens.AccessToken, changePasswordBody(dto.Password, "short"))
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPut, "/users/me/password", false)
+	})
+
+	t.Run("POST /users/password/forgot", func(t *testing.T) {
+		send := testutils.SendReq(http.MethodPost, "/users/password/forgot")
+
+		t.Run("should send an email with password reset link", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.Mailer.Reset()
+			it := assert.New(t)
+			dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+
+			resp := send(forgotPasswordBody(dto.Email))
+
+			if it.Equal(http.StatusAccepted, resp.Code) {
+				mails := testutils.Mailer.Sent(dto.Email)
+
+				if it.Len(mails, 1) {
+					it.Contains(mails[0].Body, config.ClientURL.String())
+					it.NotEmpty(testutils.TokenFromMail(t, dto.Email))
+				}
+
+				var count int64
+				db.Model(&user.PasswordResetToken{}).Count(&count)
+				it.EqualValues(1, count)
+			}
+		})
+
+		t.Run("should return 202 without sending an email if user doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.Mailer.Reset()
+			it := assert.New(t)
+
+			resp := send(forgotPasswordBody("email@not.exist"))
+
+			if it.Equal(http.StatusAccepted, resp.Code) {
+				it.Empty(testutils.Mailer.Sent("email@not.exist"))
+			}
+		})
+
+		t.Run("should return 422 if email is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			assert.Equal(t, http.StatusUnprocessableEntity, send(forgotPasswordBody("invalid")).Code)
+		})
 	})
 
+	t.Run("POST /users/password/reset", func(t *testing.T) {
+		send := testutils.SendReq(http.MethodPost, "/users/password/reset")
+		forgot := testutils.SendReq(http.MethodPost, "/users/password/forgot")
+
+		t.Run("should set a new password and log out all sessions", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.Mailer.Reset()
+			it := assert.New(t)
+			dto, tokens := getTokens(t)
+			require.Equal(t, http.StatusAccepted, forgot(forgotPasswordBody(dto.Email)).Code)
+			token := testutils.TokenFromMail(t, dto.Email)
+
+			resp := send(resetPasswordBody(token, "new-password"))
+
+			if it.Equal(http.StatusNoContent, resp.Code) {
+				it.Equal(http.StatusUnauthorized, testutils.ReqWithToken(http.MethodGet, "/users/me")(tokens.AccessToken, "").Code)
+
+				it.Equal(http.StatusNotFound, testutils.Login(dto).Code)
+				dto.Password = "new-password"
+				it.Equal(http.StatusOK, testutils.Login(dto).Code)
+
+				it.Equal(http.StatusBadRequest, send(resetPasswordBody(token, "another-password")).Code, "expected token to be single-use")
+			}
+		})
+
+		t.Run("should only accept the latest token", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.Mailer.Reset()
+			it := assert.New(t)
+			dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+
+			require.Equal(t, http.StatusAccepted, forgot(forgotPasswordBody(dto.Email)).Code)
+			first := testutils.TokenFromMail(t, dto.Email)
+			require.Equal(t, http.StatusAccepted, forgot(forgotPasswordBody(dto.Email)).Code)
+			latest := testutils.TokenFromMail(t, dto.Email)
+
+			it.Equal(http.StatusBadRequest, send(resetPasswordBody(first, "new-password")).Code)
+			it.Equal(http.StatusNoContent, send(resetPasswordBody(latest, "new-password")).Code)
+		})
+
+		t.Run("should return 400 if token is invalid or has expired", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			testutils.Mailer.Reset()
+			it := assert.New(t)
+			dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+
+			it.Equal(http.StatusBadRequest, send(resetPasswordBody("invalid", "new-password")).Code)
+
+			require.Equal(t, http.StatusAccepted, forgot(forgotPasswordBody(dto.Email)).Code)
+			token := testutils.TokenFromMail(t, dto.Email)
+			require.NoError(t, db.Model(&user.PasswordResetToken{}).Where("token_hash = ?", user.HashToken(token)).Update("expires_at", time.Now()).Error)
+
+			it.Equal(http.StatusBadRequest, send(resetPasswordBody(token, "new-password")).Code)
+		})
+
+		t.Run("should return 422 if password is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			assert.Equal(t, http.StatusUnprocessableEntity, send(resetPasswordBody("token", "short")).Code)
+		})
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	signUp := func(t *testing.T) (user.AuthDTO, *http.Cookie) {
+		dto := user.AuthDTO{Email: "unverified@user.com", Password: "secretpass"}
+		data, _ := json.Marshal(&dto)
+		resp := testutils.SendReq(http.MethodPost, "/users/signup")(string(data))
+		require.Equal(t, http.StatusCreated, resp.Code)
+		return dto, testutils.FindCookieByName(resp.Result(), user.SessionCookieName)
+	}
+
+	t.Run("GET /users/verify", func(t *testing.T) {
+		send := func(token string) *httptest.ResponseRecorder {
+			return testutils.SendReq(http.MethodGet, "/users/verify?token="+token)("")
+		}
+
+		t.Run("should send verification link on sign up and verify email with it", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			dto, c := signUp(t)
+			info := testutils.ReqWithCookie(http.MethodGet, "/users/me")
+
+			var me user.ResponseDTO
+			if it.NoError(json.NewDecoder(info(c, "").Body).Decode(&me)) {
+				it.False(me.EmailVerified)
+			}
+
+			resp := send(testutils.TokenFromMail(t, dto.Email))
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var respDTO user.ResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) {
+					it.Equal(dto.Email, respDTO.Email)
+					it.True(respDTO.EmailVerified)
+				}
+
+				if it.NoError(json.NewDecoder(info(c, "").Body).Decode(&me)) {
+					it.True(me.EmailVerified)
+				}
+			}
+		})
+
+		t.Run("should return 400 if token is invalid or has been used", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			dto, _ := signUp(t)
+			token := testutils.TokenFromMail(t, dto.Email)
+
+			it.Equal(http.StatusBadRequest, send("").Code)
+			it.Equal(http.StatusBadRequest, send("invalid-token").Code)
+			it.Equal(http.StatusOK, send(token).Code)
+			it.Equal(http.StatusBadRequest, send(token).Code)
+		})
+	})
+
+	t.Run("POST /users/me/verify", func(t *testing.T) {
+		send := testutils.ReqWithCookie(http.MethodPost, "/users/me/verify")
+
+		t.Run("should send a new link and invalidate the previous one", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			dto, c := signUp(t)
+			old := testutils.TokenFromMail(t, dto.Email)
+
+			if it.Equal(http.StatusAccepted, send(c, "").Code) {
+				token := testutils.TokenFromMail(t, dto.Email)
+				it.NotEqual(old, token)
+
+				verify := testutils.SendReq(http.MethodGet, "/users/verify?token="+old)
+				it.Equal(http.StatusBadRequest, verify("").Code)
+				verify = testutils.SendReq(http.MethodGet, "/users/verify?token="+token)
+				it.Equal(http.StatusOK, verify("").Code)
+			}
+		})
+
+		t.Run("should return 409 if email has already been verified", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			assert.Equal(t, http.StatusConflict, send(c, "").Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPost, "/users/me/verify", false)
+	})
+}
+
+func TestProfile(t *testing.T) {
+	t.Run("PUT /users/me", func(t *testing.T) {
+		send := testutils.ReqWithCookie(http.MethodPut, "/users/me")
+
+		t.Run("should replace name and phone of current user", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+
+			resp := send(c, `{"name": " John Doe ", "phone": "+14155552671"}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto user.ResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(testUsers[0].Email, dto.Email)
+					it.Equal("John Doe", dto.Name)
+					it.Equal("+14155552671", dto.Phone)
+				}
+
+				var u user.User
+				if it.NoError(db.First(&u, testUsers[0].ID).Error) {
+					it.Equal("John Doe", u.Name)
+					it.Equal("+14155552671", u.Phone)
+				}
+			}
+
+			it.Equal(http.StatusOK, send(c, `{}`).Code)
+
+			var u user.User
+			if it.NoError(db.First(&u, testUsers[0].ID).Error) {
+				it.Empty(u.Name)
+				it.Empty(u.Phone)
+			}
+		})
+
+		t.Run("should return 422 if phone or name is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			tests := []string{
+				`{"phone": "12345"}`,
+				`{"phone": "+1 415 555 2671"}`,
+				fmt.Sprintf(`{"name": %q}`, strings.Repeat("n", 101)),
+			}
+
+			for _, body := range tests {
+				it.Equal(http.StatusUnprocessableEntity, send(c, body).Code, body)
+			}
+		})
+
+		testutils.RunAuthTests(t, http.MethodPut, "/users/me", false)
+	})
+}
+
+func TestAddresses(t *testing.T) {
+	findAll := func(t *testing.T, c *http.Cookie) []user.AddressResponseDTO {
+		resp := testutils.ReqWithCookie(http.MethodGet, "/users/me/addresses")(c, "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var addresses []user.AddressResponseDTO
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&addresses))
+		return addresses
+	}
+
+	t.Run("GET /users/me/addresses", func(t *testing.T) {
+		t.Run("should return addresses of current user", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			a := testutils.FindTestAddressByUserID(testUsers[0].ID)
+
+			addresses := findAll(t, c)
+
+			if it.Len(addresses, 1) {
+				it.Equal(a.ID, addresses[0].ID)
+				it.Equal(a.Line1, addresses[0].Line1)
+				it.Equal(a.City, addresses[0].City)
+				it.True(addresses[0].IsDefault)
+			}
+		})
+
+		testutils.RunAuthTests(t, http.MethodGet, "/users/me/addresses", false)
+	})
+
+	t.Run("POST /users/me/addresses", func(t *testing.T) {
+		send := testutils.ReqWithCookie(http.MethodPost, "/users/me/addresses")
+
+		t.Run("should create an address and make it default if requested", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			old := testutils.FindTestAddressByUserID(testUsers[0].ID)
+
+			resp := send(c, `{"label": "Work", "line1": " 2 Work Rd ", "city": "Shelbyville", "phone": "+14155552671", "is_default": true}`)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				var dto user.AddressResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.NotZero(dto.ID)
+					it.Equal("Work", dto.Label)
+					it.Equal("2 Work Rd", dto.Line1)
+					it.Equal("Shelbyville", dto.City)
+					it.Equal("+14155552671", dto.Phone)
+					it.True(dto.IsDefault)
+				}
+
+				addresses := findAll(t, c)
+
+				if it.Len(addresses, 2) {
+					it.Equal(dto.ID, addresses[0].ID)
+					it.Equal(old.ID, addresses[1].ID)
+					it.False(addresses[1].IsDefault)
+				}
+			}
+		})
+
+		t.Run("should keep the default address if the new one isn't default", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			old := testutils.FindTestAddressByUserID(testUsers[0].ID)
+
+			resp := send(c, `{"line1": "2 Work Rd", "city": "Shelbyville"}`)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				addresses := findAll(t, c)
+
+				if it.Len(addresses, 2) {
+					it.Equal(old.ID, addresses[0].ID)
+					it.True(addresses[0].IsDefault)
+					it.False(addresses[1].IsDefault)
+				}
+			}
+		})
+
+		t.Run("should make the first address default", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			require.NoError(t, db.Where("user_id = ?", testUsers[0].ID).Delete(&user.Address{}).Error)
+
+			resp := send(c, `{"line1": "2 Work Rd", "city": "Shelbyville"}`)
+
+			if it.Equal(http.StatusCreated, resp.Code) {
+				var dto user.AddressResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.True(dto.IsDefault)
+				}
+			}
+		})
+
+		t.Run("should return 422 if json is incorrect or contains validation errors", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			tests := []string{
+				`{"line1": "2 Work Rd", "city": `,
+				`{"city": "Shelbyville"}`,
+				`{"line1": "2 Work Rd"}`,
+				`{"line1": "   ", "city": "Shelbyville"}`,
+				`{"line1": "2 Work Rd", "city": "Shelbyville", "phone": "12345"}`,
+			}
+
+			for _, body := range tests {
+				it.Equal(http.StatusUnprocessableEntity, send(c, body).Code, body)
+			}
+		})
+
+		testutils.RunAuthTests(t, http.MethodPost, "/users/me/addresses", false)
+	})
+
+	t.Run("PUT /users/me/addresses/:id", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint, body string) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodPut, fmt.Sprintf("/users/me/addresses/%d", id))(c, body)
+		}
+
+		t.Run("should replace the address and make it default if requested", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			old := testutils.FindTestAddressByUserID(testUsers[0].ID)
+			a := user.Address{UserID: testUsers[0].ID, Line1: "2 Work Rd", City: "Shelbyville"}
+			require.NoError(t, db.Omit("User").Create(&a).Error)
+
+			resp := send(c, a.ID, `{"line1": "3 Work Rd", "city": "Shelbyville", "comment": "Door code 42", "is_default": true}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto user.AddressResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(a.ID, dto.ID)
+					it.Equal("3 Work Rd", dto.Line1)
+					it.Equal("Door code 42", dto.Comment)
+					it.True(dto.IsDefault)
+				}
+
+				addresses := findAll(t, c)
+
+				if it.Len(addresses, 2) {
+					it.Equal(a.ID, addresses[0].ID)
+					it.Equal(old.ID, addresses[1].ID)
+					it.False(addresses[1].IsDefault)
+				}
+			}
+		})
+
+		t.Run("should keep the default address default", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			a := testutils.FindTestAddressByUserID(testUsers[0].ID)
+
+			resp := send(c, a.ID, `{"line1": "3 Main St", "city": "Springfield", "is_default": false}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto user.AddressResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal("3 Main St", dto.Line1)
+					it.True(dto.IsDefault)
+				}
+			}
+		})
+
+		t.Run("should return 404 if address belongs to another user", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			other := testutils.FindTestAddressByUserID(testUsers[1].ID)
+
+			resp := send(c, other.ID, `{"line1": "3 Main St", "city": "Springfield"}`)
+
+			if it.Equal(http.StatusNotFound, resp.Code) {
+				var a user.Address
+				if it.NoError(db.First(&a, other.ID).Error) {
+					it.Equal(other.Line1, a.Line1)
+				}
+			}
+		})
+
+		t.Run("should return 400 if id is invalid", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomUser(t)
+			resp := testutils.ReqWithCookie(http.MethodPut, "/users/me/addresses/abc")(c, `{"line1": "3 Main St", "city": "Springfield"}`)
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPut, "/users/me/addresses/1", false)
+	})
+
+	t.Run("DELETE /users/me/addresses/:id", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodDelete, fmt.Sprintf("/users/me/addresses/%d", id))(c, "")
+		}
+
+		t.Run("should delete the address and make the oldest remaining one default", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			old := testutils.FindTestAddressByUserID(testUsers[0].ID)
+			addresses := []user.Address{
+				{UserID: testUsers[0].ID, Line1: "2 Work Rd", City: "Shelbyville"},
+				{UserID: testUsers[0].ID, Line1: "3 Work Rd", City: "Shelbyville"},
+			}
+			require.NoError(t, db.Omit("User").Create(&addresses).Error)
+
+			if it.Equal(http.StatusNoContent, send(c, old.ID).Code) {
+				found := findAll(t, c)
+
+				if it.Len(found, 2) {
+					it.Equal(addresses[0].ID, found[0].ID)
+					it.True(found[0].IsDefault)
+					it.False(found[1].IsDefault)
+				}
+			}
+
+			it.Equal(http.StatusNoContent, send(c, addresses[1].ID).Code)
+			found := findAll(t, c)
+
+			if it.Len(found, 1) {
+				it.True(found[0].IsDefault)
+			}
+		})
+
+		t.Run("should return 404 if address belongs to another user", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestUsersDTOs[0])
+			other := testutils.FindTestAddressByUserID(testUsers[1].ID)
+
+			it.Equal(http.StatusNotFound, send(c, other.ID).Code)
+			it.NoError(db.First(&user.Address{}, other.ID).Error)
+		})
+
+		testutils.RunAuthTests(t, http.MethodDelete, "/users/me/addresses/1", false)
+	})
+}
+
+func TestUserManagement(t *testing.T) {
+	target := func(id uint) string {
+		return fmt.Sprintf("/users/%d", id)
+	}
+
+	t.Run("GET /users/:id", func(t *testing.T) {
+		t.Run("should return user by id", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			admin := testutils.TestAdmins[1]
+
+			resp := testutils.ReqWithCookie(http.MethodGet, target(admin.ID))(c, "")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var dto user.ResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&dto)) {
+					it.Equal(admin.Email, dto.Email)
+					it.Equal(admin.ID, dto.ID)
+					it.True(dto.IsAdmin)
+					it.False(dto.Disabled)
+				}
+			}
+		})
+
+		t.Run("should return 404 if user doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			assert.Equal(t, http.StatusNotFound, testutils.ReqWithCookie(http.MethodGet, target(1000))(c, "").Code)
+			assert.Equal(t, http.StatusBadRequest, testutils.ReqWithCookie(http.MethodGet, "/users/abc")(c, "").Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodGet, target(1), true)
+	})
+
+	t.Run("PATCH /users/:id", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint, body string) (*httptest.ResponseRecorder, user.ResponseDTO) {
+			var dto user.ResponseDTO
+			resp := testutils.ReqWithCookie(http.MethodPatch, target(id))(c, body)
+			_ = json.Unmarshal(resp.Body.Bytes(), &dto)
+			return resp, dto
+		}
+
+		t.Run("should grant and revoke admin role", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			u := testutils.TestUsers[0]
+
+			resp, dto := send(c, u.ID, `{"is_admin": true}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				it.True(dto.IsAdmin)
+				it.Equal([]user.Role{user.RoleAdmin}, dto.Roles)
+			}
+
+			resp, dto = send(c, u.ID, `{"is_admin": true}`)
+
+			if it.Equal(http.StatusOK, resp.Code, "expected granting admin role twice to succeed") {
+				it.Equal([]user.Role{user.RoleAdmin}, dto.Roles)
+			}
+
+			resp, dto = send(c, u.ID, `{"is_admin": false}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				it.False(dto.IsAdmin)
+				it.Empty(dto.Roles)
+			}
+		})
+
+		t.Run("should disable user, log them out and prevent signing in", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := testutils.TestUsersDTOs[0]
+			u := testutils.TestUsers[0]
+			tokens := tokensFor(t, dto)
+
+			resp, respDTO := send(c, u.ID, `{"disabled": true}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				it.True(respDTO.Disabled)
+				it.Equal(http.StatusUnauthorized, testutils.ReqWithToken(http.MethodGet, "/users/me")(tokens.AccessToken, "").Code)
+				it.Equal(http.StatusUnauthorized, testutils.SendReq(http.MethodPost, "/users/refresh")(refreshBody(tokens.RefreshToken)).Code)
+				it.Equal(http.StatusForbidden, testutils.Login(dto).Code)
+				it.Equal(http.StatusNotFound, testutils.Login(user.AuthDTO{Email: dto.Email, Password: "wrong-password"}).Code,
+					"expected disabled state to be hidden from clients that don't know the password")
+			}
+
+			resp, respDTO = send(c, u.ID, `{"disabled": false}`)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				it.False(respDTO.Disabled)
+				it.Equal(http.StatusOK, testutils.Login(dto).Code)
+			}
+		})
+
+		t.Run("should return 409 if admin disables themselves", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			c := testutils.LoginAs(t, testutils.TestAdminsDTOs[0])
+
+			resp, _ := send(c, testutils.TestAdmins[0].ID, `{"disabled": true}`)
+			assert.Equal(t, http.StatusConflict, resp.Code)
+		})
+
+		t.Run("should return 409 if the last admin loses admin role or is disabled", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestAdminsDTOs[0])
+			admins := testutils.TestAdmins
+
+			resp, _ := send(c, admins[1].ID, `{"disabled": true}`)
+			it.Equal(http.StatusOK, resp.Code)
+			resp, _ = send(c, admins[2].ID, `{"is_admin": false}`)
+			it.Equal(http.StatusOK, resp.Code)
+
+			resp, _ = send(c, admins[0].ID, `{"is_admin": false}`)
+			it.Equal(http.StatusConflict, resp.Code)
+		})
+
+		t.Run("should return 404 if user doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp, _ := send(c, 1000, `{"disabled": true}`)
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPatch, target(1), true)
+	})
+
+	t.Run("DELETE /users/:id", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint) *httptest.ResponseRecorder {
+			return testutils.ReqWithCookie(http.MethodDelete, target(id))(c, "")
+		}
+
+		t.Run("should delete user and their sessions", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto := testutils.TestUsersDTOs[0]
+			tokens := tokensFor(t, dto)
+
+			if it.Equal(http.StatusNoContent, send(c, testutils.TestUsers[0].ID).Code) {
+				it.Equal(http.StatusUnauthorized, testutils.ReqWithToken(http.MethodGet, "/users/me")(tokens.AccessToken, "").Code)
+				it.Equal(http.StatusNotFound, testutils.Login(dto).Code)
+				it.Equal(http.StatusNotFound, send(c, testutils.TestUsers[0].ID).Code)
+			}
+		})
+
+		t.Run("should return 409 if admin deletes themselves or the last admin", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestAdminsDTOs[0])
+			admins := testutils.TestAdmins
+
+			it.Equal(http.StatusConflict, send(c, admins[0].ID).Code)
+			it.Equal(http.StatusNoContent, send(c, admins[1].ID).Code)
+			it.Equal(http.StatusNoContent, send(c, admins[2].ID).Code)
+
+			// the only admin left can't delete themselves
+			it.Equal(http.StatusConflict, send(c, admins[0].ID).Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodDelete, target(1), true)
+	})
+}
+
+func TestRoles(t *testing.T) {
+	t.Run("GET /users/roles", func(t *testing.T) {
+		t.Run("should return all roles with their permissions", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			resp := testutils.ReqWithCookie(http.MethodGet, "/users/roles")(c, "")
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var roles []user.RoleDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&roles)) {
+					it.Equal(user.ToRoleDTOs(), roles)
+				}
+			}
+		})
+
+		testutils.RunAuthTests(t, http.MethodGet, "/users/roles", true)
+	})
+
+	t.Run("PUT /users/:id/roles", func(t *testing.T) {
+		send := func(c *http.Cookie, id uint, roles ...user.Role) *httptest.ResponseRecorder {
+			data, _ := json.Marshal(user.RolesDTO{Roles: append([]user.Role{}, roles...)})
+			return testutils.ReqWithCookie(http.MethodPut, fmt.Sprintf("/users/%d/roles", id))(c, string(data))
+		}
+
+		t.Run("should replace roles and apply them to existing sessions", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			dto, tokens := testutils.TestUsersDTOs[0], tokensFor(t, testutils.TestUsersDTOs[0])
+			u := testutils.TestUsers[0]
+			findAll := testutils.ReqWithToken(http.MethodGet, "/users")
+
+			it.Equal(http.StatusUnauthorized, findAll(tokens.AccessToken, "").Code)
+
+			resp := send(c, u.ID, user.RoleKitchen, user.RoleMenuManager, user.RoleKitchen)
+
+			if it.Equal(http.StatusOK, resp.Code) {
+				var respDTO user.ResponseDTO
+
+				if it.NoError(json.NewDecoder(resp.Body).Decode(&respDTO)) {
+					it.Equal(dto.Email, respDTO.Email)
+					it.Equal([]user.Role{user.RoleKitchen, user.RoleMenuManager}, respDTO.Roles)
+					it.Equal([]user.Permission{user.PermMenuWrite, user.PermOrdersReadAll, user.PermOrdersUpdateStatus}, respDTO.Permissions)
+					it.False(respDTO.IsAdmin)
+				}
+			}
+
+			it.Equal(http.StatusUnauthorized, findAll(tokens.AccessToken, "").Code)
+			it.Equal(http.StatusOK, send(c, u.ID, user.RoleAdmin).Code)
+			it.Equal(http.StatusOK, findAll(tokens.AccessToken, "").Code)
+			it.Equal(http.StatusOK, send(c, u.ID).Code)
+			it.Equal(http.StatusUnauthorized, findAll(tokens.AccessToken, "").Code)
+		})
+
+		t.Run("should return 409 if admin role is removed from the last admin", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			c := testutils.LoginAs(t, testutils.TestAdminsDTOs[0])
+			admins := testutils.TestAdmins
+
+			it.Equal(http.StatusOK, send(c, admins[1].ID).Code)
+			it.Equal(http.StatusOK, send(c, admins[2].ID, user.RoleKitchen).Code)
+			it.Equal(http.StatusOK, send(c, admins[0].ID, user.RoleAdmin, user.RoleKitchen).Code)
+			it.Equal(http.StatusConflict, send(c, admins[0].ID, user.RoleKitchen).Code)
+			it.Equal(http.StatusOK, testutils.ReqWithCookie(http.MethodGet, "/users")(c, "").Code)
+		})
+
+		t.Run("should return 422 if role doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			it := assert.New(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+			u := testutils.TestUsers[0]
+
+			it.Equal(http.StatusUnprocessableEntity, send(c, u.ID, user.RoleKitchen, "superuser").Code)
+			it.Equal(http.StatusUnprocessableEntity, testutils.ReqWithCookie(http.MethodPut, fmt.Sprintf("/users/%d/roles", u.ID))(c, "{}").Code)
+
+			roles, err := user.ProvideRepository(db).FindRoles(u.ID)
+
+			if it.NoError(err) {
+				it.Empty(roles)
+			}
+		})
+
+		t.Run("should return 404 if user doesn't exist", func(t *testing.T) {
+			testutils.SetupUsersDB(t)
+			_, c := testutils.LoginAsRandomAdmin(t)
+
+			assert.Equal(t, http.StatusNotFound, send(c, 1000, user.RoleKitchen).Code)
+		})
+
+		testutils.RunAuthTests(t, http.MethodPut, "/users/1/roles", true)
+	})
+}
+
+func TestRateLimits(t *testing.T) {
+	signIn := func(r *gin.Engine, dto user.AuthDTO) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(&dto)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/signin", bytes.NewReader(data)))
+		return w
+	}
+
+	t.Run("should return 429 with Retry-After after too many sign in requests from one IP", func(t *testing.T) {
+		testutils.SetupUsersDB(t)
+		it := assert.New(t)
+		r := testutils.LimitedRouter(t, map[*config.Rate]config.Rate{
+			&config.AuthRateLimit: {Limit: 3, Period: time.Minute},
+		})
+
+		for i := 0; i < 3; i++ {
+			it.Equal(http.StatusOK, signIn(r, testutils.TestUsersDTOs[i]).Code)
+		}
+
+		resp := signIn(r, testutils.TestAdminsDTOs[0])
+
+		if it.Equal(http.StatusTooManyRequests, resp.Code) {
+			it.Equal("20", resp.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("should return 429 after too many sign in requests with one email", func(t *testing.T) {
+		testutils.SetupUsersDB(t)
+		it := assert.New(t)
+		r := testutils.LimitedRouter(t, map[*config.Rate]config.Rate{
+			&config.AuthEmailRateLimit: {Limit: 2, Period: time.Minute},
+		})
+		dto := testutils.TestUsersDTOs[0]
+
+		it.Equal(http.StatusOK, signIn(r, dto).Code)
+		it.Equal(http.StatusOK, signIn(r, dto).Code)
+		it.Equal(http.StatusTooManyRequests, signIn(r, dto).Code)
+		it.Equal(http.StatusOK, signIn(r, testutils.TestUsersDTOs[1]).Code)
+	})
+
+	t.Run("should lock sign in with the email after repeated failures", func(t *testing.T) {
+		testutils.SetupUsersDB(t)
+		it := assert.New(t)
+		r := testutils.LimitedRouter(t, map[*config.Rate]config.Rate{
+			&config.LoginLockout: {Limit: 2, Period: time.Minute},
+		})
+		dto := testutils.TestUsersDTOs[0]
+		wrong := dto
+		wrong.Password = "wrong-password"
+
+		it.Equal(http.StatusNotFound, signIn(r, wrong).Code)
+		it.Equal(http.StatusNotFound, signIn(r, wrong).Code)
+
+		resp := signIn(r, dto)
+
+		if it.Equal(http.StatusTooManyRequests, resp.Code, "expected correct password to be rejected while locked") {
+			it.Equal("30", resp.Header().Get("Retry-After"))
+		}
+
+		it.Equal(http.StatusOK, signIn(r, testutils.TestUsersDTOs[1]).Code)
+	})
+
+	t.Run("should reset failures after successful sign in", func(t *testing.T) {
+		testutils.SetupUsersDB(t)
+		it := assert.New(t)
+		r := testutils.LimitedRouter(t, map[*config.Rate]config.Rate{
+			&config.LoginLockout: {Limit: 2, Period: time.Minute},
+		})
+		dto := testutils.TestUsersDTOs[0]
+		wrong := dto
+		wrong.Password = "wrong-password"
+
+		it.Equal(http.StatusNotFound, signIn(r, wrong).Code)
+		it.Equal(http.StatusOK, signIn(r, dto).Code)
+		it.Equal(http.StatusNotFound, signIn(r, wrong).Code)
+		it.Equal(http.StatusOK, signIn(r, dto).Code)
+	})
+
+	t.Run("should limit all requests from one IP", func(t *testing.T) {
+		it := assert.New(t)
+		r := testutils.LimitedRouter(t, map[*config.Rate]config.Rate{
+			&config.RateLimit: {Limit: 2, Period: time.Minute},
+		})
+
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+			it.Equal(http.StatusUnauthorized, w.Code)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+		it.Equal(http.StatusTooManyRequests, w.Code)
+	})
+}
+
+// getTokens signs in as a random user with POST /users/token.
+func getTokens(t *testing.T) (user.AuthDTO, user.TokenDTO) {
+	dto := testutils.TestUsersDTOs[common.RandomInt(len(testutils.TestUsersDTOs))]
+	return dto, tokensFor(t, dto)
+}
+
+func tokensFor(t *testing.T, dto user.AuthDTO) user.TokenDTO {
+	data, _ := json.Marshal(&dto)
+	req := httptest.NewRequest(http.MethodPost, "/users/token", bytes.NewReader(data))
+	req.Header.Set("User-Agent", testUserAgent)
+	resp := httptest.NewRecorder()
+	testutils.Router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var tokens user.TokenDTO
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	return tokens
+}
+
+func refreshBody(token string) string {
+	data, _ := json.Marshal(user.RefreshDTO{RefreshToken: token})
+	return string(data)
+}
+
+func changePasswordBody(current, next string) string {
+	data, _ := json.Marshal(user.ChangePasswordDTO{CurrentPassword: current, NewPassword: next})
+	return string(data)
+}
+
+func forgotPasswordBody(email string) string {
+	data, _ := json.Marshal(user.ForgotPasswordDTO{Email: email})
+	return string(data)
+}
+
+func resetPasswordBody(token, password string) string {
+	data, _ := json.Marshal(user.ResetPasswordDTO{Token: token, Password: password})
+	return string(data)
 }
 
 func validateSessionCookie(t *testing.T, c *http.Cookie) {